@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go-api-orm/utils"
+)
+
+// ErrInvalidToken se retorna cuando un token no es válido para el uso solicitado
+var ErrInvalidToken = errors.New("token inválido")
+
+// Claims son los claims tipados de todos los tokens emitidos por la API.
+// El ID del usuario viaja en sub; Purpose (typ) distingue los tokens de propósito
+// específico (p. ej. mfa_pending) de los tokens de acceso, que no lo llevan.
+// Actor (act) identifica al administrador que suplanta al usuario (RFC 8693).
+// TenantID (tid) es la organización en la que actúa el token de acceso y RoleID (rid) el
+// rol del usuario en ella; Role es su nombre, solo informativo.
+type Claims struct {
+	Role      string `json:"role,omitempty"`
+	RoleID    uint   `json:"rid,omitempty"`
+	TenantID  uint   `json:"tid,omitempty"`
+	SessionID uint   `json:"sid,omitempty"`
+	Purpose   string `json:"typ,omitempty"`
+	Actor     *Actor `json:"act,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Actor es el claim act de un token de suplantación
+type Actor struct {
+	Subject string `json:"sub"`
+}
+
+// UserID retorna el ID del usuario contenido en sub
+func (c *Claims) UserID() uint {
+	id, err := strconv.ParseUint(c.Subject, 10, 32)
+	if err != nil {
+		return 0
+	}
+	return uint(id)
+}
+
+// ActorID retorna el ID del administrador que suplanta al usuario o 0 si no hay suplantación
+func (c *Claims) ActorID() uint {
+	if c.Actor == nil {
+		return 0
+	}
+	id, err := strconv.ParseUint(c.Actor.Subject, 10, 32)
+	if err != nil {
+		return 0
+	}
+	return uint(id)
+}
+
+// IssuedAtTime retorna iat o el instante cero si el token no lo incluye
+func (c *Claims) IssuedAtTime() time.Time {
+	if c.IssuedAt == nil {
+		return time.Time{}
+	}
+	return c.IssuedAt.Time
+}
+
+// ExpiresAtTime retorna exp o el instante cero si el token no lo incluye
+func (c *Claims) ExpiresAtTime() time.Time {
+	if c.ExpiresAt == nil {
+		return time.Time{}
+	}
+	return c.ExpiresAt.Time
+}
+
+// Issuer retorna el emisor de los tokens (JWT_ISSUER)
+func Issuer() string {
+	return utils.GetEnvString("JWT_ISSUER", "go-api-orm")
+}
+
+// Audience retorna la audiencia de los tokens (JWT_AUDIENCE)
+func Audience() string {
+	return utils.GetEnvString("JWT_AUDIENCE", "go-api-orm")
+}
+
+// AccessTokenTTL retorna la duración de los tokens de acceso (JWT_EXPIRATION_MINUTES, 15 por defecto).
+// Son de corta duración: la sesión se mantiene renovándolos con el token de refresco.
+func AccessTokenTTL() time.Duration {
+	minutes := utils.GetEnvInt("JWT_EXPIRATION_MINUTES", 15)
+	if minutes <= 0 {
+		minutes = 15
+	}
+	return time.Minute * time.Duration(minutes)
+}
+
+// IssueAccessToken genera un token de acceso para el usuario en la organización tenantID,
+// donde tiene el rol indicado. Con sessionID distinto de 0 el token queda vinculado a esa sesión (claim sid).
+func IssueAccessToken(userID, tenantID uint, role string, roleID, sessionID uint) (string, error) {
+	claims, err := newClaims(userID, AccessTokenTTL())
+	if err != nil {
+		return "", err
+	}
+	claims.Role = role
+	claims.RoleID = roleID
+	claims.TenantID = tenantID
+	claims.SessionID = sessionID
+
+	return utils.SignToken(claims)
+}
+
+// ImpersonationTTL retorna la duración de los tokens de suplantación (IMPERSONATION_EXPIRATION_MINUTES)
+func ImpersonationTTL() time.Duration {
+	return time.Minute * time.Duration(utils.GetEnvInt("IMPERSONATION_EXPIRATION_MINUTES", 15))
+}
+
+// IssueImpersonationToken genera un token de acceso para userID en nombre del administrador actorID.
+// El token queda vinculado a la sesión del administrador y no tiene token de refresco.
+func IssueImpersonationToken(userID, tenantID uint, role string, roleID, actorID, sessionID uint) (string, error) {
+	claims, err := newClaims(userID, ImpersonationTTL())
+	if err != nil {
+		return "", err
+	}
+	claims.Role = role
+	claims.RoleID = roleID
+	claims.TenantID = tenantID
+	claims.SessionID = sessionID
+	claims.Actor = &Actor{Subject: strconv.FormatUint(uint64(actorID), 10)}
+
+	return utils.SignToken(claims)
+}
+
+// IssuePurposeToken genera un token de corta duración válido solo para el propósito indicado
+func IssuePurposeToken(userID uint, purpose string, ttl time.Duration) (string, error) {
+	claims, err := newClaims(userID, ttl)
+	if err != nil {
+		return "", err
+	}
+	claims.Purpose = purpose
+
+	return utils.SignToken(claims)
+}
+
+// ParseAccessToken valida un token de acceso: firma, exp, nbf, iss y aud
+func ParseAccessToken(tokenString string) (*Claims, error) {
+	return parse(tokenString, "")
+}
+
+// ParsePurposeToken valida un token generado con IssuePurposeToken para el propósito indicado
+func ParsePurposeToken(tokenString, purpose string) (*Claims, error) {
+	if purpose == "" {
+		return nil, ErrInvalidToken
+	}
+	return parse(tokenString, purpose)
+}
+
+func newClaims(userID uint, ttl time.Duration) (*Claims, error) {
+	jti, err := utils.GenerateTokenID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   strconv.FormatUint(uint64(userID), 10),
+			Issuer:    Issuer(),
+			Audience:  jwt.ClaimStrings{Audience()},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}, nil
+}
+
+func parse(tokenString, purpose string) (*Claims, error) {
+	// El método de firma y la clave se validan según el kid del token
+	token, err := utils.ParseToken(tokenString, &Claims{},
+		jwt.WithIssuer(Issuer()),
+		jwt.WithAudience(Audience()),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid || claims.Purpose != purpose || claims.UserID() == 0 {
+		return nil, ErrInvalidToken
+	}
+	if claims.Actor != nil && (purpose != "" || claims.ActorID() == 0) {
+		return nil, ErrInvalidToken
+	}
+	// Los tokens de acceso siempre llevan la organización y el rol, y los de propósito específico nunca
+	if (purpose == "") != (claims.TenantID != 0) || (purpose == "") != (claims.RoleID != 0) {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
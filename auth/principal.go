@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Métodos de autenticación de un Principal
+const (
+	MethodJWT      = "jwt"
+	MethodAPIToken = "api_token"
+)
+
+// principalKey es la clave del contexto de gin en la que AuthMiddleware guarda el Principal
+const principalKey = "auth.principal"
+
+// Principal identifica a quien realiza la petición, sea cual sea el tipo de credencial
+type Principal struct {
+	UserID     uint
+	Role       string
+	RoleID     uint      // rol en la organización en la que actúa; los permisos se resuelven por él
+	TenantID   uint      // organización en la que actúa
+	Method     string    // MethodJWT o MethodAPIToken
+	Scopes     []string  // scopes de la clave de API; vacío con JWT (acceso completo)
+	SessionID  uint      // sesión del JWT (0 si no tiene)
+	TokenID    string    // jti del JWT
+	ExpiresAt  time.Time // expiración del JWT
+	APITokenID uint      // ID de la clave de API
+	ActorID    uint      // administrador que suplanta al usuario (0 si no hay suplantación)
+}
+
+// IsAPIToken indica si la petición se autenticó con una clave de API
+func (p *Principal) IsAPIToken() bool {
+	return p.Method == MethodAPIToken
+}
+
+// IsImpersonated indica si un administrador está actuando en nombre del usuario
+func (p *Principal) IsImpersonated() bool {
+	return p.ActorID != 0
+}
+
+// SetPrincipal guarda el Principal autenticado en el contexto
+func SetPrincipal(c *gin.Context, principal *Principal) {
+	c.Set(principalKey, principal)
+}
+
+// CurrentUser retorna el Principal autenticado por AuthMiddleware
+func CurrentUser(c *gin.Context) (*Principal, bool) {
+	value, exists := c.Get(principalKey)
+	if !exists {
+		return nil, false
+	}
+
+	principal, ok := value.(*Principal)
+	return principal, ok && principal != nil
+}
+
+// CurrentUserID retorna el ID del usuario autenticado
+func CurrentUserID(c *gin.Context) (uint, bool) {
+	principal, ok := CurrentUser(c)
+	if !ok {
+		return 0, false
+	}
+	return principal.UserID, true
+}
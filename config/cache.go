@@ -0,0 +1,15 @@
+package config
+
+import (
+	"time"
+
+	"go-api-orm/services"
+)
+
+// Cache es la caché en memoria compartida por toda la aplicación
+var Cache *services.CacheService
+
+// InitCache inicializa la caché compartida
+func InitCache() {
+	Cache = services.NewCacheService(5*time.Minute, 10*time.Minute)
+}
@@ -10,6 +10,7 @@ import (
 	_ "github.com/lib/pq"
 	"go-api-orm/migrations"
 	"go-api-orm/models"
+	"go-api-orm/tenancy"
 	"go-api-orm/utils"
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
@@ -109,20 +110,50 @@ func InitDB() {
 		log.Fatalf("Error connecting to database: %v", err)
 	}
 
+	// Filtrar por organización las consultas que llevan una en su contexto
+	if err := DB.Use(tenancy.Plugin{}); err != nil {
+		log.Fatalf("Error registering tenancy plugin: %v", err)
+	}
+
 	// Auto-migrar los modelos
 	err = DB.AutoMigrate(
 		&models.User{},
 		&models.Post{},
 		&models.Role{},
+		&models.Permission{},
+		&models.RefreshToken{},
+		&models.RevokedToken{},
+		&models.PasswordResetToken{},
+		&models.RecoveryCode{},
+		&models.LoginAttempt{},
+		&models.APIToken{},
+		&models.UserIdentity{},
+		&models.Session{},
+		&models.PasswordHistory{},
+		&models.AuditLog{},
+		&models.Invitation{},
+		&models.Organization{},
+		&models.Membership{},
+		&models.PostRevision{},
+		&models.Tag{},
+		&models.Category{},
 	)
 	if err != nil {
 		log.Fatalf("Error auto-migrating database: %v", err)
 	}
 
+	// Las migraciones de datos operan sobre todas las organizaciones
+	seedDB := tenancy.Unscoped(DB)
+
 	// Crear roles por defecto
-	if err := migrations.SeedDefaultRoles(DB); err != nil {
+	if err := migrations.SeedDefaultRoles(seedDB); err != nil {
 		log.Printf("Error seeding default roles: %v", err)
 	}
+
+	// Crear la organización por defecto y asignarle los registros sin organización
+	if err := migrations.SeedDefaultOrganization(seedDB); err != nil {
+		log.Printf("Error seeding default organization: %v", err)
+	}
 }
 
 func connectMySQL(config *gorm.Config) (*gorm.DB, error) {
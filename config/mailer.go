@@ -0,0 +1,19 @@
+package config
+
+import (
+	"log"
+
+	"go-api-orm/services"
+)
+
+// Mailer envía los correos de la aplicación según MAIL_DRIVER
+var Mailer services.Mailer
+
+// InitMailer crea el Mailer una sola vez al iniciar: con MAIL_LOG_PATH mantiene el archivo abierto
+func InitMailer() {
+	mailer, err := services.NewMailerFromEnv()
+	if err != nil {
+		log.Fatalf("Error initializing mailer: %v", err)
+	}
+	Mailer = mailer
+}
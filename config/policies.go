@@ -0,0 +1,23 @@
+package config
+
+import (
+	"log"
+	"time"
+
+	"go-api-orm/policies"
+	"go-api-orm/utils"
+)
+
+// Policies es el motor de políticas cargado desde POLICY_FILE
+var Policies *policies.Engine
+
+// InitPolicies carga el fichero de políticas y lo recarga cada POLICY_RELOAD_SECONDS cuando cambia
+func InitPolicies() {
+	engine, err := policies.NewEngine(utils.GetEnvString("POLICY_FILE", ""))
+	if err != nil {
+		log.Fatalf("Error loading policy file: %v", err)
+	}
+
+	engine.Watch(time.Second * time.Duration(utils.GetEnvInt("POLICY_RELOAD_SECONDS", 5)))
+	Policies = engine
+}
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go-api-orm/config"
+	"go-api-orm/models"
+	"go-api-orm/services"
+)
+
+// CreateAPITokenInput representa los datos para crear una clave de API
+type CreateAPITokenInput struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+// ListAPITokens lista las claves de API de un usuario (el propio usuario o un administrador)
+func ListAPITokens(c *gin.Context) {
+	userID, ok := tokenOwnerID(c, true)
+	if !ok {
+		return
+	}
+
+	tokens, err := services.NewAPITokenService(config.DB, config.Cache).List(userID)
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": tokens})
+}
+
+// CreateAPIToken crea una clave de API para el usuario autenticado.
+// El valor en claro solo se muestra en esta respuesta.
+func CreateAPIToken(c *gin.Context) {
+	userID, ok := tokenOwnerID(c, false)
+	if !ok {
+		return
+	}
+
+	var input CreateAPITokenInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	token, record, err := services.NewAPITokenService(config.DB, config.Cache).Create(userID, input.Name, input.Scopes, input.ExpiresInDays)
+	if err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Clave de API creada. Guárdala ahora: no volverá a mostrarse",
+		"token":   token,
+		"data":    record,
+	})
+}
+
+// DeleteAPIToken revoca una clave de API (el propio usuario o un administrador)
+func DeleteAPIToken(c *gin.Context) {
+	userID, ok := tokenOwnerID(c, true)
+	if !ok {
+		return
+	}
+
+	tokenID, err := strconv.ParseUint(c.Param("token_id"), 10, 32)
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	if err := services.NewAPITokenService(config.DB, config.Cache).Revoke(userID, uint(tokenID)); err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Clave de API revocada correctamente"})
+}
+
+// tokenOwnerID valida que el usuario autenticado pueda gestionar las claves del usuario :id.
+// Los administradores solo pueden consultar y revocar claves ajenas, no crearlas.
+func tokenOwnerID(c *gin.Context, allowAdmin bool) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return 0, false
+	}
+
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return 0, false
+	}
+
+	if uint(id) != principal.UserID && !(allowAdmin && isAdmin(principal.RoleID)) {
+		status, response := services.ErrorResponse(services.ErrForbidden("No tienes permisos para gestionar las claves de este usuario"))
+		c.JSON(status, response)
+		return 0, false
+	}
+
+	// Un administrador solo gestiona las claves de los usuarios de su organización
+	if uint(id) != principal.UserID {
+		var count int64
+		if err := tenantDB(c).Model(&models.User{}).Where("id = ?", id).Count(&count).Error; err != nil || count == 0 {
+			status, response := services.ErrorResponse(services.ErrNotFound("Usuario"))
+			c.JSON(status, response)
+			return 0, false
+		}
+	}
+
+	return uint(id), true
+}
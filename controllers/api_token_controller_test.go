@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-api-orm/auth"
+	"go-api-orm/config"
+	"go-api-orm/middleware"
+	"go-api-orm/models"
+	"go-api-orm/services"
+	"go-api-orm/tenancy"
+)
+
+func TestAPITokensAreLimitedByScopeAndSession(t *testing.T) {
+	db := tenancy.Unscoped(newTestApp(t))
+
+	home, err := services.NewOrganizationService(db, config.Cache).Default()
+	if err != nil {
+		t.Fatalf("organización por defecto: %v", err)
+	}
+	role, err := services.DefaultRole(db)
+	if err != nil {
+		t.Fatalf("rol por defecto: %v", err)
+	}
+	ana := models.User{Username: "ana", Email: "ana@example.com", Password: "Secreta123!", RoleID: role.ID, TenantID: home.ID}
+	if err := db.Create(&ana).Error; err != nil {
+		t.Fatalf("crear usuario: %v", err)
+	}
+	tokens := services.NewAPITokenService(db, config.Cache)
+	keys := map[string]string{}
+	for _, scope := range []string{services.APITokenScopeRead, services.APITokenScopeWrite} {
+		plain, _, err := tokens.Create(ana.ID, scope, []string{scope}, 0)
+		if err != nil {
+			t.Fatalf("crear clave: %v", err)
+		}
+		keys[scope] = plain
+	}
+	expired, expiredToken, err := tokens.Create(ana.ID, "expirada", []string{services.APITokenScopeWrite}, 0)
+	if err != nil {
+		t.Fatalf("crear clave: %v", err)
+	}
+	if err := db.Model(&models.APIToken{}).Where("id = ?", expiredToken.ID).Update("expires_at", time.Now().Add(-time.Minute)).Error; err != nil {
+		t.Fatalf("expirar clave: %v", err)
+	}
+
+	router := gin.New()
+	router.Use(middleware.Tenant())
+	principal := func(c *gin.Context) {
+		principal, _ := auth.CurrentUser(c)
+		c.JSON(http.StatusOK, gin.H{"user_id": principal.UserID, "method": principal.Method, "tenant_id": principal.TenantID})
+	}
+	router.GET("/api/principal", middleware.AuthMiddleware(), principal)
+	router.POST("/api/principal", middleware.AuthMiddleware(), principal)
+	router.DELETE("/api/principal", middleware.AuthMiddleware(), principal)
+	router.PUT("/api/users/:id", middleware.AuthMiddleware(), middleware.RejectAPITokens(), middleware.RejectImpersonation(), UpdateUser)
+
+	for _, tc := range []struct {
+		name   string
+		method string
+		key    string
+		status int
+		code   string
+	}{
+		{"read permite GET", http.MethodGet, keys["read"], http.StatusOK, ""},
+		{"read no permite POST", http.MethodPost, keys["read"], http.StatusForbidden, "INSUFFICIENT_SCOPE"},
+		{"read no permite DELETE", http.MethodDelete, keys["read"], http.StatusForbidden, "INSUFFICIENT_SCOPE"},
+		{"write permite GET", http.MethodGet, keys["write"], http.StatusOK, ""},
+		{"write permite POST", http.MethodPost, keys["write"], http.StatusOK, ""},
+		{"una clave expirada", http.MethodGet, expired, http.StatusUnauthorized, "UNAUTHORIZED"},
+		{"una clave desconocida", http.MethodGet, "pat_desconocida", http.StatusUnauthorized, "UNAUTHORIZED"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			status, body := requestJSON(t, router, tc.method, "/api/principal", nil, bearer(tc.key))
+			if status != tc.status {
+				t.Fatalf("status = %d, se esperaba %d: %v", status, tc.status, body)
+			}
+			if tc.code != "" && errorCode(body) != tc.code {
+				t.Errorf("código = %v, se esperaba %s", errorCode(body), tc.code)
+			}
+			if tc.status == http.StatusOK && (body["method"] != auth.MethodAPIToken || body["user_id"] != float64(ana.ID) || body["tenant_id"] != float64(home.ID)) {
+				t.Errorf("principal = %v", body)
+			}
+		})
+	}
+
+	// Ni siquiera una clave write puede editar el perfil, que requiere una sesión interactiva
+	path := fmt.Sprintf("/api/users/%d", ana.ID)
+	if status, body := requestJSON(t, router, http.MethodPut, path, map[string]string{"username": "otra"}, bearer(keys["write"])); status != http.StatusForbidden {
+		t.Errorf("PUT %s con una clave de API = %d: %v", path, status, body)
+	}
+
+	// La clave deja de servir cuando se elimina el usuario
+	if err := db.Delete(&ana).Error; err != nil {
+		t.Fatalf("eliminar usuario: %v", err)
+	}
+	if status, body := getJSON(t, router, "/api/principal", bearer(keys["read"])); status != http.StatusUnauthorized {
+		t.Errorf("clave de un usuario eliminado = %d: %v", status, body)
+	}
+}
@@ -0,0 +1,223 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-api-orm/auth"
+	"go-api-orm/config"
+	"go-api-orm/models"
+	"go-api-orm/services"
+	"go-api-orm/tenancy"
+	"go-api-orm/utils"
+)
+
+// RefreshTokenInput representa los datos necesarios para refrescar el token de acceso
+type RefreshTokenInput struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// RefreshToken intercambia un token de refresco por un nuevo token de acceso
+// y un nuevo token de refresco (rotación)
+func RefreshToken(c *gin.Context) {
+	var input RefreshTokenInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	refreshService := services.NewRefreshTokenService(config.DB)
+	refreshToken, previous, err := refreshService.Rotate(input.RefreshToken)
+	if err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	var user models.User
+	if err := tenancy.Unscoped(config.DB).Preload("Role").First(&user, previous.UserID).Error; err != nil {
+		refreshService.RevokeFamily(previous.FamilyID)
+		status, response := services.ErrorResponse(services.ErrInvalidRefreshToken())
+		c.JSON(status, response)
+		return
+	}
+
+	// Mantener la sesión del token de refresco original
+	var sessionID uint
+	if previous.SessionID != nil {
+		sessionID = *previous.SessionID
+		if err := services.NewSessionService(config.DB, config.Cache).Extend(sessionID, c.ClientIP()); err != nil {
+			status, response := services.ErrorResponse(services.ErrInternal(err))
+			c.JSON(status, response)
+			return
+		}
+	}
+
+	tenantID, role, err := activeOrganization(&user, sessionID)
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+
+	token, err := auth.IssueAccessToken(user.ID, tenantID, role.Name, role.ID, sessionID)
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":         token,
+		"refresh_token": refreshToken,
+		"expires_in":    int(auth.AccessTokenTTL().Seconds()),
+	})
+}
+
+// activeOrganization retorna la organización activa de la sesión y el rol del usuario en ella.
+// Si el usuario perdió el acceso a esa organización la sesión vuelve a su organización propia.
+func activeOrganization(user *models.User, sessionID uint) (uint, *models.Role, error) {
+	if sessionID == 0 {
+		return user.TenantID, &user.Role, nil
+	}
+
+	sessionService := services.NewSessionService(config.DB, config.Cache)
+	organizationID, err := sessionService.Organization(sessionID)
+	if err != nil || organizationID == 0 {
+		return user.TenantID, &user.Role, err
+	}
+
+	role, err := services.NewOrganizationService(config.DB, config.Cache).Access(user, organizationID)
+	if err != nil {
+		if _, ok := err.(*services.APIError); !ok {
+			return 0, nil, err
+		}
+		return user.TenantID, &user.Role, sessionService.SetOrganization(sessionID, 0)
+	}
+	return organizationID, role, nil
+}
+
+// LogoutInput permite revocar opcionalmente el token de refresco junto al de acceso
+type LogoutInput struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Logout revoca el token de acceso actual, cierra su sesión y, si se envía, revoca el token de refresco
+func Logout(c *gin.Context) {
+	var input LogoutInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+			c.JSON(status, response)
+			return
+		}
+	}
+
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+	expiresAt := principal.ExpiresAt
+	if expiresAt.IsZero() {
+		expiresAt = time.Now().Add(auth.AccessTokenTTL())
+	}
+
+	revocationService := services.NewTokenRevocationService(config.DB, config.Cache)
+	if err := revocationService.RevokeToken(principal.TokenID, principal.UserID, expiresAt); err != nil {
+		// Solo un token sin jti es un error del cliente; el resto son fallos internos
+		apiErr := services.ErrInternal(err)
+		if errors.Is(err, services.ErrMissingJTI) {
+			apiErr = services.ErrInvalidInput(err.Error())
+		}
+		status, response := services.ErrorResponse(apiErr)
+		c.JSON(status, response)
+		return
+	}
+
+	if input.RefreshToken != "" {
+		if err := services.NewRefreshTokenService(config.DB).Revoke(input.RefreshToken); err != nil {
+			status, response := services.ErrorResponse(services.ErrInternal(err))
+			c.JSON(status, response)
+			return
+		}
+	}
+
+	// Cerrar la sesión actual (revoca también sus tokens de refresco)
+	if principal.SessionID != 0 {
+		if err := services.NewSessionService(config.DB, config.Cache).Revoke(principal.UserID, principal.SessionID); err != nil {
+			status, response := services.ErrorResponse(err)
+			c.JSON(status, response)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Sesión cerrada correctamente"})
+}
+
+// RevokeUserTokens revoca todos los tokens emitidos a un usuario (solo administradores)
+func RevokeUserTokens(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	var user models.User
+	if err := tenantDB(c).First(&user, id).Error; err != nil {
+		status, response := services.ErrorResponse(services.ErrNotFound("Usuario"))
+		c.JSON(status, response)
+		return
+	}
+
+	revocationService := services.NewTokenRevocationService(config.DB, config.Cache)
+	if err := revocationService.RevokeAllForUser(user.ID); err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tokens del usuario revocados correctamente"})
+}
+
+// UnlockUser elimina el bloqueo por intentos de login fallidos de una cuenta (solo administradores)
+func UnlockUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	var user models.User
+	if err := tenantDB(c).First(&user, id).Error; err != nil {
+		status, response := services.ErrorResponse(services.ErrNotFound("Usuario"))
+		c.JSON(status, response)
+		return
+	}
+
+	if err := services.NewLoginThrottleService(config.DB, config.Cache).Unlock(user.Email); err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Cuenta desbloqueada correctamente"})
+}
+
+// GetJWKS publica las claves públicas usadas para verificar los tokens
+func GetJWKS(c *gin.Context) {
+	jwks, err := utils.GetJWKS()
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, jwks)
+}
@@ -0,0 +1,230 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go-api-orm/models"
+	"go-api-orm/serializers"
+	"go-api-orm/services"
+)
+
+// CreateCategoryInput representa los datos necesarios para crear una categoría
+type CreateCategoryInput struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Slug        string `json:"slug" binding:"max=150"` // opcional; se genera a partir del nombre
+	Description string `json:"description" binding:"max=255"`
+	ParentID    *uint  `json:"parent_id"` // categoría de la que es subcategoría
+}
+
+// UpdateCategoryInput representa los datos que se pueden actualizar de una categoría
+type UpdateCategoryInput struct {
+	Name        string `json:"name" binding:"max=100"`
+	Slug        string `json:"slug" binding:"max=150"`
+	Description string `json:"description" binding:"max=255"`
+	ParentID    *uint  `json:"parent_id"` // 0 la convierte en una categoría raíz
+}
+
+// CreateCategory crea una categoría, opcionalmente como subcategoría de otra
+func CreateCategory(c *gin.Context) {
+	var input CreateCategoryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	taxonomy := services.NewTaxonomyService(tenantDB(c))
+	if input.Slug != "" {
+		if err := taxonomy.EnsureSlugAvailable(&models.Category{}, input.Slug, 0); err != nil {
+			status, response := services.ErrorResponse(err)
+			c.JSON(status, response)
+			return
+		}
+	} else if models.GenerateSlug(input.Name) == "" {
+		status, response := services.ErrorResponse(services.ErrInvalidInput("El nombre debe contener letras o números"))
+		c.JSON(status, response)
+		return
+	}
+
+	if input.ParentID != nil && *input.ParentID == 0 {
+		input.ParentID = nil
+	}
+	if err := taxonomy.ValidateParent(0, input.ParentID); err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	category := models.Category{
+		Name:        input.Name,
+		Slug:        input.Slug, // Si está vacío, el hook BeforeCreate generará uno
+		Description: input.Description,
+		ParentID:    input.ParentID,
+	}
+
+	if err := tenantDB(c).Create(&category).Error; err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+
+	respondTaxonomy(c, http.StatusCreated, serializers.Category, category)
+}
+
+// GetCategories obtiene las categorías con paginación y filtros
+func GetCategories(c *gin.Context) {
+	var categories []models.Category
+
+	// Obtener parámetros de paginación y búsqueda
+	pagination := services.GeneratePaginationFromRequest(c)
+	searchFilters := services.ExtractSearchParams(c)
+	sortParams := services.ExtractSortParams(c)
+	searchFilters, sortParams = visibleFilters(c, serializers.Category, searchFilters, sortParams)
+
+	// Aplicar filtros y paginación
+	db := tenantDB(c)
+	db = services.ApplySearchFilters(db, searchFilters)
+	db = services.ApplySorting(db, sortParams)
+
+	err := db.Scopes(services.Paginate(categories, &pagination, db)).Find(&categories).Error
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+
+	// Construir los componentes de la respuesta
+	paginationResponse := services.BuildPaginationResponse(c, pagination.Page, pagination.Limit, pagination.TotalRows, pagination.TotalPages)
+
+	metadataResponse := services.BuildMetadataResponse(
+		[]services.SearchField{
+			{
+				Name:        "name",
+				Type:        "string",
+				Description: "Nombre de la categoría",
+				Operators:   []string{"eq", "like", "nlike"},
+			},
+			{
+				Name:        "slug",
+				Type:        "string",
+				Description: "Slug de la categoría",
+				Operators:   []string{"eq", "in"},
+			},
+			{
+				Name:        "parent_id",
+				Type:        "number",
+				Description: "ID de la categoría padre",
+				Operators:   []string{"eq", "in"},
+			},
+		},
+		[]services.SortField{
+			{
+				Name:        "name",
+				Description: "Ordenar por nombre",
+			},
+			{
+				Name:        "created_at",
+				Description: "Ordenar por fecha de creación",
+			},
+		},
+		searchFilters,
+		sortParams,
+	)
+
+	// Construir la respuesta final
+	data, ok := serialize(c, serializers.Category, categories)
+	if !ok {
+		return
+	}
+	response := services.BuildAPIResponse(data, metadataResponse, paginationResponse)
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetCategory obtiene una categoría por su slug con su categoría padre y sus subcategorías
+func GetCategory(c *gin.Context) {
+	var category models.Category
+	if err := tenantDB(c).Preload("Parent").Preload("Children").Where("slug = ?", c.Param("slug")).First(&category).Error; err != nil {
+		status, response := services.ErrorResponse(services.ErrNotFound("Categoría"))
+		c.JSON(status, response)
+		return
+	}
+
+	respondTaxonomy(c, http.StatusOK, serializers.Category, category)
+}
+
+// UpdateCategory actualiza una categoría. El slug no cambia al renombrarla.
+func UpdateCategory(c *gin.Context) {
+	var category models.Category
+	if err := tenantDB(c).Where("slug = ?", c.Param("slug")).First(&category).Error; err != nil {
+		status, response := services.ErrorResponse(services.ErrNotFound("Categoría"))
+		c.JSON(status, response)
+		return
+	}
+
+	var input UpdateCategoryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	taxonomy := services.NewTaxonomyService(tenantDB(c))
+
+	// Actualizar solo los campos proporcionados
+	updates := map[string]interface{}{}
+	if input.Name != "" {
+		updates["name"] = input.Name
+	}
+	if input.Description != "" {
+		updates["description"] = input.Description
+	}
+	if input.Slug != "" {
+		if err := taxonomy.EnsureSlugAvailable(&models.Category{}, input.Slug, category.ID); err != nil {
+			status, response := services.ErrorResponse(err)
+			c.JSON(status, response)
+			return
+		}
+		updates["slug"] = input.Slug
+	}
+	if input.ParentID != nil {
+		if *input.ParentID == 0 {
+			updates["parent_id"] = nil
+		} else {
+			if err := taxonomy.ValidateParent(category.ID, input.ParentID); err != nil {
+				status, response := services.ErrorResponse(err)
+				c.JSON(status, response)
+				return
+			}
+			updates["parent_id"] = *input.ParentID
+		}
+	}
+
+	if err := tenantDB(c).Model(&category).Updates(updates).Error; err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+
+	respondTaxonomy(c, http.StatusOK, serializers.Category, category)
+}
+
+// DeleteCategory elimina una categoría y la quita de sus posts. Sus subcategorías pasan
+// a depender de la categoría padre de la eliminada.
+func DeleteCategory(c *gin.Context) {
+	var category models.Category
+	if err := tenantDB(c).Where("slug = ?", c.Param("slug")).First(&category).Error; err != nil {
+		status, response := services.ErrorResponse(services.ErrNotFound("Categoría"))
+		c.JSON(status, response)
+		return
+	}
+
+	if err := services.NewTaxonomyService(tenantDB(c)).DeleteCategory(&category); err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Categoría eliminada correctamente"})
+}
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-api-orm/config"
+	"go-api-orm/models"
+	"go-api-orm/services"
+)
+
+// VerifyEmailInput representa los datos para verificar un email
+type VerifyEmailInput struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ResendVerificationInput representa los datos para reenviar el enlace de verificación
+type ResendVerificationInput struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// VerifyEmail marca el email del usuario como verificado a partir del enlace firmado
+func VerifyEmail(c *gin.Context) {
+	var input VerifyEmailInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	user, err := services.NewEmailVerificationService(config.DB, nil).Verify(input.Token)
+	if err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Email verificado correctamente",
+		"verified_at": user.VerifiedAt,
+	})
+}
+
+// ResendVerificationEmail reenvía el enlace de verificación.
+// Siempre responde lo mismo para no revelar si el email está registrado.
+func ResendVerificationEmail(c *gin.Context) {
+	var input ResendVerificationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	// Limitar los reenvíos a uno por minuto por email
+	cacheKey := "email_verification_resend:" + strings.ToLower(input.Email)
+	if _, found := config.Cache.Get(cacheKey); !found {
+		config.Cache.SetWithTTL(cacheKey, true, time.Minute)
+
+		if err := services.NewEmailVerificationService(config.DB, config.Mailer).Resend(input.Email); err != nil {
+			log.Printf("Error resending verification email: %v", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Si la cuenta existe y no está verificada, recibirás un nuevo enlace"})
+}
+
+// sendVerificationEmail envía el enlace de verificación sin interrumpir la petición si el envío falla
+func sendVerificationEmail(user *models.User) {
+	if err := services.NewEmailVerificationService(config.DB, config.Mailer).SendVerification(user); err != nil {
+		log.Printf("Error sending verification email: %v", err)
+	}
+}
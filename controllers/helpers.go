@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"log"
+
+	"github.com/gin-gonic/gin"
+	"go-api-orm/auth"
+	"go-api-orm/config"
+	"go-api-orm/models"
+	"go-api-orm/serializers"
+	"go-api-orm/services"
+	"go-api-orm/tenancy"
+	"gorm.io/gorm"
+)
+
+// currentPrincipal obtiene el Principal autenticado o responde con un error
+func currentPrincipal(c *gin.Context) (*auth.Principal, bool) {
+	principal, ok := auth.CurrentUser(c)
+	if !ok {
+		status, response := services.ErrorResponse(services.ErrUnauthorized("Usuario no autenticado"))
+		c.JSON(status, response)
+		return nil, false
+	}
+
+	return principal, true
+}
+
+// loadCurrentUser carga el usuario autenticado con su rol o responde con un error
+func loadCurrentUser(c *gin.Context) (*models.User, bool) {
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return nil, false
+	}
+
+	var user models.User
+	if err := tenancy.Unscoped(config.DB).Preload("Role").First(&user, principal.UserID).Error; err != nil {
+		status, response := services.ErrorResponse(services.ErrNotFound("Usuario"))
+		c.JSON(status, response)
+		return nil, false
+	}
+
+	return &user, true
+}
+
+// tenantDB retorna la conexión con la organización de la petición, de modo que las
+// consultas sobre modelos con TenantID se limiten a ella
+func tenantDB(c *gin.Context) *gorm.DB {
+	return config.DB.WithContext(c.Request.Context())
+}
+
+// currentViewer retorna el usuario que recibe la respuesta, para aplicar la visibilidad de los
+// campos. Sin autenticación retorna un Viewer anónimo.
+func currentViewer(c *gin.Context) serializers.Viewer {
+	principal, ok := auth.CurrentUser(c)
+	if !ok {
+		return serializers.Viewer{}
+	}
+
+	return serializers.Viewer{UserID: principal.UserID, Admin: isAdmin(principal.RoleID)}
+}
+
+// isAdmin indica si el rol es admin o hereda de él según la jerarquía de roles
+func isAdmin(roleID uint) bool {
+	admin, err := services.NewRoleService(tenancy.Unscoped(config.DB), config.Cache).InheritsFrom(roleID, services.AdminRole)
+	if err != nil {
+		log.Printf("Error resolving role %d: %v", roleID, err)
+	}
+	return admin
+}
+
+// serialize aplica el serializador del recurso para el usuario de la petición o responde con un error
+func serialize(c *gin.Context, serializer *serializers.Serializer, value interface{}) (interface{}, bool) {
+	data, err := serializer.Serialize(currentViewer(c), value)
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return nil, false
+	}
+	return data, true
+}
+
+// visibleFilters descarta los filtros y el orden por campos que el usuario no puede ver,
+// que permitirían deducir su valor. Los filtros por relaciones públicas se conservan.
+func visibleFilters(c *gin.Context, serializer *serializers.Serializer, filters []map[string]string, sort map[string]string, relations ...services.SearchRelation) ([]map[string]string, map[string]string) {
+	viewer := currentViewer(c)
+
+	public := map[string]bool{}
+	for _, relation := range relations {
+		public[relation.Field] = true
+	}
+
+	visible := make([]map[string]string, 0, len(filters))
+	for _, filter := range filters {
+		if public[filter["field"]] || serializer.Filterable(viewer, filter["field"]) {
+			visible = append(visible, filter)
+		}
+	}
+	for field := range sort {
+		if !serializer.Filterable(viewer, field) {
+			delete(sort, field)
+		}
+	}
+	return visible, sort
+}
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-api-orm/config"
+	"go-api-orm/migrations"
+	"go-api-orm/models"
+	"go-api-orm/services"
+	"go-api-orm/tenancy"
+	"go-api-orm/utils"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestApp prepara config.DB y config.Cache como config.InitDB y config.InitCache, sobre
+// una base de datos SQLite temporal, y las claves para firmar tokens con JWT_SECRET_KEY.
+// Activa SHOW_PAGINATION para poder comprobar el total de las respuestas paginadas.
+func newTestApp(t *testing.T) *gorm.DB {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	t.Setenv("JWT_SECRET_KEY", "controllers-test-secret")
+	t.Setenv("SHOW_PAGINATION", "true")
+	if err := utils.InitJWTKeys(); err != nil {
+		t.Fatalf("InitJWTKeys: %v", err)
+	}
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if err := db.Use(tenancy.Plugin{}); err != nil {
+		t.Fatalf("tenancy plugin: %v", err)
+	}
+	if err := db.AutoMigrate(
+		&models.User{},
+		&models.Post{},
+		&models.Role{},
+		&models.Permission{},
+		&models.RefreshToken{},
+		&models.RevokedToken{},
+		&models.PasswordResetToken{},
+		&models.RecoveryCode{},
+		&models.LoginAttempt{},
+		&models.APIToken{},
+		&models.UserIdentity{},
+		&models.Session{},
+		&models.PasswordHistory{},
+		&models.AuditLog{},
+		&models.Invitation{},
+		&models.Organization{},
+		&models.Membership{},
+		&models.PostRevision{},
+		&models.Tag{},
+		&models.Category{},
+	); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if err := migrations.SeedDefaultRoles(tenancy.Unscoped(db)); err != nil {
+		t.Fatalf("seed roles: %v", err)
+	}
+	if err := migrations.SeedDefaultOrganization(tenancy.Unscoped(db)); err != nil {
+		t.Fatalf("seed organization: %v", err)
+	}
+
+	previousDB, previousCache := config.DB, config.Cache
+	config.DB = db
+	config.Cache = services.NewCacheService(time.Minute, 0)
+	t.Cleanup(func() {
+		config.DB, config.Cache = previousDB, previousCache
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// getJSON hace una petición GET al router y decodifica la respuesta
+func getJSON(t *testing.T, router http.Handler, path string, headers map[string]string) (int, map[string]interface{}) {
+	t.Helper()
+	return requestJSON(t, router, http.MethodGet, path, nil, headers)
+}
+
+// requestJSON hace una petición al router con el cuerpo codificado en JSON y decodifica la respuesta
+func requestJSON(t *testing.T, router http.Handler, method, path string, payload interface{}, headers map[string]string) (int, map[string]interface{}) {
+	t.Helper()
+
+	var reader io.Reader
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			t.Fatalf("codificar %v: %v", payload, err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	request := httptest.NewRequest(method, path, reader)
+	request.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("%s %s: respuesta no JSON %q: %v", method, path, recorder.Body.String(), err)
+	}
+	return recorder.Code, body
+}
@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go-api-orm/auth"
+	"go-api-orm/config"
+	"go-api-orm/models"
+	"go-api-orm/services"
+	"go-api-orm/tenancy"
+)
+
+// StartImpersonation emite un token para actuar en nombre del usuario :id (solo administradores).
+// El token lleva el claim act con el administrador, dura IMPERSONATION_EXPIRATION_MINUTES
+// y queda vinculado a la sesión del administrador.
+func StartImpersonation(c *gin.Context) {
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	if uint(id) == principal.UserID {
+		status, response := services.ErrorResponse(services.ErrInvalidInput("No puedes suplantarte a ti mismo"))
+		c.JSON(status, response)
+		return
+	}
+
+	// Solo se puede suplantar a usuarios de la organización en la que actúa el administrador
+	var user models.User
+	if err := tenantDB(c).Preload("Role").First(&user, id).Error; err != nil {
+		status, response := services.ErrorResponse(services.ErrNotFound("Usuario"))
+		c.JSON(status, response)
+		return
+	}
+
+	// Suplantar a otro administrador no permitiría reproducir nada que el actor no pueda hacer ya
+	if isAdmin(user.RoleID) {
+		status, response := services.ErrorResponse(services.ErrForbidden("No se puede suplantar a otro administrador"))
+		c.JSON(status, response)
+		return
+	}
+
+	token, err := auth.IssueImpersonationToken(user.ID, principal.TenantID, user.Role.Name, user.RoleID, principal.UserID, principal.SessionID)
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+
+	recordImpersonation(c, services.AuditImpersonationStart, principal.UserID, user.ID, http.StatusOK)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Suplantación iniciada",
+		"token":      token,
+		"expires_in": int(auth.ImpersonationTTL().Seconds()),
+		"actor_id":   principal.UserID,
+		"user": gin.H{
+			"id":       user.ID,
+			"username": user.Username,
+			"email":    user.Email,
+			"role":     user.Role.Name,
+		},
+	})
+}
+
+// EndImpersonation revoca el token de suplantación y devuelve un token de acceso
+// del administrador vinculado a su misma sesión
+func EndImpersonation(c *gin.Context) {
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	if !principal.IsImpersonated() {
+		status, response := services.ErrorResponse(services.ErrInvalidInput("No hay ninguna suplantación activa"))
+		c.JSON(status, response)
+		return
+	}
+
+	if err := services.NewTokenRevocationService(config.DB, config.Cache).RevokeToken(principal.TokenID, principal.UserID, principal.ExpiresAt); err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+
+	var actor models.User
+	if err := tenancy.Unscoped(config.DB).Preload("Role").First(&actor, principal.ActorID).Error; err != nil {
+		status, response := services.ErrorResponse(services.ErrUnauthorized("El administrador ya no existe"))
+		c.JSON(status, response)
+		return
+	}
+
+	// El administrador vuelve a la organización en la que actuaba, con su rol en ella
+	role, err := services.NewOrganizationService(config.DB, config.Cache).Access(&actor, principal.TenantID)
+	if err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	token, err := auth.IssueAccessToken(actor.ID, principal.TenantID, role.Name, role.ID, principal.SessionID)
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+
+	recordImpersonation(c, services.AuditImpersonationEnd, actor.ID, principal.UserID, http.StatusOK)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Suplantación finalizada",
+		"token":      token,
+		"expires_in": int(auth.AccessTokenTTL().Seconds()),
+		"session_id": principal.SessionID,
+		"user": gin.H{
+			"id":       actor.ID,
+			"username": actor.Username,
+			"email":    actor.Email,
+			"role":     role.Name,
+		},
+	})
+}
+
+// recordImpersonation registra el inicio o el fin de una suplantación en el historial de auditoría
+func recordImpersonation(c *gin.Context, action string, actorID, userID uint, status int) {
+	err := services.NewAuditService(config.DB).Record(&models.AuditLog{
+		ActorID:   actorID,
+		UserID:    userID,
+		Action:    action,
+		Method:    c.Request.Method,
+		Path:      c.Request.URL.Path,
+		Status:    status,
+		IPAddress: c.ClientIP(),
+	})
+	if err != nil {
+		log.Printf("Error recording impersonation audit log: %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go-api-orm/auth"
+	"go-api-orm/config"
+	"go-api-orm/middleware"
+	"go-api-orm/models"
+	"go-api-orm/services"
+	"go-api-orm/tenancy"
+)
+
+func TestImpersonatedRequestsAreMarkedAndRestricted(t *testing.T) {
+	db := tenancy.Unscoped(newTestApp(t))
+
+	home, err := services.NewOrganizationService(db, config.Cache).Default()
+	if err != nil {
+		t.Fatalf("organización por defecto: %v", err)
+	}
+	var admin models.Role
+	if err := db.Where("name = ? AND tenant_id = 0", services.AdminRole).First(&admin).Error; err != nil {
+		t.Fatalf("rol admin: %v", err)
+	}
+	member, err := services.DefaultRole(db)
+	if err != nil {
+		t.Fatalf("rol por defecto: %v", err)
+	}
+	ana := models.User{Username: "ana", Email: "ana@example.com", Password: "Secreta123!", RoleID: admin.ID, TenantID: home.ID}
+	carl := models.User{Username: "carl", Email: "carl@example.com", Password: "Secreta123!", RoleID: member.ID, TenantID: home.ID}
+	for _, user := range []*models.User{&ana, &carl} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("crear usuario: %v", err)
+		}
+	}
+	impersonation, err := auth.IssueImpersonationToken(carl.ID, home.ID, member.Name, member.ID, ana.ID, 0)
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+	carlToken, err := auth.IssueAccessToken(carl.ID, home.ID, member.Name, member.ID, 0)
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+
+	router := gin.New()
+	router.Use(middleware.Tenant())
+	protected := router.Group("/api", middleware.AuthMiddleware())
+	protected.GET("/principal", func(c *gin.Context) {
+		principal, _ := auth.CurrentUser(c)
+		c.JSON(http.StatusOK, gin.H{"user_id": principal.UserID})
+	})
+	protected.GET("/empty", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{}) })
+	protected.GET("/list", func(c *gin.Context) { c.JSON(http.StatusOK, []int{1, 2}) })
+	protected.GET("/text", func(c *gin.Context) { c.String(http.StatusOK, "texto") })
+	protected.POST("/logout", middleware.RejectAPITokens(), middleware.RejectImpersonation(), Logout)
+	protected.PUT("/users/me/password", middleware.RejectAPITokens(), middleware.RejectImpersonation(), ChangePassword)
+	protected.PUT("/users/:id", middleware.RejectAPITokens(), middleware.RejectImpersonation(), UpdateUser)
+
+	actor := float64(ana.ID)
+	status, body := getJSON(t, router, "/api/principal", bearer(impersonation))
+	if status != http.StatusOK || body["user_id"] != float64(carl.ID) || body[middleware.ImpersonationField] != actor {
+		t.Errorf("respuesta suplantada = %d: %v", status, body)
+	}
+	if _, body := getJSON(t, router, "/api/empty", bearer(impersonation)); len(body) != 1 || body[middleware.ImpersonationField] != actor {
+		t.Errorf("objeto vacío suplantado = %v", body)
+	}
+
+	// La cabecera marca cualquier respuesta; el cuerpo solo se modifica si es un objeto JSON
+	for path, expected := range map[string]string{"/api/list": "[1,2]", "/api/text": "texto"} {
+		request := httptest.NewRequest(http.MethodGet, path, nil)
+		request.Header.Set("Authorization", "Bearer "+impersonation)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		if recorder.Body.String() != expected || recorder.Header().Get(middleware.ImpersonationHeader) != strconv.Itoa(int(ana.ID)) {
+			t.Errorf("%s = %q (cabecera %q)", path, recorder.Body.String(), recorder.Header().Get(middleware.ImpersonationHeader))
+		}
+	}
+
+	// Sin suplantación la respuesta no se marca
+	request := httptest.NewRequest(http.MethodGet, "/api/principal", nil)
+	request.Header.Set("Authorization", "Bearer "+carlToken)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	if recorder.Header().Get(middleware.ImpersonationHeader) != "" || recorder.Body.String() != fmt.Sprintf(`{"user_id":%d}`, carl.ID) {
+		t.Errorf("respuesta sin suplantación = %q (cabecera %q)", recorder.Body.String(), recorder.Header().Get(middleware.ImpersonationHeader))
+	}
+
+	// Cerrar la sesión y editar el perfil o la contraseña no están disponibles durante la suplantación
+	for _, tc := range []struct {
+		method  string
+		path    string
+		payload interface{}
+	}{
+		{http.MethodPost, "/api/logout", nil},
+		{http.MethodPut, fmt.Sprintf("/api/users/%d", carl.ID), map[string]string{"email": "otro@example.com"}},
+		{http.MethodPut, "/api/users/me/password", map[string]string{"current_password": "Secreta123!", "new_password": "OtraClave456?"}},
+	} {
+		status, body := requestJSON(t, router, tc.method, tc.path, tc.payload, bearer(impersonation))
+		if status != http.StatusForbidden || errorCode(body) != "FORBIDDEN" || body[middleware.ImpersonationField] != actor {
+			t.Errorf("%s %s suplantado = %d: %v", tc.method, tc.path, status, body)
+		}
+	}
+	var stored models.User
+	if err := db.First(&stored, carl.ID).Error; err != nil || stored.Email != carl.Email {
+		t.Errorf("el email de carl cambió durante la suplantación (%v, %v)", stored.Email, err)
+	}
+
+	// Cada petición suplantada queda en el historial de auditoría, también las rechazadas
+	var audited []models.AuditLog
+	if err := db.Where("action = ? AND actor_id = ? AND user_id = ?", services.AuditImpersonationRequest, ana.ID, carl.ID).Find(&audited).Error; err != nil {
+		t.Fatalf("leer auditoría: %v", err)
+	}
+	if len(audited) != 7 {
+		t.Errorf("se auditaron %d peticiones suplantadas, se esperaban 7", len(audited))
+	}
+	for _, entry := range audited {
+		if entry.Path == "/api/logout" && entry.Status != http.StatusForbidden {
+			t.Errorf("auditoría de /api/logout con status %d", entry.Status)
+		}
+	}
+}
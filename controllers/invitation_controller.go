@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go-api-orm/auth"
+	"go-api-orm/config"
+	"go-api-orm/serializers"
+	"go-api-orm/services"
+)
+
+// CreateInvitationInput representa los datos de una invitación
+type CreateInvitationInput struct {
+	Email  string `json:"email" binding:"required,email"`
+	RoleID uint   `json:"role_id" binding:"required"`
+}
+
+// CreateInvitation invita a un email a registrarse con el rol indicado (solo administradores).
+// El enlace con el token se envía por correo.
+func CreateInvitation(c *gin.Context) {
+	var input CreateInvitationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	principal, _ := auth.CurrentUser(c)
+
+	invitation, err := services.NewInvitationService(tenantDB(c), config.Mailer).Invite(input.Email, input.RoleID, principal.UserID)
+	if err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	data, ok := serialize(c, serializers.Invitation, invitation)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Invitación enviada correctamente",
+		"data":    data,
+	})
+}
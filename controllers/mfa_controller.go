@@ -0,0 +1,284 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-api-orm/auth"
+	"go-api-orm/config"
+	"go-api-orm/models"
+	"go-api-orm/services"
+	"go-api-orm/tenancy"
+)
+
+const maxMFAAttempts = 5
+
+// MFATokenInput representa el token intermedio entregado por Login
+type MFATokenInput struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+}
+
+// MFACodeInput representa un código TOTP o de recuperación
+type MFACodeInput struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// MFALoginInput representa los datos para completar un login en dos pasos
+type MFALoginInput struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// LoginMFA completa el login de un usuario con la autenticación en dos pasos activada
+func LoginMFA(c *gin.Context) {
+	var input MFALoginInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	user, claims, err := userFromMFAToken(input.MFAToken, services.MFAPendingPurpose)
+	if err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	if err := verifyMFAAttempt(c, user, claims, func() error {
+		return services.NewTwoFactorService(config.DB).Verify(user, input.Code)
+	}); err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	response, err := loginResponse(c, user)
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+	recordLoginSuccess(user.Email)
+
+	c.JSON(http.StatusOK, response)
+}
+
+// LoginMFAEnroll inicia la inscripción obligatoria de un usuario que aún no tiene segundo factor
+func LoginMFAEnroll(c *gin.Context) {
+	var input MFATokenInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	user, _, err := userFromMFAToken(input.MFAToken, services.MFAEnrollmentPurpose)
+	if err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	beginMFAEnrollment(c, user)
+}
+
+// LoginMFAEnrollConfirm confirma la inscripción obligatoria y completa el login
+func LoginMFAEnrollConfirm(c *gin.Context) {
+	var input MFALoginInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	user, claims, err := userFromMFAToken(input.MFAToken, services.MFAEnrollmentPurpose)
+	if err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	var recoveryCodes []string
+	if err := verifyMFAAttempt(c, user, claims, func() error {
+		var err error
+		recoveryCodes, err = services.NewTwoFactorService(config.DB).ConfirmEnrollment(user, input.Code)
+		return err
+	}); err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	response, err := loginResponse(c, user)
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+	response["recovery_codes"] = recoveryCodes
+	recordLoginSuccess(user.Email)
+
+	c.JSON(http.StatusOK, response)
+}
+
+// EnrollMFA inicia la inscripción del usuario autenticado en la autenticación en dos pasos
+func EnrollMFA(c *gin.Context) {
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	beginMFAEnrollment(c, user)
+}
+
+// ConfirmMFA activa la autenticación en dos pasos del usuario autenticado
+func ConfirmMFA(c *gin.Context) {
+	var input MFACodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	recoveryCodes, err := services.NewTwoFactorService(config.DB).ConfirmEnrollment(user, input.Code)
+	if err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Autenticación en dos pasos activada",
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+// DisableMFA desactiva la autenticación en dos pasos del usuario autenticado
+func DisableMFA(c *gin.Context) {
+	var input MFACodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	if services.IsMFARequiredForRole(user.Role.Name) {
+		status, response := services.ErrorResponse(services.ErrForbidden("La autenticación en dos pasos es obligatoria para tu rol"))
+		c.JSON(status, response)
+		return
+	}
+
+	if err := services.NewTwoFactorService(config.DB).Disable(user, input.Code); err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Autenticación en dos pasos desactivada"})
+}
+
+// RegenerateRecoveryCodes reemplaza los códigos de recuperación del usuario autenticado
+func RegenerateRecoveryCodes(c *gin.Context) {
+	var input MFACodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	recoveryCodes, err := services.NewTwoFactorService(config.DB).RegenerateRecoveryCodes(user, input.Code)
+	if err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": recoveryCodes})
+}
+
+func beginMFAEnrollment(c *gin.Context, user *models.User) {
+	secret, uri, err := services.NewTwoFactorService(config.DB).BeginEnrollment(user)
+	if err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":           secret,
+		"provisioning_uri": uri,
+	})
+}
+
+// userFromMFAToken valida un token intermedio y carga el usuario al que pertenece
+func userFromMFAToken(token, purpose string) (*models.User, *auth.Claims, error) {
+	claims, err := auth.ParsePurposeToken(token, purpose)
+	if err != nil {
+		return nil, nil, services.ErrUnauthorized("Token de verificación inválido o expirado")
+	}
+
+	revoked, err := services.NewTokenRevocationService(config.DB, config.Cache).IsRevoked(claims.ID, claims.UserID(), claims.IssuedAtTime())
+	if err != nil {
+		return nil, nil, err
+	}
+	if revoked {
+		return nil, nil, services.ErrUnauthorized("Token de verificación inválido o expirado")
+	}
+
+	var user models.User
+	if err := tenancy.Unscoped(config.DB).Preload("Role").First(&user, claims.UserID()).Error; err != nil {
+		return nil, nil, services.ErrUnauthorized("Token de verificación inválido o expirado")
+	}
+
+	return &user, claims, nil
+}
+
+// verifyMFAAttempt ejecuta la verificación limitando los intentos por token intermedio
+// y revoca el token tras un éxito o al agotar los intentos. Los códigos incorrectos cuentan
+// como logins fallidos de la cuenta, así que pedir tokens nuevos con la contraseña no permite
+// seguir probando códigos una vez bloqueada.
+func verifyMFAAttempt(c *gin.Context, user *models.User, claims *auth.Claims, verify func() error) error {
+	jti, userID := claims.ID, claims.UserID()
+	expiresAt := claims.ExpiresAtTime()
+
+	throttle := services.NewLoginThrottleService(config.DB, config.Cache)
+	if err := checkLoginThrottle(c, throttle, user.Email); err != nil {
+		return err
+	}
+
+	revocationService := services.NewTokenRevocationService(config.DB, config.Cache)
+	if err := verify(); err != nil {
+		if err := throttle.RecordFailure(user.Email, c.ClientIP()); err != nil {
+			log.Printf("Error recording failed login: %v", err)
+		}
+
+		attemptsKey := "mfa_attempts:" + jti
+		attempts, _ := services.GetTyped[int](config.Cache, attemptsKey)
+		attempts++
+		config.Cache.SetWithTTL(attemptsKey, attempts, time.Until(expiresAt))
+		if attempts >= maxMFAAttempts {
+			revocationService.RevokeToken(jti, userID, expiresAt)
+		}
+		return err
+	}
+
+	return revocationService.RevokeToken(jti, userID, expiresAt)
+}
@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-api-orm/config"
+	"go-api-orm/models"
+	"go-api-orm/services"
+	"go-api-orm/tenancy"
+	"go-api-orm/utils"
+)
+
+func TestMFAFailuresCountAsFailedLogins(t *testing.T) {
+	t.Setenv("LOGIN_MAX_ATTEMPTS", "3")
+	t.Setenv("LOGIN_BACKOFF_BASE_SECONDS", "0")
+	db := tenancy.Unscoped(newTestApp(t))
+
+	home, err := services.NewOrganizationService(db, config.Cache).Default()
+	if err != nil {
+		t.Fatalf("organización por defecto: %v", err)
+	}
+	role, err := services.DefaultRole(db)
+	if err != nil {
+		t.Fatalf("rol por defecto: %v", err)
+	}
+	now := time.Now()
+	secrets := map[string]string{}
+	for _, username := range []string{"ana", "bob"} {
+		secret, err := utils.GenerateTOTPSecret()
+		if err != nil {
+			t.Fatalf("GenerateTOTPSecret: %v", err)
+		}
+		user := models.User{Username: username, Email: username + "@example.com", Password: "Secreta123!",
+			RoleID: role.ID, TenantID: home.ID, VerifiedAt: &now, TOTPSecret: secret, MFAEnabledAt: &now}
+		if err := db.Create(&user).Error; err != nil {
+			t.Fatalf("crear usuario: %v", err)
+		}
+		secrets[user.Email] = secret
+	}
+
+	router := gin.New()
+	router.POST("/api/login", Login)
+	router.POST("/api/login/mfa", LoginMFA)
+
+	login := func(email string) (int, map[string]interface{}) {
+		return requestJSON(t, router, http.MethodPost, "/api/login", map[string]string{"email": email, "password": "Secreta123!"}, nil)
+	}
+	verify := func(email, code string) (int, map[string]interface{}) {
+		status, body := login(email)
+		if status != http.StatusOK || body["mfa_token"] == nil {
+			t.Fatalf("login de %s = %d: %v", email, status, body)
+		}
+		return requestJSON(t, router, http.MethodPost, "/api/login/mfa", map[string]interface{}{"mfa_token": body["mfa_token"], "code": code}, nil)
+	}
+
+	// Cada token intermedio nuevo no reinicia los fallos: la contraseña sola no completa el login
+	for i := 0; i < 3; i++ {
+		if status, body := verify("ana@example.com", "incorrecto"); status != http.StatusUnauthorized {
+			t.Fatalf("código incorrecto = %d: %v", status, body)
+		}
+	}
+	if status, body := login("ana@example.com"); status != http.StatusLocked || errorCode(body) != "ACCOUNT_LOCKED" {
+		t.Errorf("login tras agotar los intentos con el segundo factor = %d: %v", status, body)
+	}
+
+	// Completar el login con el segundo factor reinicia los fallos de la cuenta
+	if status, body := verify("bob@example.com", "incorrecto"); status != http.StatusUnauthorized {
+		t.Fatalf("código incorrecto = %d: %v", status, body)
+	}
+	code, err := utils.TOTPCode(secrets["bob@example.com"], utils.TOTPStep(time.Now()))
+	if err != nil {
+		t.Fatalf("TOTPCode: %v", err)
+	}
+	if status, body := verify("bob@example.com", code); status != http.StatusOK || body["token"] == nil {
+		t.Fatalf("login con el segundo factor = %d: %v", status, body)
+	}
+	var failures int64
+	if err := db.Model(&models.LoginAttempt{}).Where("identifier = ?", "email:bob@example.com").Count(&failures).Error; err != nil || failures != 0 {
+		t.Errorf("siguen registrados los fallos de la cuenta (%d, %v)", failures, err)
+	}
+}
@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go-api-orm/config"
+	"go-api-orm/services"
+)
+
+// OIDCLogin redirige al usuario al proveedor de identidad para iniciar sesión.
+// Con ?redirect=false retorna la URL en lugar de redirigir (útil para SPAs y apps móviles).
+func OIDCLogin(c *gin.Context) {
+	provider, err := services.LoadOIDCProvider(c.Param("provider"))
+	if err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	authorizationURL, err := services.NewOIDCService(config.DB, config.Cache).AuthorizationURL(provider)
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+
+	if c.Query("redirect") == "false" {
+		c.JSON(http.StatusOK, gin.H{"authorization_url": authorizationURL})
+		return
+	}
+
+	c.Redirect(http.StatusFound, authorizationURL)
+}
+
+// OIDCCallback recibe el código de autorización del proveedor, vincula o crea el usuario
+// y completa el login como Login
+func OIDCCallback(c *gin.Context) {
+	provider, err := services.LoadOIDCProvider(c.Param("provider"))
+	if err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	if providerError := c.Query("error"); providerError != "" {
+		status, response := services.ErrorResponse(services.ErrOIDCLoginFailed(providerError + ": " + c.Query("error_description")))
+		c.JSON(status, response)
+		return
+	}
+
+	code := c.Query("code")
+	if code == "" {
+		status, response := services.ErrorResponse(services.ErrInvalidInput("El parámetro code es requerido"))
+		c.JSON(status, response)
+		return
+	}
+
+	oidcService := services.NewOIDCService(config.DB, config.Cache)
+	claims, err := oidcService.Exchange(provider, code, c.Query("state"))
+	if err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	user, created, err := oidcService.ResolveUser(provider, claims)
+	if err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	// Los usuarios nuevos con un email no verificado por el proveedor reciben el enlace de verificación
+	if created && !user.IsVerified() {
+		sendVerificationEmail(user)
+	}
+
+	completeLogin(c, user)
+}
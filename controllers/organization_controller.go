@@ -0,0 +1,344 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go-api-orm/auth"
+	"go-api-orm/config"
+	"go-api-orm/models"
+	"go-api-orm/serializers"
+	"go-api-orm/services"
+)
+
+// CreateOrganizationInput representa los datos necesarios para crear una organización
+type CreateOrganizationInput struct {
+	Name string `json:"name" binding:"required,max=100"`
+	Slug string `json:"slug" binding:"max=100"` // opcional; se genera a partir del nombre
+}
+
+// InviteMemberInput representa el email al que se invita a la organización y su rol en ella
+type InviteMemberInput struct {
+	Email  string `json:"email" binding:"required,email"`
+	RoleID uint   `json:"role_id" binding:"required"`
+}
+
+// UpdateMemberInput representa el nuevo rol de un miembro
+type UpdateMemberInput struct {
+	RoleID uint `json:"role_id" binding:"required"`
+}
+
+// AcceptInvitationInput representa el token de una invitación a una organización
+type AcceptInvitationInput struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// GetOrganizations lista las organizaciones a las que tiene acceso el usuario autenticado
+func GetOrganizations(c *gin.Context) {
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+	principal, _ := auth.CurrentUser(c)
+
+	organizations, err := services.NewOrganizationService(config.DB, config.Cache).ForUser(user)
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    organizations,
+		"current": principal.TenantID,
+	})
+}
+
+// CreateOrganization crea una organización. El usuario que la crea recibe el rol admin en ella.
+func CreateOrganization(c *gin.Context) {
+	var input CreateOrganizationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	transformService := services.NewTransformService()
+	slug := input.Slug
+	if slug == "" {
+		slug = transformService.GenerateSlug(input.Name)
+	}
+	if !transformService.ValidateSlug(slug) {
+		status, response := services.ErrorResponse(services.ErrInvalidInput("El slug solo puede contener letras minúsculas, números y guiones"))
+		c.JSON(status, response)
+		return
+	}
+
+	organization, err := services.NewOrganizationService(config.DB, config.Cache).Create(input.Name, slug, principal.UserID)
+	if err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	c.JSON(http.StatusCreated, organization)
+}
+
+// GetOrganizationMembers lista los usuarios de otras organizaciones con acceso a la organización
+func GetOrganizationMembers(c *gin.Context) {
+	organizationID, ok := currentOrganizationID(c)
+	if !ok {
+		return
+	}
+
+	memberships, err := services.NewOrganizationService(config.DB, config.Cache).Members(organizationID)
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+
+	data, ok := serialize(c, serializers.Member, memberships)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+// InviteOrganizationMember invita por correo a un email a unirse a la organización con un rol.
+// El acceso se concede cuando el usuario con ese email acepta la invitación.
+func InviteOrganizationMember(c *gin.Context) {
+	organizationID, ok := currentOrganizationID(c)
+	if !ok {
+		return
+	}
+
+	var input InviteMemberInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	principal, _ := auth.CurrentUser(c)
+
+	invitation, err := services.NewInvitationService(tenantDB(c), config.Mailer).InviteMember(organizationID, input.Email, input.RoleID, principal.UserID)
+	if err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	data, ok := serialize(c, serializers.Invitation, invitation)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Invitación enviada correctamente",
+		"data":    data,
+	})
+}
+
+// AcceptOrganizationInvitation da acceso al usuario autenticado a la organización de la invitación
+func AcceptOrganizationInvitation(c *gin.Context) {
+	var input AcceptInvitationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	membership, err := services.NewInvitationService(config.DB, config.Mailer).AcceptMembership(input.Token, user)
+	if err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Invitación aceptada correctamente",
+		"data": services.OrganizationAccess{
+			Organization: membership.Organization,
+			Role:         membership.Role.Name,
+		},
+	})
+}
+
+// UpdateOrganizationMember cambia el rol de un miembro. Se cierran sus sesiones en la organización
+// para que el rol se aplique de inmediato; sus sesiones en otras organizaciones no se ven afectadas.
+func UpdateOrganizationMember(c *gin.Context) {
+	organizationID, ok := currentOrganizationID(c)
+	if !ok {
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	var input UpdateMemberInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	membership, err := services.NewOrganizationService(tenantDB(c), config.Cache).UpdateMemberRole(organizationID, uint(userID), input.RoleID)
+	if err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	if err := services.NewSessionService(config.DB, config.Cache).RevokeForOrganization(membership.UserID, organizationID); err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+
+	data, ok := serialize(c, serializers.Member, membership)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Miembro actualizado correctamente",
+		"data":    data,
+	})
+}
+
+// RemoveOrganizationMember quita el acceso a la organización a un usuario de otra organización
+// y cierra sus sesiones en ella, que podrían seguir actuando en la organización
+func RemoveOrganizationMember(c *gin.Context) {
+	organizationID, ok := currentOrganizationID(c)
+	if !ok {
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	if err := services.NewOrganizationService(config.DB, config.Cache).RemoveMember(organizationID, uint(userID)); err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	if err := services.NewSessionService(config.DB, config.Cache).RevokeForOrganization(uint(userID), organizationID); err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Miembro eliminado correctamente"})
+}
+
+// SwitchOrganization cambia la organización en la que actúa la sesión. Se revoca el token actual
+// y se emite uno nuevo con la organización y el rol del usuario en ella; al refrescar el token
+// la sesión se mantiene en la organización elegida.
+func SwitchOrganization(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	user, ok := loadCurrentUser(c)
+	if !ok {
+		return
+	}
+	principal, _ := auth.CurrentUser(c)
+	if principal.SessionID == 0 {
+		status, response := services.ErrorResponse(services.ErrInvalidInput("El token no está vinculado a una sesión"))
+		c.JSON(status, response)
+		return
+	}
+
+	var organization models.Organization
+	if err := config.DB.First(&organization, id).Error; err != nil {
+		status, response := services.ErrorResponse(services.ErrNotFound("Organización"))
+		c.JSON(status, response)
+		return
+	}
+
+	role, err := services.NewOrganizationService(config.DB, config.Cache).Access(user, organization.ID)
+	if err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	// La organización propia se guarda como 0 para que la sesión la siga si el usuario cambia de organización
+	active := organization.ID
+	if active == user.TenantID {
+		active = 0
+	}
+	if err := services.NewSessionService(config.DB, config.Cache).SetOrganization(principal.SessionID, active); err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+
+	if err := services.NewTokenRevocationService(config.DB, config.Cache).RevokeToken(principal.TokenID, principal.UserID, principal.ExpiresAt); err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+
+	token, err := auth.IssueAccessToken(user.ID, organization.ID, role.Name, role.ID, principal.SessionID)
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Organización cambiada correctamente",
+		"token":        token,
+		"expires_in":   int(auth.AccessTokenTTL().Seconds()),
+		"session_id":   principal.SessionID,
+		"organization": organization,
+		"role":         role.Name,
+	})
+}
+
+// currentOrganizationID retorna el ID de la ruta si es la organización en la que actúa el usuario.
+// Los miembros solo se gestionan desde dentro de la organización.
+func currentOrganizationID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return 0, false
+	}
+
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return 0, false
+	}
+	if uint(id) != principal.TenantID {
+		status, response := services.ErrorResponse(services.ErrForbidden("Cambia a la organización para gestionar sus miembros"))
+		c.JSON(status, response)
+		return 0, false
+	}
+
+	return uint(id), true
+}
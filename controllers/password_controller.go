@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go-api-orm/config"
+	"go-api-orm/services"
+)
+
+// ForgotPasswordInput representa los datos para solicitar el restablecimiento de contraseña
+type ForgotPasswordInput struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordInput representa los datos para restablecer la contraseña
+type ResetPasswordInput struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// ChangePasswordInput representa los datos para cambiar la contraseña del usuario autenticado
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// ForgotPassword envía un enlace de restablecimiento al email indicado.
+// Siempre responde lo mismo para no revelar si el email está registrado.
+func ForgotPassword(c *gin.Context) {
+	var input ForgotPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	if err := services.NewPasswordResetService(config.DB, config.Mailer).RequestReset(input.Email); err != nil {
+		log.Printf("Error requesting password reset: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Si el correo está registrado, recibirás un enlace para restablecer tu contraseña"})
+}
+
+// ResetPassword asigna una nueva contraseña usando un token de restablecimiento
+func ResetPassword(c *gin.Context) {
+	var input ResetPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	user, err := services.NewPasswordResetService(config.DB, nil).ResetPassword(input.Token, input.Password)
+	if err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	// Cerrar todas las sesiones abiertas con la contraseña anterior
+	if err := services.NewTokenRevocationService(config.DB, config.Cache).RevokeAllForUser(user.ID); err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Contraseña restablecida correctamente"})
+}
+
+// ChangePassword cambia la contraseña del usuario autenticado.
+// Se mantiene la sesión actual y se cierran las demás junto con sus tokens de refresco; las claves de API se mantienen.
+func ChangePassword(c *gin.Context) {
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	var input ChangePasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	if err := services.NewPasswordService(config.DB).ChangePassword(principal.UserID, input.CurrentPassword, input.NewPassword); err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	if err := services.NewTokenRevocationService(config.DB, config.Cache).RevokeOthersForUser(principal.UserID, principal.SessionID); err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Contraseña actualizada correctamente. Se cerraron las demás sesiones"})
+}
@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-api-orm/auth"
+	"go-api-orm/config"
+	"go-api-orm/models"
+	"go-api-orm/policies"
+	"go-api-orm/services"
+)
+
+// ExplainPolicyInput representa una petición de autorización simulada
+type ExplainPolicyInput struct {
+	UserID       uint                   `json:"user_id" binding:"required"`
+	Action       string                 `json:"action" binding:"required"` // p. ej. posts:update
+	ResourceType string                 `json:"resource_type"`             // p. ej. post
+	ResourceID   string                 `json:"resource_id"`               // slug del post
+	Subject      map[string]interface{} `json:"subject"`                   // atributos que sustituyen a los del usuario
+	Resource     map[string]interface{} `json:"resource"`                  // atributos que sustituyen a los del recurso
+	Environment  map[string]interface{} `json:"environment"`
+}
+
+// GetPolicies muestra la política cargada por el motor
+func GetPolicies(c *gin.Context) {
+	policy, loadedAt := config.Policies.Policy()
+
+	response := gin.H{
+		"path":  config.Policies.Path(),
+		"rules": policy.Rules,
+	}
+	if !loadedAt.IsZero() {
+		response["loaded_at"] = loadedAt
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// ExplainPolicy evalúa una petición simulada sin ejecutarla y muestra por qué se permitiría o denegaría.
+// El sujeto se construye a partir del usuario indicado y, para posts, el recurso a partir de su slug.
+func ExplainPolicy(c *gin.Context) {
+	var input ExplainPolicyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	var user models.User
+	if err := tenantDB(c).Preload("Role").First(&user, input.UserID).Error; err != nil {
+		status, response := services.ErrorResponse(services.ErrNotFound("Usuario"))
+		c.JSON(status, response)
+		return
+	}
+
+	environment := merge(policies.EnvironmentAttributes(c.ClientIP(), time.Now()), input.Environment)
+
+	var post *models.Post
+	if input.ResourceType == "post" && input.ResourceID != "" {
+		post = &models.Post{}
+		if err := tenantDB(c).Where("slug = ?", input.ResourceID).First(post).Error; err != nil {
+			status, response := services.ErrorResponse(services.ErrNotFound("Post"))
+			c.JSON(status, response)
+			return
+		}
+	}
+
+	// Las acciones sobre un post concreto se explican con su política de propiedad, que
+	// aplica los permisos del rol (propio, _any o hard_delete) si ninguna regla coincide
+	if action, ok := ownershipAction(input.Action); ok && post != nil {
+		principal := &auth.Principal{UserID: user.ID, Role: user.Role.Name, RoleID: user.RoleID, TenantID: user.TenantID, Method: auth.MethodJWT}
+		policy := policies.NewPostPolicy(config.Policies, tenantDB(c), config.Cache)
+		request := policy.Request(principal, action, post, environment)
+		request.Subject = merge(request.Subject, input.Subject)
+		request.Resource = merge(request.Resource, input.Resource)
+
+		explanation, err := policy.ExplainRequest(principal, action, post, request)
+		if err != nil {
+			status, response := services.ErrorResponse(services.ErrInternal(err))
+			c.JSON(status, response)
+			return
+		}
+		response := gin.H{
+			"request":  request,
+			"policy":   explanation.Decision,
+			"allowed":  explanation.Allowed,
+			"decision": explanation.Decision.Reason,
+		}
+		if !explanation.Decision.Applicable {
+			response["permission"] = explanation.Permission
+			response["permission_granted"] = explanation.PermissionGranted
+		}
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	request := policies.Request{
+		Subject:      merge(policies.UserAttributes(&user), input.Subject),
+		Action:       input.Action,
+		ResourceType: input.ResourceType,
+		Resource:     merge(policies.Attributes{}, input.Resource),
+		Environment:  environment,
+	}
+	if post != nil {
+		request.Resource = merge(policies.ResourceAttributes(post), input.Resource)
+	}
+
+	decision := config.Policies.Evaluate(request)
+	response := gin.H{
+		"request":  request,
+		"policy":   decision,
+		"allowed":  decision.Allowed,
+		"decision": decision.Reason,
+	}
+
+	// Si ninguna regla coincide se aplica el permiso del rol, como en RequirePermission
+	if !decision.Applicable {
+		granted, err := services.NewPermissionService(tenantDB(c), config.Cache).HasPermission(user.RoleID, input.Action)
+		if err != nil {
+			status, response := services.ErrorResponse(services.ErrInternal(err))
+			c.JSON(status, response)
+			return
+		}
+		response["permission"] = input.Action
+		response["permission_granted"] = granted
+		response["allowed"] = granted
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ownershipAction retorna la acción de la política de propiedad de posts (posts:update -> update)
+func ownershipAction(action string) (string, bool) {
+	switch action {
+	case "posts:" + policies.ActionUpdate:
+		return policies.ActionUpdate, true
+	case "posts:" + policies.ActionDelete:
+		return policies.ActionDelete, true
+	case "posts:" + policies.ActionHardDelete:
+		return policies.ActionHardDelete, true
+	case "posts:" + policies.ActionPublish:
+		return policies.ActionPublish, true
+	}
+	return "", false
+}
+
+// merge copia los atributos de overrides sobre base
+func merge(base policies.Attributes, overrides map[string]interface{}) policies.Attributes {
+	for key, value := range overrides {
+		base[key] = value
+	}
+	return base
+}
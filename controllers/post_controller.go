@@ -1,24 +1,35 @@
 package controllers
 
 import (
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"go-api-orm/auth"
 	"go-api-orm/config"
 	"go-api-orm/models"
+	"go-api-orm/policies"
+	"go-api-orm/serializers"
 	"go-api-orm/services"
+	"go-api-orm/tenancy"
+	"gorm.io/gorm"
 )
 
 type CreatePostInput struct {
-	Title   string `json:"title" binding:"required"`
-	Content string `json:"content" binding:"required"`
-	Slug    string `json:"slug"` // opcional
+	Title      string   `json:"title" binding:"required"`
+	Content    string   `json:"content" binding:"required"`
+	Slug       string   `json:"slug"`       // opcional
+	Tags       []string `json:"tags"`       // nombres de las etiquetas; se crean las que no existen
+	Categories []string `json:"categories"` // slugs de categorías existentes
 }
 
 type UpdatePostInput struct {
-	Title   string `json:"title"`
-	Content string `json:"content"`
-	Slug    string `json:"slug"`
+	Title      string   `json:"title"`
+	Content    string   `json:"content"`
+	Slug       string   `json:"slug"`
+	Tags       []string `json:"tags"`       // si se envía reemplaza las etiquetas; [] las quita todas
+	Categories []string `json:"categories"` // si se envía reemplaza las categorías; [] las quita todas
 }
 
 // CreatePost crea un nuevo post
@@ -30,11 +41,9 @@ func CreatePost(c *gin.Context) {
 		return
 	}
 
-	// Obtener el ID del usuario del token
-	userId, ok := c.Get("userId")
+	// Obtener el usuario autenticado
+	principal, ok := currentPrincipal(c)
 	if !ok {
-		status, response := services.ErrorResponse(services.ErrInvalidInput("No se encontró el ID del usuario"))
-		c.JSON(status, response)
 		return
 	}
 
@@ -43,30 +52,55 @@ func CreatePost(c *gin.Context) {
 		Title:    input.Title,
 		Content:  input.Content,
 		Slug:     input.Slug, // Si está vacío, el hook BeforeCreate generará uno
-		AuthorID: userId.(uint), // Obtener el ID del usuario del token
+		AuthorID: principal.UserID,
 	}
 
-	if err := config.DB.Create(&post).Error; err != nil {
-		status, response := services.ErrorResponse(services.ErrInternal(err))
+	// El post se crea junto con sus etiquetas, sus categorías y su primera revisión
+	err := tenantDB(c).Transaction(func(tx *gorm.DB) error {
+		taxonomy := services.NewTaxonomyService(tx)
+		tags, err := taxonomy.ResolveTags(input.Tags)
+		if err != nil {
+			return err
+		}
+		categories, err := taxonomy.ResolveCategories(input.Categories)
+		if err != nil {
+			return err
+		}
+		post.Tags = tags
+		post.Categories = categories
+
+		if err := tx.Create(&post).Error; err != nil {
+			return err
+		}
+		_, err = services.NewPostRevisionService(tx).Record(&post, principal.UserID)
+		return err
+	})
+	if err != nil {
+		status, response := services.ErrorResponse(err)
 		c.JSON(status, response)
 		return
 	}
 
-	c.JSON(http.StatusCreated, post)
+	respondPost(c, http.StatusCreated, &post)
 }
 
 // GetPostBySlug obtiene un post por su slug
 func GetPostBySlug(c *gin.Context) {
 	slug := c.Param("slug")
 	
+	// El autor puede ser miembro de otra organización, así que se carga sin filtrar por organización
 	var post models.Post
-	if err := config.DB.Where("slug = ?", slug).First(&post).Error; err != nil {
+	if err := tenantDB(c).Scopes(postVisibility(c)).Preload("Author", tenancy.Unscoped).Preload("Tags").Preload("Categories").Where("slug = ?", slug).First(&post).Error; err != nil {
 		status, response := services.ErrorResponse(services.ErrNotFound("Post"))
 		c.JSON(status, response)
 		return
 	}
 
-	c.JSON(http.StatusOK, post)
+	data, ok := serialize(c, serializers.Post, post)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, data)
 }
 
 // GetPosts obtiene todos los posts con paginación y filtros
@@ -77,13 +111,22 @@ func GetPosts(c *gin.Context) {
 	pagination := services.GeneratePaginationFromRequest(c)
 	searchFilters := services.ExtractSearchParams(c)
 	sortParams := services.ExtractSortParams(c)
+	searchFilters, sortParams = visibleFilters(c, serializers.Post, searchFilters, sortParams, services.PostTaxonomyRelations...)
+
+	// Filtrar por una categoría incluye sus subcategorías
+	queryFilters, err := services.NewTaxonomyService(tenantDB(c)).ExpandCategoryFilters(searchFilters)
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
 	
 	// Aplicar filtros y paginación
-	db := config.DB
-	db = services.ApplySearchFilters(db, searchFilters)
+	db := tenantDB(c).Scopes(postVisibility(c))
+	db = services.ApplySearchFilters(db, queryFilters, services.PostTaxonomyRelations...)
 	db = services.ApplySorting(db, sortParams)
 	
-	err := db.Scopes(services.Paginate(posts, &pagination, db)).Find(&posts).Error
+	err = db.Scopes(services.Paginate(posts, &pagination, db)).Preload("Author", tenancy.Unscoped).Preload("Tags").Preload("Categories").Find(&posts).Error
 	if err != nil {
 		status, response := services.ErrorResponse(services.ErrInternal(err))
 		c.JSON(status, response)
@@ -107,6 +150,30 @@ func GetPosts(c *gin.Context) {
 				Description: "Slug del post",
 				Operators:   []string{"eq"},
 			},
+			{
+				Name:        "status",
+				Type:        "string",
+				Description: "Estado editorial (draft, in_review, scheduled, published, archived)",
+				Operators:   []string{"eq", "in"},
+			},
+			{
+				Name:        "published_at",
+				Type:        "date",
+				Description: "Fecha de publicación",
+				Operators:   []string{"gt", "gte", "lt", "lte"},
+			},
+			{
+				Name:        "tag",
+				Type:        "string",
+				Description: "Slug de una etiqueta del post",
+				Operators:   []string{"eq", "ne", "in", "nin"},
+			},
+			{
+				Name:        "category",
+				Type:        "string",
+				Description: "Slug de una categoría del post; incluye sus subcategorías",
+				Operators:   []string{"eq", "ne", "in", "nin"},
+			},
 			{
 				Name:        "created_at",
 				Type:        "date",
@@ -115,6 +182,10 @@ func GetPosts(c *gin.Context) {
 			},
 		},
 		[]services.SortField{
+			{
+				Name:        "published_at",
+				Description: "Ordenar por fecha de publicación",
+			},
 			{
 				Name:        "title",
 				Description: "Ordenar por título",
@@ -129,22 +200,39 @@ func GetPosts(c *gin.Context) {
 	)
 
 	// Construir la respuesta final
-	response := services.BuildAPIResponse(posts, metadataResponse, paginationResponse)
+	data, ok := serialize(c, serializers.Post, posts)
+	if !ok {
+		return
+	}
+	response := services.BuildAPIResponse(data, metadataResponse, paginationResponse)
 
 	c.JSON(http.StatusOK, response)
 }
 
-// UpdatePost actualiza un post existente
+// UpdatePost actualiza un post existente.
+// El autor puede editar sus posts; los posts ajenos requieren posts:update_any.
 func UpdatePost(c *gin.Context) {
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
 	slug := c.Param("slug")
 	
+	// Los posts que el usuario no puede ver no existen para él
 	var post models.Post
-	if err := config.DB.Where("slug = ?", slug).First(&post).Error; err != nil {
+	if err := tenantDB(c).Scopes(postVisibility(c)).Where("slug = ?", slug).First(&post).Error; err != nil {
 		status, response := services.ErrorResponse(services.ErrNotFound("Post"))
 		c.JSON(status, response)
 		return
 	}
 
+	if err := policies.NewPostPolicy(config.Policies, tenantDB(c), config.Cache).Authorize(principal, policies.ActionUpdate, &post, c.ClientIP()); err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
 	var input UpdatePostInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
@@ -170,7 +258,7 @@ func UpdatePost(c *gin.Context) {
 		}
 		// Verificar que el slug no exista
 		var count int64
-		if err := config.DB.Model(&models.Post{}).Where("slug = ? AND id != ?", input.Slug, post.ID).Count(&count).Error; err != nil {
+		if err := tenantDB(c).Model(&models.Post{}).Where("slug = ? AND id != ?", input.Slug, post.ID).Count(&count).Error; err != nil {
 			status, response := services.ErrorResponse(services.ErrInternal(err))
 			c.JSON(status, response)
 			return
@@ -183,31 +271,198 @@ func UpdatePost(c *gin.Context) {
 		updates["slug"] = input.Slug
 	}
 
-	if err := config.DB.Model(&post).Updates(updates).Error; err != nil {
-		status, response := services.ErrorResponse(services.ErrInternal(err))
+	// Cada cambio guarda una revisión con el estado resultante. Los posts sin historial
+	// guardan antes su estado anterior para que el cambio se pueda deshacer.
+	err := tenantDB(c).Transaction(func(tx *gorm.DB) error {
+		revisions := services.NewPostRevisionService(tx)
+		if err := revisions.EnsureInitial(&post); err != nil {
+			return err
+		}
+		if err := tx.Model(&post).Updates(updates).Error; err != nil {
+			return err
+		}
+		// El hook BeforeUpdate puede haber regenerado el slug
+		if err := tx.First(&post, post.ID).Error; err != nil {
+			return err
+		}
+
+		taxonomy := services.NewTaxonomyService(tx)
+		if input.Tags != nil {
+			tags, err := taxonomy.ResolveTags(input.Tags)
+			if err != nil {
+				return err
+			}
+			if err := taxonomy.SetPostTags(&post, tags); err != nil {
+				return err
+			}
+		}
+		if input.Categories != nil {
+			categories, err := taxonomy.ResolveCategories(input.Categories)
+			if err != nil {
+				return err
+			}
+			if err := taxonomy.SetPostCategories(&post, categories); err != nil {
+				return err
+			}
+		}
+
+		_, err := revisions.Record(&post, principal.UserID)
+		return err
+	})
+	if err != nil {
+		status, response := services.ErrorResponse(err)
 		c.JSON(status, response)
 		return
 	}
 
-	c.JSON(http.StatusOK, post)
+	respondPost(c, http.StatusOK, &post)
 }
 
-// DeletePost elimina un post
+// DeletePost elimina un post.
+// El autor puede eliminar sus posts y los posts ajenos requieren posts:delete_any.
+// Con ?permanent=true el post se elimina definitivamente, lo que requiere posts:hard_delete.
 func DeletePost(c *gin.Context) {
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
 	slug := c.Param("slug")
-	
+	permanent := c.Query("permanent") == "true"
+
+	// El borrado definitivo también alcanza a los posts ya eliminados
+	db := tenantDB(c)
+	action := policies.ActionDelete
+	if permanent {
+		db = db.Unscoped()
+		action = policies.ActionHardDelete
+	}
+
 	var post models.Post
-	if err := config.DB.Where("slug = ?", slug).First(&post).Error; err != nil {
+	if err := db.Scopes(postVisibility(c)).Where("slug = ?", slug).First(&post).Error; err != nil {
 		status, response := services.ErrorResponse(services.ErrNotFound("Post"))
 		c.JSON(status, response)
 		return
 	}
 
-	if err := config.DB.Delete(&post).Error; err != nil {
+	if err := policies.NewPostPolicy(config.Policies, tenantDB(c), config.Cache).Authorize(principal, action, &post, c.ClientIP()); err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	// El borrado definitivo elimina también el historial de revisiones y las etiquetas y categorías asignadas
+	err := tenantDB(c).Transaction(func(tx *gorm.DB) error {
+		if !permanent {
+			return tx.Delete(&post).Error
+		}
+		if err := tx.Where("post_id = ?", post.ID).Delete(&models.PostRevision{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM post_tags WHERE post_id = ?", post.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM post_categories WHERE post_id = ?", post.ID).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&post).Error
+	})
+	if err != nil {
 		status, response := services.ErrorResponse(services.ErrInternal(err))
 		c.JSON(status, response)
 		return
 	}
 
+	if permanent {
+		c.JSON(http.StatusOK, gin.H{"message": "Post eliminado definitivamente"})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "Post eliminado correctamente"})
 }
+
+// PostStatusInput representa un cambio de estado del post
+type PostStatusInput struct {
+	Status    string     `json:"status" binding:"required,oneof=draft in_review scheduled published archived"`
+	PublishAt *time.Time `json:"publish_at"` // obligatorio para el estado scheduled
+}
+
+// ChangePostStatus cambia el estado editorial de un post. El autor puede enviarlo a revisión
+// y retirarlo; revisar, programar, publicar y archivar requieren posts:publish.
+func ChangePostStatus(c *gin.Context) {
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return
+	}
+
+	var input PostStatusInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	var post models.Post
+	if err := tenantDB(c).Scopes(postVisibility(c)).Where("slug = ?", c.Param("slug")).First(&post).Error; err != nil {
+		status, response := services.ErrorResponse(services.ErrNotFound("Post"))
+		c.JSON(status, response)
+		return
+	}
+
+	workflow := services.NewPostWorkflowService(tenantDB(c))
+	editorial, err := workflow.CheckTransition(post.Status, input.Status)
+	if err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	// Las reglas del motor de políticas sobre posts:publish se aplican antes que el permiso del rol
+	action := policies.ActionUpdate
+	if editorial {
+		action = policies.ActionPublish
+	}
+	if err := policies.NewPostPolicy(config.Policies, tenantDB(c), config.Cache).Authorize(principal, action, &post, c.ClientIP()); err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	if err := workflow.Transition(&post, input.Status, input.PublishAt); err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	respondPost(c, http.StatusOK, &post)
+}
+
+// postVisibility limita la consulta a los posts que puede ver el usuario de la petición:
+// los publicados, los suyos y, con posts:publish, todos. Se aplica también al buscar el post
+// que se modifica, se elimina o cuyas revisiones se consultan.
+func postVisibility(c *gin.Context) func(*gorm.DB) *gorm.DB {
+	principal, ok := auth.CurrentUser(c)
+	if !ok {
+		return services.VisiblePosts(0, false)
+	}
+
+	all, err := services.NewPermissionService(tenantDB(c), config.Cache).HasPermission(principal.RoleID, services.PublishPermission)
+	if err != nil {
+		log.Printf("Error checking %s for role %s: %v", services.PublishPermission, principal.Role, err)
+	}
+	return services.VisiblePosts(principal.UserID, all)
+}
+
+// respondPost responde con el post recién creado o modificado, incluidos su autor, sus etiquetas y sus categorías
+func respondPost(c *gin.Context, status int, post *models.Post) {
+	if err := tenantDB(c).Preload("Author", tenancy.Unscoped).Preload("Tags").Preload("Categories").First(post, post.ID).Error; err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+
+	data, ok := serialize(c, serializers.Post, post)
+	if !ok {
+		return
+	}
+	c.JSON(status, data)
+}
@@ -0,0 +1,272 @@
+package controllers
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go-api-orm/auth"
+	"go-api-orm/config"
+	"go-api-orm/middleware"
+	"go-api-orm/models"
+	"go-api-orm/policies"
+	"go-api-orm/services"
+	"go-api-orm/tenancy"
+)
+
+// Los editores no pueden publicar sus propios posts aunque su rol tenga posts:publish
+const noSelfPublishPolicy = `{
+  "version": 1,
+  "rules": [
+    {
+      "id": "editors-no-self-publish",
+      "effect": "deny",
+      "actions": ["posts:publish"],
+      "resources": ["post"],
+      "conditions": [
+        {"attribute": "subject.role", "operator": "eq", "value": "editor"},
+        {"attribute": "resource.author_id", "operator": "eq", "ref": "subject.id"}
+      ]
+    }
+  ]
+}`
+
+// errorCode retorna el código de una respuesta de error
+func errorCode(body map[string]interface{}) interface{} {
+	if apiErr, ok := body["error"].(map[string]interface{}); ok {
+		return apiErr["code"]
+	}
+	return nil
+}
+
+func TestChangePostStatusAppliesThePolicyEngineAndHidesInvisiblePosts(t *testing.T) {
+	db := tenancy.Unscoped(newTestApp(t))
+
+	path := filepath.Join(t.TempDir(), "policies.json")
+	if err := os.WriteFile(path, []byte(noSelfPublishPolicy), 0o600); err != nil {
+		t.Fatalf("escribir políticas: %v", err)
+	}
+	engine, err := policies.NewEngine(path)
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	previousPolicies := config.Policies
+	config.Policies = engine
+	t.Cleanup(func() { config.Policies = previousPolicies })
+
+	home, err := services.NewOrganizationService(db, config.Cache).Default()
+	if err != nil {
+		t.Fatalf("organización por defecto: %v", err)
+	}
+	var editor models.Role
+	if err := db.Where("name = ? AND tenant_id = 0", "editor").First(&editor).Error; err != nil {
+		t.Fatalf("rol editor: %v", err)
+	}
+	member, err := services.DefaultRole(db)
+	if err != nil {
+		t.Fatalf("rol por defecto: %v", err)
+	}
+
+	eva := models.User{Username: "eva", Email: "eva@example.com", Password: "Secreta123!", RoleID: editor.ID, TenantID: home.ID}
+	carl := models.User{Username: "carl", Email: "carl@example.com", Password: "Secreta123!", RoleID: member.ID, TenantID: home.ID}
+	for _, user := range []*models.User{&eva, &carl} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("crear usuario: %v", err)
+		}
+	}
+	for _, post := range []models.Post{
+		{Title: "Borrador de eva", Content: "Contenido", AuthorID: eva.ID, TenantID: home.ID, Status: models.PostStatusDraft},
+		{Title: "Borrador de carl", Content: "Contenido", AuthorID: carl.ID, TenantID: home.ID, Status: models.PostStatusDraft},
+	} {
+		if err := db.Create(&post).Error; err != nil {
+			t.Fatalf("crear post: %v", err)
+		}
+	}
+	evaToken, err := auth.IssueAccessToken(eva.ID, home.ID, editor.Name, editor.ID, 0)
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+	carlToken, err := auth.IssueAccessToken(carl.ID, home.ID, member.Name, member.ID, 0)
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+
+	router := gin.New()
+	router.Use(middleware.Tenant())
+	protected := router.Group("/api/posts", middleware.AuthMiddleware())
+	protected.PUT("/:slug", UpdatePost)
+	protected.DELETE("/:slug", DeletePost)
+	protected.PUT("/:slug/status", ChangePostStatus)
+	protected.GET("/:slug/revisions", GetPostRevisions)
+
+	publish := map[string]string{"status": models.PostStatusPublished}
+	for _, tc := range []struct {
+		name   string
+		token  string
+		slug   string
+		status int
+		code   string
+	}{
+		{"sin posts:publish sobre el post propio", carlToken, "borrador-de-carl", http.StatusForbidden, "MISSING_PERMISSION"},
+		{"la regla deniega al editor publicar su post", evaToken, "borrador-de-eva", http.StatusForbidden, "FORBIDDEN"},
+		{"el editor publica el post de otro autor", evaToken, "borrador-de-carl", http.StatusOK, ""},
+		{"borrador ajeno que el usuario no ve", carlToken, "borrador-de-eva", http.StatusNotFound, "NOT_FOUND"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			status, body := requestJSON(t, router, http.MethodPut, "/api/posts/"+tc.slug+"/status", publish, bearer(tc.token))
+			if status != tc.status {
+				t.Fatalf("status = %d, se esperaba %d: %v", status, tc.status, body)
+			}
+			if tc.code != "" && errorCode(body) != tc.code {
+				t.Errorf("código = %v, se esperaba %s", errorCode(body), tc.code)
+			}
+		})
+	}
+
+	// El borrador de eva tampoco existe para carl al editarlo, eliminarlo o ver sus revisiones
+	for _, request := range []struct {
+		method  string
+		path    string
+		payload interface{}
+	}{
+		{http.MethodPut, "/api/posts/borrador-de-eva", map[string]string{"title": "Cambiado"}},
+		{http.MethodDelete, "/api/posts/borrador-de-eva", nil},
+		{http.MethodGet, "/api/posts/borrador-de-eva/revisions", nil},
+	} {
+		status, body := requestJSON(t, router, request.method, request.path, request.payload, bearer(carlToken))
+		if status != http.StatusNotFound {
+			t.Errorf("%s %s = %d, se esperaba 404: %v", request.method, request.path, status, body)
+		}
+	}
+}
+
+// Una regla allow concede crear posts a un rol sin el permiso y una deny se lo quita a otro
+const createPostPolicy = `{
+  "version": 1,
+  "rules": [
+    {"id": "guests-create-posts", "effect": "allow", "actions": ["posts:create"], "resources": ["post"],
+     "conditions": [{"attribute": "subject.role", "operator": "eq", "value": "invitado"}]},
+    {"id": "editors-no-create", "effect": "deny", "actions": ["posts:create"], "resources": ["post"],
+     "conditions": [{"attribute": "subject.role", "operator": "eq", "value": "editor"}]}
+  ]
+}`
+
+func TestCreatePostFollowsThePolicyEngine(t *testing.T) {
+	db := tenancy.Unscoped(newTestApp(t))
+
+	path := filepath.Join(t.TempDir(), "policies.json")
+	if err := os.WriteFile(path, []byte(createPostPolicy), 0o600); err != nil {
+		t.Fatalf("escribir políticas: %v", err)
+	}
+	engine, err := policies.NewEngine(path)
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	previousPolicies := config.Policies
+	config.Policies = engine
+	t.Cleanup(func() { config.Policies = previousPolicies })
+
+	home, err := services.NewOrganizationService(db, config.Cache).Default()
+	if err != nil {
+		t.Fatalf("organización por defecto: %v", err)
+	}
+	roles := map[string]models.Role{}
+	for _, name := range []string{"editor", "user"} {
+		var role models.Role
+		if err := db.Where("name = ? AND tenant_id = 0", name).First(&role).Error; err != nil {
+			t.Fatalf("rol %s: %v", name, err)
+		}
+		roles[name] = role
+	}
+	// invitado no tiene ningún permiso
+	guest := models.Role{Name: "invitado", TenantID: home.ID}
+	if err := db.Create(&guest).Error; err != nil {
+		t.Fatalf("crear rol: %v", err)
+	}
+	roles[guest.Name] = guest
+
+	router := gin.New()
+	router.Use(middleware.Tenant())
+	router.POST("/api/posts", middleware.AuthMiddleware(), middleware.RequirePolicy("posts:create", "post"), CreatePost)
+
+	for _, tc := range []struct {
+		name   string
+		role   string
+		status int
+	}{
+		{"una regla allow concede el permiso", "invitado", http.StatusCreated},
+		{"una regla deny lo quita", "editor", http.StatusForbidden},
+		{"sin reglas se exige el permiso del rol", "user", http.StatusCreated},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			role := roles[tc.role]
+			user := models.User{Username: tc.role, Email: tc.role + "@example.com", Password: "Secreta123!", RoleID: role.ID, TenantID: home.ID}
+			if err := db.Create(&user).Error; err != nil {
+				t.Fatalf("crear usuario: %v", err)
+			}
+			token, err := auth.IssueAccessToken(user.ID, home.ID, role.Name, role.ID, 0)
+			if err != nil {
+				t.Fatalf("token: %v", err)
+			}
+
+			status, body := requestJSON(t, router, http.MethodPost, "/api/posts", map[string]string{"title": "Post de " + tc.role, "content": "Contenido"}, bearer(token))
+			if status != tc.status {
+				t.Errorf("status = %d, se esperaba %d: %v", status, tc.status, body)
+			}
+		})
+	}
+}
+
+func TestPermanentDeleteRemovesTheTaxonomyAssociations(t *testing.T) {
+	db := tenancy.Unscoped(newTestApp(t))
+
+	home, err := services.NewOrganizationService(db, config.Cache).Default()
+	if err != nil {
+		t.Fatalf("organización por defecto: %v", err)
+	}
+	var admin models.Role
+	if err := db.Where("name = ? AND tenant_id = 0", services.AdminRole).First(&admin).Error; err != nil {
+		t.Fatalf("rol admin: %v", err)
+	}
+	ana := models.User{Username: "ana", Email: "ana@example.com", Password: "Secreta123!", RoleID: admin.ID, TenantID: home.ID}
+	if err := db.Create(&ana).Error; err != nil {
+		t.Fatalf("crear usuario: %v", err)
+	}
+	tag := models.Tag{Name: "Go", Slug: "go", TenantID: home.ID}
+	category := models.Category{Name: "Backend", Slug: "backend", TenantID: home.ID}
+	if err := db.Create(&tag).Error; err != nil {
+		t.Fatalf("crear etiqueta: %v", err)
+	}
+	if err := db.Create(&category).Error; err != nil {
+		t.Fatalf("crear categoría: %v", err)
+	}
+	for _, title := range []string{"Se elimina", "Se mantiene"} {
+		post := models.Post{Title: title, Content: "Contenido", AuthorID: ana.ID, TenantID: home.ID,
+			Tags: []models.Tag{tag}, Categories: []models.Category{category}}
+		if err := db.Create(&post).Error; err != nil {
+			t.Fatalf("crear post: %v", err)
+		}
+	}
+	token, err := auth.IssueAccessToken(ana.ID, home.ID, admin.Name, admin.ID, 0)
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+
+	router := gin.New()
+	router.Use(middleware.Tenant())
+	router.DELETE("/api/posts/:slug", middleware.AuthMiddleware(), DeletePost)
+
+	if status, body := requestJSON(t, router, http.MethodDelete, "/api/posts/se-elimina?permanent=true", nil, bearer(token)); status != http.StatusOK {
+		t.Fatalf("DELETE permanente = %d: %v", status, body)
+	}
+
+	// Solo quedan las asociaciones del post que se mantiene
+	for _, table := range []string{"post_tags", "post_categories"} {
+		var count int64
+		if err := db.Table(table).Count(&count).Error; err != nil || count != 1 {
+			t.Errorf("%s tiene %d filas (%v), se esperaba 1", table, count, err)
+		}
+	}
+}
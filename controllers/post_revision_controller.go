@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go-api-orm/auth"
+	"go-api-orm/config"
+	"go-api-orm/models"
+	"go-api-orm/policies"
+	"go-api-orm/serializers"
+	"go-api-orm/services"
+)
+
+// GetPostRevisions lista las revisiones del post, de la más reciente a la más antigua.
+// El historial lo consultan quienes pueden editar el post.
+func GetPostRevisions(c *gin.Context) {
+	post, _, ok := authorizedPost(c, policies.ActionUpdate)
+	if !ok {
+		return
+	}
+
+	revisions, err := services.NewPostRevisionService(tenantDB(c)).List(post.ID)
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+
+	data, ok := serialize(c, serializers.PostRevision, revisions)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+// GetPostRevision obtiene una revisión del post con su contenido completo
+func GetPostRevision(c *gin.Context) {
+	post, _, ok := authorizedPost(c, policies.ActionUpdate)
+	if !ok {
+		return
+	}
+
+	number, ok := revisionNumber(c, c.Param("rev"))
+	if !ok {
+		return
+	}
+
+	revision, err := services.NewPostRevisionService(tenantDB(c)).Get(post.ID, number)
+	if err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	data, ok := serialize(c, serializers.PostRevision, revision)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, data)
+}
+
+// DiffPostRevisions compara dos revisiones del post (?from=1&to=3) línea a línea
+func DiffPostRevisions(c *gin.Context) {
+	post, _, ok := authorizedPost(c, policies.ActionUpdate)
+	if !ok {
+		return
+	}
+
+	from, ok := revisionNumber(c, c.Query("from"))
+	if !ok {
+		return
+	}
+	to, ok := revisionNumber(c, c.Query("to"))
+	if !ok {
+		return
+	}
+
+	diff, err := services.NewPostRevisionService(tenantDB(c)).Diff(post.ID, from, to)
+	if err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+// RestorePostRevision devuelve el post a una revisión anterior. La restauración se guarda como
+// una nueva revisión, por lo que también se puede deshacer.
+func RestorePostRevision(c *gin.Context) {
+	post, principal, ok := authorizedPost(c, policies.ActionUpdate)
+	if !ok {
+		return
+	}
+
+	number, ok := revisionNumber(c, c.Param("rev"))
+	if !ok {
+		return
+	}
+
+	if _, err := services.NewPostRevisionService(tenantDB(c)).Restore(post, number, principal.UserID); err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	respondPost(c, http.StatusOK, post)
+}
+
+// PrunePostRevisions elimina las revisiones antiguas del post y conserva las ?keep=N más recientes.
+// Como borra historial requiere poder eliminar el post.
+func PrunePostRevisions(c *gin.Context) {
+	post, _, ok := authorizedPost(c, policies.ActionDelete)
+	if !ok {
+		return
+	}
+
+	keep, err := strconv.Atoi(c.Query("keep"))
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput("keep debe ser un número de revisiones"))
+		c.JSON(status, response)
+		return
+	}
+
+	deleted, err := services.NewPostRevisionService(tenantDB(c)).Prune(post.ID, keep)
+	if err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Revisiones eliminadas correctamente",
+		"deleted": deleted,
+	})
+}
+
+// authorizedPost carga el post de la ruta, si el usuario puede verlo, y comprueba que puede
+// realizar la acción sobre él
+func authorizedPost(c *gin.Context, action string) (*models.Post, *auth.Principal, bool) {
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return nil, nil, false
+	}
+
+	var post models.Post
+	if err := tenantDB(c).Scopes(postVisibility(c)).Where("slug = ?", c.Param("slug")).First(&post).Error; err != nil {
+		status, response := services.ErrorResponse(services.ErrNotFound("Post"))
+		c.JSON(status, response)
+		return nil, nil, false
+	}
+
+	if err := policies.NewPostPolicy(config.Policies, tenantDB(c), config.Cache).Authorize(principal, action, &post, c.ClientIP()); err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return nil, nil, false
+	}
+
+	return &post, principal, true
+}
+
+// revisionNumber convierte el número de revisión o responde con un error
+func revisionNumber(c *gin.Context, value string) (uint, bool) {
+	number, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || number == 0 {
+		status, response := services.ErrorResponse(services.ErrInvalidInput("Número de revisión inválido"))
+		c.JSON(status, response)
+		return 0, false
+	}
+	return uint(number), true
+}
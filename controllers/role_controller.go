@@ -1,11 +1,14 @@
 package controllers
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"go-api-orm/config"
 	"go-api-orm/models"
+	"go-api-orm/serializers"
 	"go-api-orm/services"
 )
 
@@ -13,12 +16,26 @@ import (
 type CreateRoleInput struct {
 	Name        string `json:"name" binding:"required"`
 	Description string `json:"description"`
+	ParentID    *uint  `json:"parent_id"` // rol del que hereda los permisos
 }
 
 // UpdateRoleInput representa los datos que se pueden actualizar de un rol
 type UpdateRoleInput struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	ParentID    *uint  `json:"parent_id"` // 0 quita el rol padre
+}
+
+// RoleResponse incluye la cadena de herencia y los permisos efectivos del rol
+type RoleResponse struct {
+	models.Role
+	InheritsFrom         []string `json:"inherits_from"`
+	EffectivePermissions []string `json:"effective_permissions"`
+}
+
+// RolePermissionsInput representa los permisos a asignar a un rol
+type RolePermissionsInput struct {
+	Permissions []string `json:"permissions" binding:"required,min=1"`
 }
 
 // CreateRole crea un nuevo rol
@@ -30,18 +47,34 @@ func CreateRole(c *gin.Context) {
 		return
 	}
 
+	if input.ParentID != nil && *input.ParentID == 0 {
+		input.ParentID = nil
+	}
+	roleService := services.NewRoleService(tenantDB(c), config.Cache)
+	if err := roleService.EnsureNameAvailable(input.Name, 0); err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+	if err := roleService.ValidateParent(0, input.ParentID); err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
 	role := models.Role{
 		Name:        input.Name,
 		Description: input.Description,
+		ParentID:    input.ParentID,
 	}
 
-	if err := config.DB.Create(&role).Error; err != nil {
+	if err := tenantDB(c).Create(&role).Error; err != nil {
 		status, response := services.ErrorResponse(services.ErrInternal(err))
 		c.JSON(status, response)
 		return
 	}
 
-	c.JSON(http.StatusCreated, role)
+	respondRole(c, http.StatusCreated, role)
 }
 
 // GetRoles obtiene todos los roles con paginación y filtros
@@ -52,9 +85,10 @@ func GetRoles(c *gin.Context) {
 	pagination := services.GeneratePaginationFromRequest(c)
 	searchFilters := services.ExtractSearchParams(c)
 	sortParams := services.ExtractSortParams(c)
+	searchFilters, sortParams = visibleFilters(c, serializers.RoleDetail, searchFilters, sortParams)
 	
 	// Aplicar filtros y paginación
-	db := config.DB
+	db := tenantDB(c)
 	db = services.ApplySearchFilters(db, searchFilters)
 	db = services.ApplySorting(db, sortParams)
 	
@@ -104,7 +138,11 @@ func GetRoles(c *gin.Context) {
 	)
 
 	// Construir la respuesta final
-	response := services.BuildAPIResponse(roles, metadataResponse, paginationResponse)
+	data, ok := serialize(c, serializers.RoleDetail, roles)
+	if !ok {
+		return
+	}
+	response := services.BuildAPIResponse(data, metadataResponse, paginationResponse)
 
 	c.JSON(http.StatusOK, response)
 }
@@ -114,13 +152,38 @@ func GetRole(c *gin.Context) {
 	id := c.Param("id")
 	
 	var role models.Role
-	if err := config.DB.First(&role, id).Error; err != nil {
+	if err := tenantDB(c).Preload("Permissions").First(&role, id).Error; err != nil {
 		status, response := services.ErrorResponse(services.ErrNotFound("Role"))
 		c.JSON(status, response)
 		return
 	}
 
-	c.JSON(http.StatusOK, role)
+	lineage, err := services.NewRoleService(tenantDB(c), config.Cache).Lineage(role.ID)
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+	permissions, err := services.NewPermissionService(tenantDB(c), config.Cache).RolePermissions(role.ID)
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+
+	// El primer elemento de la cadena es el propio rol
+	inheritsFrom := []string{}
+	for _, ancestor := range lineage {
+		if ancestor.ID != role.ID {
+			inheritsFrom = append(inheritsFrom, ancestor.Name)
+		}
+	}
+
+	respondRole(c, http.StatusOK, RoleResponse{
+		Role:                 role,
+		InheritsFrom:         inheritsFrom,
+		EffectivePermissions: permissions,
+	})
 }
 
 // UpdateRole actualiza un rol existente
@@ -128,11 +191,14 @@ func UpdateRole(c *gin.Context) {
 	id := c.Param("id")
 	
 	var role models.Role
-	if err := config.DB.First(&role, id).Error; err != nil {
+	if err := tenantDB(c).First(&role, id).Error; err != nil {
 		status, response := services.ErrorResponse(services.ErrNotFound("Role"))
 		c.JSON(status, response)
 		return
 	}
+	if !ensureRoleEditable(c, &role) {
+		return
+	}
 
 	var input UpdateRoleInput
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -141,40 +207,198 @@ func UpdateRole(c *gin.Context) {
 		return
 	}
 
+	if services.IsProtected(&role) && input.Name != "" && input.Name != role.Name {
+		status, response := services.ErrorResponse(services.ErrSystemRole(fmt.Sprintf("El rol %s es un rol del sistema o el rol por defecto", role.Name)))
+		c.JSON(status, response)
+		return
+	}
+
 	// Actualizar solo los campos proporcionados
 	updates := map[string]interface{}{}
-	if input.Name != "" {
+	if input.Name != "" && input.Name != role.Name {
+		if err := services.NewRoleService(tenantDB(c), config.Cache).EnsureNameAvailable(input.Name, role.ID); err != nil {
+			status, response := services.ErrorResponse(err)
+			c.JSON(status, response)
+			return
+		}
 		updates["name"] = input.Name
 	}
 	if input.Description != "" {
 		updates["description"] = input.Description
 	}
+	if input.ParentID != nil {
+		if *input.ParentID == 0 {
+			updates["parent_id"] = nil
+		} else {
+			if err := services.NewRoleService(tenantDB(c), config.Cache).ValidateParent(role.ID, input.ParentID); err != nil {
+				status, response := services.ErrorResponse(err)
+				c.JSON(status, response)
+				return
+			}
+			updates["parent_id"] = *input.ParentID
+		}
+	}
 
-	if err := config.DB.Model(&role).Updates(updates).Error; err != nil {
+	if err := tenantDB(c).Model(&role).Updates(updates).Error; err != nil {
 		status, response := services.ErrorResponse(services.ErrInternal(err))
 		c.JSON(status, response)
 		return
 	}
 
-	c.JSON(http.StatusOK, role)
+	// Los permisos cacheados incluyen los heredados y la cadena cacheada incluye los nombres
+	services.NewPermissionService(tenantDB(c), config.Cache).Invalidate(role.ID)
+
+	respondRole(c, http.StatusOK, role)
 }
 
-// DeleteRole elimina un rol
+// DeleteRole elimina un rol. Si tiene usuarios hay que indicar el rol al que se reasignan
+// con ?reassign_to=<id>. Los roles del sistema no se pueden eliminar.
 func DeleteRole(c *gin.Context) {
 	id := c.Param("id")
+
+	var reassignTo uint64
+	if value := c.Query("reassign_to"); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			status, response := services.ErrorResponse(services.ErrInvalidInput("reassign_to debe ser el ID de un rol"))
+			c.JSON(status, response)
+			return
+		}
+		reassignTo = parsed
+	}
 	
 	var role models.Role
-	if err := config.DB.First(&role, id).Error; err != nil {
+	if err := tenantDB(c).First(&role, id).Error; err != nil {
 		status, response := services.ErrorResponse(services.ErrNotFound("Role"))
 		c.JSON(status, response)
 		return
 	}
+	if !ensureRoleEditable(c, &role) {
+		return
+	}
 
-	if err := config.DB.Delete(&role).Error; err != nil {
-		status, response := services.ErrorResponse(services.ErrInternal(err))
+	// Los usuarios se reasignan y los roles que heredaban de él pasan a heredar de su padre
+	if err := services.NewRoleService(tenantDB(c), config.Cache).Delete(&role, uint(reassignTo)); err != nil {
+		status, response := services.ErrorResponse(err)
 		c.JSON(status, response)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Role deleted successfully"})
 }
+
+// GetPermissions lista todos los permisos disponibles
+func GetPermissions(c *gin.Context) {
+	permissions, err := services.NewPermissionService(tenantDB(c), config.Cache).List()
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+
+	data, ok := serialize(c, serializers.Permission, permissions)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": data})
+}
+
+// GrantRolePermissions asigna permisos a un rol
+func GrantRolePermissions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	var input RolePermissionsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	var existing models.Role
+	if err := tenantDB(c).First(&existing, id).Error; err != nil {
+		status, response := services.ErrorResponse(services.ErrNotFound("Role"))
+		c.JSON(status, response)
+		return
+	}
+	if !ensureRoleEditable(c, &existing) {
+		return
+	}
+
+	role, err := services.NewPermissionService(tenantDB(c), config.Cache).Grant(uint(id), input.Permissions)
+	if err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	respondRole(c, http.StatusOK, role)
+}
+
+// RevokeRolePermission quita un permiso a un rol
+func RevokeRolePermission(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	var existing models.Role
+	if err := tenantDB(c).First(&existing, id).Error; err != nil {
+		status, response := services.ErrorResponse(services.ErrNotFound("Role"))
+		c.JSON(status, response)
+		return
+	}
+	if !ensureRoleEditable(c, &existing) {
+		return
+	}
+
+	role, err := services.NewPermissionService(tenantDB(c), config.Cache).Revoke(uint(id), c.Param("permission"))
+	if err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	respondRole(c, http.StatusOK, role)
+}
+
+// ensureRoleEditable comprueba que el rol se pueda modificar desde la organización de la petición.
+// Los roles globales (tenant_id 0) afectan a todas las organizaciones y solo se modifican
+// desde la organización por defecto.
+func ensureRoleEditable(c *gin.Context, role *models.Role) bool {
+	if role.TenantID != 0 {
+		return true
+	}
+
+	principal, ok := currentPrincipal(c)
+	if !ok {
+		return false
+	}
+	organization, err := services.NewOrganizationService(config.DB, config.Cache).Default()
+	if err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return false
+	}
+	if principal.TenantID != organization.ID {
+		status, response := services.ErrorResponse(services.ErrForbidden("Los roles globales solo se pueden modificar desde la organización por defecto"))
+		c.JSON(status, response)
+		return false
+	}
+	return true
+}
+
+// respondRole responde con el rol aplicando su serializador
+func respondRole(c *gin.Context, status int, value interface{}) {
+	data, ok := serialize(c, serializers.RoleDetail, value)
+	if !ok {
+		return
+	}
+	c.JSON(status, data)
+}
@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go-api-orm/models"
+	"go-api-orm/serializers"
+	"go-api-orm/services"
+)
+
+// CreateTagInput representa los datos necesarios para crear una etiqueta
+type CreateTagInput struct {
+	Name string `json:"name" binding:"required,max=50"`
+	Slug string `json:"slug" binding:"max=100"` // opcional; se genera a partir del nombre
+}
+
+// UpdateTagInput representa los datos que se pueden actualizar de una etiqueta
+type UpdateTagInput struct {
+	Name string `json:"name" binding:"max=50"`
+	Slug string `json:"slug" binding:"max=100"`
+}
+
+// CreateTag crea una etiqueta
+func CreateTag(c *gin.Context) {
+	var input CreateTagInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	if input.Slug != "" {
+		if err := services.NewTaxonomyService(tenantDB(c)).EnsureSlugAvailable(&models.Tag{}, input.Slug, 0); err != nil {
+			status, response := services.ErrorResponse(err)
+			c.JSON(status, response)
+			return
+		}
+	} else if models.GenerateSlug(input.Name) == "" {
+		status, response := services.ErrorResponse(services.ErrInvalidInput("El nombre debe contener letras o números"))
+		c.JSON(status, response)
+		return
+	}
+
+	tag := models.Tag{
+		Name: input.Name,
+		Slug: input.Slug, // Si está vacío, el hook BeforeCreate generará uno
+	}
+
+	if err := tenantDB(c).Create(&tag).Error; err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+
+	respondTaxonomy(c, http.StatusCreated, serializers.Tag, tag)
+}
+
+// GetTags obtiene las etiquetas con paginación y filtros
+func GetTags(c *gin.Context) {
+	var tags []models.Tag
+
+	// Obtener parámetros de paginación y búsqueda
+	pagination := services.GeneratePaginationFromRequest(c)
+	searchFilters := services.ExtractSearchParams(c)
+	sortParams := services.ExtractSortParams(c)
+	searchFilters, sortParams = visibleFilters(c, serializers.Tag, searchFilters, sortParams)
+
+	// Aplicar filtros y paginación
+	db := tenantDB(c)
+	db = services.ApplySearchFilters(db, searchFilters)
+	db = services.ApplySorting(db, sortParams)
+
+	err := db.Scopes(services.Paginate(tags, &pagination, db)).Find(&tags).Error
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+
+	// Construir los componentes de la respuesta
+	paginationResponse := services.BuildPaginationResponse(c, pagination.Page, pagination.Limit, pagination.TotalRows, pagination.TotalPages)
+
+	metadataResponse := services.BuildMetadataResponse(
+		[]services.SearchField{
+			{
+				Name:        "name",
+				Type:        "string",
+				Description: "Nombre de la etiqueta",
+				Operators:   []string{"eq", "like", "nlike"},
+			},
+			{
+				Name:        "slug",
+				Type:        "string",
+				Description: "Slug de la etiqueta",
+				Operators:   []string{"eq", "in"},
+			},
+		},
+		[]services.SortField{
+			{
+				Name:        "name",
+				Description: "Ordenar por nombre",
+			},
+			{
+				Name:        "created_at",
+				Description: "Ordenar por fecha de creación",
+			},
+		},
+		searchFilters,
+		sortParams,
+	)
+
+	// Construir la respuesta final
+	data, ok := serialize(c, serializers.Tag, tags)
+	if !ok {
+		return
+	}
+	response := services.BuildAPIResponse(data, metadataResponse, paginationResponse)
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetTag obtiene una etiqueta por su slug
+func GetTag(c *gin.Context) {
+	var tag models.Tag
+	if err := tenantDB(c).Where("slug = ?", c.Param("slug")).First(&tag).Error; err != nil {
+		status, response := services.ErrorResponse(services.ErrNotFound("Etiqueta"))
+		c.JSON(status, response)
+		return
+	}
+
+	respondTaxonomy(c, http.StatusOK, serializers.Tag, tag)
+}
+
+// UpdateTag cambia el nombre o el slug de una etiqueta. El slug no cambia al renombrarla.
+func UpdateTag(c *gin.Context) {
+	var tag models.Tag
+	if err := tenantDB(c).Where("slug = ?", c.Param("slug")).First(&tag).Error; err != nil {
+		status, response := services.ErrorResponse(services.ErrNotFound("Etiqueta"))
+		c.JSON(status, response)
+		return
+	}
+
+	var input UpdateTagInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	// Actualizar solo los campos proporcionados
+	updates := map[string]interface{}{}
+	if input.Name != "" {
+		updates["name"] = input.Name
+	}
+	if input.Slug != "" {
+		if err := services.NewTaxonomyService(tenantDB(c)).EnsureSlugAvailable(&models.Tag{}, input.Slug, tag.ID); err != nil {
+			status, response := services.ErrorResponse(err)
+			c.JSON(status, response)
+			return
+		}
+		updates["slug"] = input.Slug
+	}
+
+	if err := tenantDB(c).Model(&tag).Updates(updates).Error; err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+
+	respondTaxonomy(c, http.StatusOK, serializers.Tag, tag)
+}
+
+// DeleteTag elimina una etiqueta y la quita de los posts que la usan
+func DeleteTag(c *gin.Context) {
+	var tag models.Tag
+	if err := tenantDB(c).Where("slug = ?", c.Param("slug")).First(&tag).Error; err != nil {
+		status, response := services.ErrorResponse(services.ErrNotFound("Etiqueta"))
+		c.JSON(status, response)
+		return
+	}
+
+	if err := services.NewTaxonomyService(tenantDB(c)).DeleteTag(&tag); err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Etiqueta eliminada correctamente"})
+}
+
+// respondTaxonomy responde con la etiqueta o la categoría aplicando su serializador
+func respondTaxonomy(c *gin.Context, status int, serializer *serializers.Serializer, value interface{}) {
+	data, ok := serialize(c, serializer, value)
+	if !ok {
+		return
+	}
+	c.JSON(status, data)
+}
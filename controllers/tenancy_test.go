@@ -0,0 +1,236 @@
+package controllers
+
+import (
+	"net/http"
+	"sort"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go-api-orm/auth"
+	"go-api-orm/config"
+	"go-api-orm/middleware"
+	"go-api-orm/models"
+	"go-api-orm/services"
+	"go-api-orm/tenancy"
+)
+
+// tenantFixture son dos organizaciones, cada una con un administrador y sus posts
+type tenantFixture struct {
+	router   *gin.Engine
+	anaToken string // administradora de la organización por defecto
+	bobToken string // administrador de acme
+}
+
+func newTenantFixture(t *testing.T) *tenantFixture {
+	t.Helper()
+	db := tenancy.Unscoped(newTestApp(t))
+
+	home, err := services.NewOrganizationService(db, config.Cache).Default()
+	if err != nil {
+		t.Fatalf("organización por defecto: %v", err)
+	}
+	acme := models.Organization{Name: "Acme", Slug: "acme"}
+	if err := db.Create(&acme).Error; err != nil {
+		t.Fatalf("crear organización: %v", err)
+	}
+	var admin models.Role
+	if err := db.Where("name = ? AND tenant_id = 0", services.AdminRole).First(&admin).Error; err != nil {
+		t.Fatalf("rol admin: %v", err)
+	}
+
+	ana := models.User{Username: "ana", Email: "ana@example.com", Password: "Secreta123!", RoleID: admin.ID, TenantID: home.ID}
+	bob := models.User{Username: "bob", Email: "bob@example.com", Password: "Secreta123!", RoleID: admin.ID, TenantID: acme.ID}
+	for _, user := range []*models.User{&ana, &bob} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("crear usuario: %v", err)
+		}
+	}
+	for _, post := range []models.Post{
+		// Las dos organizaciones tienen una etiqueta con el mismo slug
+		{Title: "Hola desde default", AuthorID: ana.ID, TenantID: home.ID, Status: models.PostStatusPublished,
+			Tags: []models.Tag{{Name: "Go", Slug: "go", TenantID: home.ID}}},
+		{Title: "Hola desde acme", AuthorID: bob.ID, TenantID: acme.ID, Status: models.PostStatusPublished,
+			Tags: []models.Tag{{Name: "Go", Slug: "go", TenantID: acme.ID}}},
+		{Title: "Borrador de acme", AuthorID: bob.ID, TenantID: acme.ID, Status: models.PostStatusDraft},
+	} {
+		if err := db.Create(&post).Error; err != nil {
+			t.Fatalf("crear post: %v", err)
+		}
+	}
+
+	anaToken, err := auth.IssueAccessToken(ana.ID, home.ID, admin.Name, admin.ID, 0)
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+	bobToken, err := auth.IssueAccessToken(bob.ID, acme.ID, admin.Name, admin.ID, 0)
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+
+	router := gin.New()
+	router.Use(middleware.Tenant())
+	router.GET("/api/posts", middleware.OptionalAuth(), GetPosts)
+	router.GET("/api/users", middleware.AuthMiddleware(), middleware.RequirePermission("users:read"), GetUsers)
+
+	return &tenantFixture{router: router, anaToken: anaToken, bobToken: bobToken}
+}
+
+// field retorna el campo de cada elemento de data, ordenados, y el total de la paginación
+func field(t *testing.T, body map[string]interface{}, name string) ([]string, float64) {
+	t.Helper()
+
+	items, ok := body["data"].([]interface{})
+	if !ok {
+		t.Fatalf("respuesta sin data: %v", body)
+	}
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		values = append(values, item.(map[string]interface{})[name].(string))
+	}
+	sort.Strings(values)
+
+	pagination, ok := body["pagination"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("respuesta sin paginación: %v", body)
+	}
+	return values, pagination["total_items"].(float64)
+}
+
+func bearer(token string) map[string]string {
+	return map[string]string{"Authorization": "Bearer " + token}
+}
+
+func TestGetUsersOnlyReturnsTheTokenOrganization(t *testing.T) {
+	fixture := newTenantFixture(t)
+
+	for _, tc := range []struct {
+		name     string
+		token    string
+		path     string
+		expected []string
+	}{
+		{"default", fixture.anaToken, "/api/users", []string{"ana"}},
+		{"acme", fixture.bobToken, "/api/users", []string{"bob"}},
+		{"búsqueda de un usuario de otra organización", fixture.anaToken, "/api/users?search=username:eq:bob", []string{}},
+		{"búsqueda por email", fixture.anaToken, "/api/users?search=email:like:example.com", []string{"ana"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			status, body := getJSON(t, fixture.router, tc.path, bearer(tc.token))
+			if status != http.StatusOK {
+				t.Fatalf("status = %d: %v", status, body)
+			}
+			usernames, total := field(t, body, "username")
+			if len(usernames) != len(tc.expected) || int(total) != len(tc.expected) {
+				t.Fatalf("usuarios = %v (total %v), se esperaba %v", usernames, total, tc.expected)
+			}
+			for i := range usernames {
+				if usernames[i] != tc.expected[i] {
+					t.Errorf("usuarios = %v, se esperaba %v", usernames, tc.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestGetPostsOnlyReturnsTheRequestOrganization(t *testing.T) {
+	fixture := newTenantFixture(t)
+
+	for _, tc := range []struct {
+		name     string
+		path     string
+		headers  map[string]string
+		expected []string
+	}{
+		{"anónimo en la organización por defecto", "/api/posts", nil, []string{"Hola desde default"}},
+		{"anónimo con X-Organization", "/api/posts", map[string]string{middleware.OrganizationHeader: "acme"}, []string{"Hola desde acme"}},
+		{"administrador de acme", "/api/posts", bearer(fixture.bobToken), []string{"Borrador de acme", "Hola desde acme"}},
+		{"búsqueda de posts de otra organización", "/api/posts?search=title:like:acme", bearer(fixture.anaToken), []string{}},
+		{"búsqueda por estado", "/api/posts?search=status:in:draft,published", bearer(fixture.anaToken), []string{"Hola desde default"}},
+		{"búsqueda por etiqueta", "/api/posts?search=tag:eq:go", bearer(fixture.bobToken), []string{"Hola desde acme"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			status, body := getJSON(t, fixture.router, tc.path, tc.headers)
+			if status != http.StatusOK {
+				t.Fatalf("status = %d: %v", status, body)
+			}
+			titles, total := field(t, body, "title")
+			if len(titles) != len(tc.expected) || int(total) != len(tc.expected) {
+				t.Fatalf("posts = %v (total %v), se esperaba %v", titles, total, tc.expected)
+			}
+			for i := range titles {
+				if titles[i] != tc.expected[i] {
+					t.Errorf("posts = %v, se esperaba %v", titles, tc.expected)
+				}
+			}
+		})
+	}
+
+	// El token fija la organización: la cabecera X-Organization no permite leer otra
+	headers := bearer(fixture.anaToken)
+	headers[middleware.OrganizationHeader] = "acme"
+	_, body := getJSON(t, fixture.router, "/api/posts", headers)
+	if titles, _ := field(t, body, "title"); len(titles) != 1 || titles[0] != "Hola desde default" {
+		t.Errorf("con X-Organization de otra organización se obtuvo %v", titles)
+	}
+}
+
+func TestPostsOfAMemberIncludeTheirAuthor(t *testing.T) {
+	db := tenancy.Unscoped(newTestApp(t))
+
+	home, err := services.NewOrganizationService(db, config.Cache).Default()
+	if err != nil {
+		t.Fatalf("organización por defecto: %v", err)
+	}
+	acme := models.Organization{Name: "Acme", Slug: "acme"}
+	if err := db.Create(&acme).Error; err != nil {
+		t.Fatalf("crear organización: %v", err)
+	}
+	var admin models.Role
+	if err := db.Where("name = ? AND tenant_id = 0", services.AdminRole).First(&admin).Error; err != nil {
+		t.Fatalf("rol admin: %v", err)
+	}
+
+	// ana pertenece a la organización por defecto y es miembro de acme
+	ana := models.User{Username: "ana", Email: "ana@example.com", Password: "Secreta123!", RoleID: admin.ID, TenantID: home.ID}
+	if err := db.Create(&ana).Error; err != nil {
+		t.Fatalf("crear usuario: %v", err)
+	}
+	if err := db.Create(&models.Membership{OrganizationID: acme.ID, UserID: ana.ID, RoleID: admin.ID}).Error; err != nil {
+		t.Fatalf("crear membresía: %v", err)
+	}
+	post := models.Post{Title: "Hola desde acme", Content: "Contenido", AuthorID: ana.ID, TenantID: acme.ID, Status: models.PostStatusPublished}
+	if err := db.Create(&post).Error; err != nil {
+		t.Fatalf("crear post: %v", err)
+	}
+	if _, err := services.NewPostRevisionService(db).Record(&post, ana.ID); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	token, err := auth.IssueAccessToken(ana.ID, acme.ID, admin.Name, admin.ID, 0)
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+
+	router := gin.New()
+	router.Use(middleware.Tenant())
+	router.GET("/api/posts", middleware.OptionalAuth(), GetPosts)
+	router.GET("/api/posts/:slug", middleware.OptionalAuth(), GetPostBySlug)
+	router.GET("/api/posts/:slug/revisions", middleware.AuthMiddleware(), GetPostRevisions)
+
+	authorID := func(item interface{}) interface{} {
+		author, _ := item.(map[string]interface{})["author"].(map[string]interface{})
+		return author["id"]
+	}
+	anonymous := map[string]string{middleware.OrganizationHeader: "acme"}
+
+	_, body := getJSON(t, router, "/api/posts", anonymous)
+	if items, _ := body["data"].([]interface{}); len(items) != 1 || authorID(items[0]) != float64(ana.ID) {
+		t.Errorf("GET /api/posts = %v, se esperaba el autor %d", body, ana.ID)
+	}
+	if _, body := getJSON(t, router, "/api/posts/"+post.Slug, anonymous); authorID(body) != float64(ana.ID) {
+		t.Errorf("GET /api/posts/%s = %v, se esperaba el autor %d", post.Slug, body, ana.ID)
+	}
+	_, body = getJSON(t, router, "/api/posts/"+post.Slug+"/revisions", bearer(token))
+	if items, _ := body["data"].([]interface{}); len(items) != 1 || authorID(items[0]) != float64(ana.ID) {
+		t.Errorf("GET /api/posts/%s/revisions = %v, se esperaba el autor %d", post.Slug, body, ana.ID)
+	}
+}
@@ -1,22 +1,33 @@
 package controllers
 
 import (
+	"fmt"
+	"log"
+	"math"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go-api-orm/auth"
 	"go-api-orm/config"
 	"go-api-orm/models"
+	"go-api-orm/serializers"
 	"go-api-orm/services"
-	"go-api-orm/utils"
+	"go-api-orm/tenancy"
+	"gorm.io/gorm"
 )
 
 type RegisterInput struct {
-	Username string `json:"username" binding:"required"`
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required"`
-	RoleID   uint   `json:"role" binding:"omitempty"`
+	Username    string `json:"username" binding:"required"`
+	Email       string `json:"email" binding:"required,email"`
+	Password    string `json:"password" binding:"required"`
+	InviteToken string `json:"invite_token"` // invitación para registrarse con otro rol
+}
+
+// UpdateUserRoleInput representa el nuevo rol de un usuario
+type UpdateUserRoleInput struct {
+	RoleID uint `json:"role_id" binding:"required"`
 }
 
 type LoginInput struct {
@@ -55,8 +66,19 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	// Rechazar el intento si la cuenta o la IP están bloqueadas o en espera
+	throttle := services.NewLoginThrottleService(config.DB, config.Cache)
+	if err := checkLoginThrottle(c, throttle, input.Email); err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
 	var user models.User
-	if err := config.DB.Preload("Role").Where("email = ?", input.Email).First(&user).Error; err != nil {
+	if err := tenancy.Unscoped(config.DB).Preload("Role").Where("email = ?", input.Email).First(&user).Error; err != nil {
+		if err := throttle.RecordFailure(input.Email, c.ClientIP()); err != nil {
+			log.Printf("Error recording failed login: %v", err)
+		}
 		status, response := services.ErrorResponse(services.NewAPIError(
 			http.StatusUnauthorized,
 			"INVALID_CREDENTIALS",
@@ -68,7 +90,11 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	if err := user.CheckPassword(input.Password); err != nil {
+	// Verificar la contraseña y actualizar su hash si usa un algoritmo o coste anterior
+	if err := services.NewPasswordService(config.DB).Verify(&user, input.Password); err != nil {
+		if err := throttle.RecordFailure(input.Email, c.ClientIP()); err != nil {
+			log.Printf("Error recording failed login: %v", err)
+		}
 		status, response := services.ErrorResponse(services.NewAPIError(
 			http.StatusUnauthorized,
 			"INVALID_CREDENTIALS",
@@ -80,8 +106,61 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// Generar token JWT
-	token, err := utils.GenerateToken(user.ID, user.Role.Name)
+	completeLogin(c, &user)
+}
+
+// checkLoginThrottle retorna el error del bloqueo si la cuenta o la IP no pueden intentar un
+// login y, si hay que esperar, indica en Retry-After cuándo reintentar
+func checkLoginThrottle(c *gin.Context, throttle *services.LoginThrottleService, email string) error {
+	retryAfter, err := throttle.Check(email, c.ClientIP())
+	if err != nil && retryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	}
+	return err
+}
+
+// recordLoginSuccess reinicia los intentos fallidos de la cuenta cuando el login termina,
+// con el segundo factor incluido; una contraseña correcta sola no los reinicia
+func recordLoginSuccess(email string) {
+	if err := services.NewLoginThrottleService(config.DB, config.Cache).RecordSuccess(email); err != nil {
+		log.Printf("Error resetting failed logins: %v", err)
+	}
+}
+
+// completeLogin finaliza un login cuyas credenciales ya fueron verificadas: aplica la
+// verificación de email y el segundo factor, o entrega el JWT y el token de refresco
+func completeLogin(c *gin.Context, user *models.User) {
+	// Rechazar cuentas sin email verificado si así está configurado
+	if services.EmailVerificationMode() == services.EmailVerificationLogin && !user.IsVerified() {
+		status, response := services.ErrorResponse(services.ErrEmailNotVerified())
+		c.JSON(status, response)
+		return
+	}
+
+	// Autenticación en dos pasos: se entrega un token intermedio en lugar del JWT
+	if user.MFAEnabled() || services.IsMFARequiredForRole(user.Role.Name) {
+		purpose := services.MFAPendingPurpose
+		if !user.MFAEnabled() {
+			purpose = services.MFAEnrollmentPurpose
+		}
+
+		mfaToken, err := auth.IssuePurposeToken(user.ID, purpose, services.MFATokenTTL())
+		if err != nil {
+			status, response := services.ErrorResponse(services.ErrInternal(err))
+			c.JSON(status, response)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required":            purpose == services.MFAPendingPurpose,
+			"mfa_enrollment_required": purpose == services.MFAEnrollmentPurpose,
+			"mfa_token":               mfaToken,
+			"expires_in":              int(services.MFATokenTTL().Seconds()),
+		})
+		return
+	}
+
+	response, err := loginResponse(c, user)
 	if err != nil {
 		status, response := services.ErrorResponse(services.NewAPIError(
 			http.StatusInternalServerError,
@@ -93,16 +172,43 @@ func Login(c *gin.Context) {
 		c.JSON(status, response)
 		return
 	}
+	recordLoginSuccess(user.Email)
 
-	c.JSON(http.StatusOK, gin.H{
+	c.JSON(http.StatusOK, response)
+}
+
+// loginResponse registra una nueva sesión y genera el token de acceso y el de refresco
+// de un usuario autenticado
+func loginResponse(c *gin.Context, user *models.User) (gin.H, error) {
+	session, err := services.NewSessionService(config.DB, config.Cache).Create(user.ID, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		return nil, err
+	}
+
+	// Generar token JWT vinculado a la sesión
+	token, err := auth.IssueAccessToken(user.ID, user.TenantID, user.Role.Name, user.RoleID, session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Generar token de refresco
+	refreshToken, err := services.NewRefreshTokenService(config.DB).Issue(user.ID, &session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return gin.H{
 		"user": gin.H{
 			"id":       user.ID,
 			"username": user.Username,
 			"email":    user.Email,
 			"role":     user.Role.Name,
 		},
-		"token": token,
-	})
+		"token":         token,
+		"refresh_token": refreshToken,
+		"expires_in":    int(auth.AccessTokenTTL().Seconds()),
+		"session_id":    session.ID,
+	}, nil
 }
 
 // Register maneja el registro de nuevos usuarios
@@ -120,45 +226,40 @@ func Register(c *gin.Context) {
 		return
 	}
 
-	// Si no se proporciona un rol, usar el rol por defecto (user)
-	if input.RoleID == 0 {
-		var defaultRole models.Role
-		if err := config.DB.Where("name = ?", "user").First(&defaultRole).Error; err != nil {
-			status, response := services.ErrorResponse(services.NewAPIError(
-				http.StatusInternalServerError,
-				"INTERNAL_ERROR",
-				"Error interno",
-				err.Error(),
-				nil,
-			))
-			c.JSON(status, response)
-			return
-		}
-		input.RoleID = defaultRole.ID
-	} else {
-		// Verificar si el rol existe
-		var role models.Role
-		if err := config.DB.First(&role, input.RoleID).Error; err != nil {
-			status, response := services.ErrorResponse(services.NewAPIError(
-				http.StatusBadRequest,
-				"INVALID_ROLE",
-				"Rol inválido",
-				"El rol especificado no existe",
-				nil,
-			))
-			c.JSON(status, response)
-			return
-		}
+	if valid, message := services.NewValidationService().ValidatePassword(input.Password); !valid {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(message))
+		c.JSON(status, response)
+		return
 	}
 
 	user := models.User{
 		Username: input.Username,
 		Email:    input.Email,
 		Password: input.Password,
-		RoleID:   input.RoleID,
 	}
 
-	if err := config.DB.Create(&user).Error; err != nil {
+	// El rol, la organización y la invitación se resuelven en la misma transacción que crea el usuario.
+	// La organización depende de la invitación, así que la transacción no se limita a ninguna.
+	err := tenancy.Unscoped(config.DB).Transaction(func(tx *gorm.DB) error {
+		role, tenantID, err := registrationRole(tx, &input)
+		if err != nil {
+			return err
+		}
+		user.RoleID = role.ID
+		user.TenantID = tenantID
+		// La invitación llegó por correo, así que el email ya está verificado
+		if input.InviteToken != "" {
+			now := time.Now()
+			user.VerifiedAt = &now
+		}
+		return tx.Create(&user).Error
+	})
+	if err != nil {
+		if apiErr, ok := err.(*services.APIError); ok {
+			status, response := services.ErrorResponse(apiErr)
+			c.JSON(status, response)
+			return
+		}
 		status, response := services.ErrorResponse(services.NewAPIError(
 			http.StatusInternalServerError,
 			"INTERNAL_ERROR",
@@ -171,10 +272,15 @@ func Register(c *gin.Context) {
 	}
 
 	// Recargar el usuario para obtener la relación con el rol
-	config.DB.Preload("Role").First(&user, user.ID)
+	tenancy.Unscoped(config.DB).Preload("Role").First(&user, user.ID)
+
+	// Enviar el enlace de verificación de email
+	if user.VerifiedAt == nil {
+		sendVerificationEmail(&user)
+	}
 
 	response := RegisterResponse{
-		Message: "Usuario creado exitosamente",
+		Message: "Usuario creado exitosamente. Revisa tu correo para verificar tu email",
 		Data: UserDataResponse{
 			ID:        user.ID,
 			Email:     user.Email,
@@ -188,6 +294,74 @@ func Register(c *gin.Context) {
 	c.JSON(http.StatusCreated, response)
 }
 
+// registrationRole retorna el rol y la organización de un usuario nuevo: los de su invitación,
+// o la organización por defecto con el rol por defecto
+func registrationRole(tx *gorm.DB, input *RegisterInput) (*models.Role, uint, error) {
+	if input.InviteToken != "" {
+		invitation, err := services.NewInvitationService(tx, nil).Accept(input.InviteToken, input.Email)
+		if err != nil {
+			return nil, 0, err
+		}
+		var role models.Role
+		if err := tx.First(&role, invitation.RoleID).Error; err != nil {
+			return nil, 0, services.ErrInvalidRole()
+		}
+		return &role, invitation.TenantID, nil
+	}
+
+	organization, err := services.NewOrganizationService(tx, config.Cache).Default()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	role, err := services.DefaultRole(tx)
+	if err != nil {
+		return nil, 0, err
+	}
+	return role, organization.ID, nil
+}
+
+// UpdateUserRole cambia el rol de un usuario (solo administradores). Se revocan sus tokens y
+// sesiones para que el nuevo rol se aplique de inmediato en su próximo inicio de sesión; sus
+// claves de API se mantienen y aplican el nuevo rol en la siguiente petición.
+func UpdateUserRole(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	var input UpdateUserRoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidInput(err.Error()))
+		c.JSON(status, response)
+		return
+	}
+
+	user, err := services.NewRoleService(tenantDB(c), config.Cache).AssignToUser(uint(id), input.RoleID)
+	if err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		return
+	}
+
+	if err := services.NewTokenRevocationService(config.DB, config.Cache).RevokeSessionsForUser(user.ID); err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+
+	data, ok := serialize(c, serializers.User, user)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Rol actualizado correctamente",
+		"data":    data,
+	})
+}
+
 // GetUsers obtiene la lista de usuarios
 func GetUsers(c *gin.Context) {
 	var users []models.User
@@ -196,9 +370,10 @@ func GetUsers(c *gin.Context) {
 	pagination := services.GeneratePaginationFromRequest(c)
 	searchFilters := services.ExtractSearchParams(c)
 	sortParams := services.ExtractSortParams(c)
+	searchFilters, sortParams = visibleFilters(c, serializers.User, searchFilters, sortParams)
 	
 	// Aplicar filtros y paginación
-	db := config.DB.Preload("Role")
+	db := tenantDB(c).Preload("Role")
 	db = services.ApplySearchFilters(db, searchFilters)
 	db = services.ApplySorting(db, sortParams)
 	
@@ -254,7 +429,11 @@ func GetUsers(c *gin.Context) {
 	)
 
 	// Construir la respuesta final
-	response := services.BuildAPIResponse(users, metadataResponse, paginationResponse)
+	data, ok := serialize(c, serializers.User, users)
+	if !ok {
+		return
+	}
+	response := services.BuildAPIResponse(data, metadataResponse, paginationResponse)
 
 	c.JSON(http.StatusOK, response)
 }
@@ -264,7 +443,7 @@ func GetUser(c *gin.Context) {
 	id := c.Param("id")
 	var user models.User
 
-	if err := config.DB.Preload("Role").First(&user, id).Error; err != nil {
+	if err := tenantDB(c).Preload("Role").First(&user, id).Error; err != nil {
 		status, response := services.ErrorResponse(services.NewAPIError(
 			http.StatusNotFound,
 			"NOT_FOUND",
@@ -276,12 +455,11 @@ func GetUser(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"id":       user.ID,
-		"username": user.Username,
-		"email":    user.Email,
-		"role":     user.Role.Name,
-	})
+	data, ok := serialize(c, serializers.User, user)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, data)
 }
 
 // UpdateUser actualiza un usuario existente
@@ -300,8 +478,8 @@ func UpdateUser(c *gin.Context) {
 	}
 
 	// Verificar si el usuario tiene permisos para actualizar este usuario
-	userId, exists := c.Get("user_id")
-	if !exists || (uint(id) != userId.(uint) && c.GetString("user_role") != "admin") {
+	principal, ok := auth.CurrentUser(c)
+	if !ok || (uint(id) != principal.UserID && !isAdmin(principal.RoleID)) {
 		status, response := services.ErrorResponse(services.NewAPIError(
 			http.StatusForbidden,
 			"FORBIDDEN",
@@ -313,8 +491,14 @@ func UpdateUser(c *gin.Context) {
 		return
 	}
 
+	// El propio usuario puede actualizar su perfil aunque actúe en otra organización
+	db := tenantDB(c)
+	if uint(id) == principal.UserID {
+		db = tenancy.Unscoped(config.DB)
+	}
+
 	var user models.User
-	if err := config.DB.Preload("Role").First(&user, id).Error; err != nil {
+	if err := db.Preload("Role").First(&user, id).Error; err != nil {
 		status, response := services.ErrorResponse(services.NewAPIError(
 			http.StatusNotFound,
 			"NOT_FOUND",
@@ -347,11 +531,15 @@ func UpdateUser(c *gin.Context) {
 	if input.Username != "" {
 		updates["username"] = input.Username
 	}
-	if input.Email != "" {
+	emailChanged := input.Email != "" && input.Email != user.Email
+	if emailChanged {
+		// El nuevo email debe verificarse de nuevo
 		updates["email"] = input.Email
+		updates["verified_at"] = nil
 	}
 
-	if err := config.DB.Model(&user).Updates(updates).Error; err != nil {
+	// Sin el rol precargado en el modelo: GORM guardaría también la asociación
+	if err := db.Model(&models.User{}).Where("id = ?", user.ID).Updates(updates).Error; err != nil {
 		status, response := services.ErrorResponse(services.NewAPIError(
 			http.StatusInternalServerError,
 			"INTERNAL_ERROR",
@@ -363,32 +551,29 @@ func UpdateUser(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"id":       user.ID,
-		"username": user.Username,
-		"email":    user.Email,
-		"role":     user.Role.Name,
-	})
+	if err := db.Preload("Role").First(&user, user.ID).Error; err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		return
+	}
+
+	if emailChanged {
+		config.Cache.Delete(fmt.Sprintf("email_verified:%d", user.ID))
+		sendVerificationEmail(&user)
+	}
+
+	data, ok := serialize(c, serializers.User, user)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, data)
 }
 
 // DeleteUser elimina un usuario
 func DeleteUser(c *gin.Context) {
 	id := c.Param("id")
 
-	// Solo los administradores pueden eliminar usuarios
-	if c.GetString("user_role") != "admin" {
-		status, response := services.ErrorResponse(services.NewAPIError(
-			http.StatusForbidden,
-			"FORBIDDEN",
-			"Admin privileges required",
-			"",
-			nil,
-		))
-		c.JSON(status, response)
-		return
-	}
-
-	if err := config.DB.Delete(&models.User{}, id).Error; err != nil {
+	if err := tenantDB(c).Delete(&models.User{}, id).Error; err != nil {
 		status, response := services.ErrorResponse(services.NewAPIError(
 			http.StatusInternalServerError,
 			"INTERNAL_ERROR",
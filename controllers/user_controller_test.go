@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go-api-orm/auth"
+	"go-api-orm/config"
+	"go-api-orm/middleware"
+	"go-api-orm/models"
+	"go-api-orm/services"
+	"go-api-orm/tenancy"
+)
+
+func TestGetUserAppliesTheUserSerializer(t *testing.T) {
+	db := tenancy.Unscoped(newTestApp(t))
+
+	home, err := services.NewOrganizationService(db, config.Cache).Default()
+	if err != nil {
+		t.Fatalf("organización por defecto: %v", err)
+	}
+	var admin models.Role
+	if err := db.Where("name = ? AND tenant_id = 0", services.AdminRole).First(&admin).Error; err != nil {
+		t.Fatalf("rol admin: %v", err)
+	}
+	member, err := services.DefaultRole(db)
+	if err != nil {
+		t.Fatalf("rol por defecto: %v", err)
+	}
+
+	ana := models.User{Username: "ana", Email: "ana@example.com", Password: "Secreta123!", RoleID: admin.ID, TenantID: home.ID}
+	carl := models.User{Username: "carl", Email: "carl@example.com", Password: "Secreta123!", RoleID: member.ID, TenantID: home.ID}
+	for _, user := range []*models.User{&ana, &carl} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("crear usuario: %v", err)
+		}
+	}
+	anaToken, err := auth.IssueAccessToken(ana.ID, home.ID, admin.Name, admin.ID, 0)
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+	carlToken, err := auth.IssueAccessToken(carl.ID, home.ID, member.Name, member.ID, 0)
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+
+	router := gin.New()
+	router.Use(middleware.Tenant())
+	router.GET("/api/users/:id", middleware.AuthMiddleware(), middleware.RequirePermission("users:read"), GetUser)
+
+	// Otro usuario: el email se redacta y los campos de administración se omiten
+	status, body := getJSON(t, router, fmt.Sprintf("/api/users/%d", ana.ID), bearer(carlToken))
+	if status != http.StatusOK {
+		t.Fatalf("status = %d: %v", status, body)
+	}
+	if email, present := body["email"]; !present || email != nil {
+		t.Errorf("email = %v, se esperaba null", email)
+	}
+	for _, hidden := range []string{"created_at", "role_id", "tenant_id", "password"} {
+		if _, present := body[hidden]; present {
+			t.Errorf("carl ve %s en %v", hidden, body)
+		}
+	}
+	role, ok := body["role"].(map[string]interface{})
+	if !ok || role["name"] != services.AdminRole || role["description"] != nil {
+		t.Errorf("role = %v, se esperaba el id y el nombre", body["role"])
+	}
+
+	// El administrador ve todos los campos declarados
+	status, body = getJSON(t, router, fmt.Sprintf("/api/users/%d", carl.ID), bearer(anaToken))
+	if status != http.StatusOK {
+		t.Fatalf("status = %d: %v", status, body)
+	}
+	if body["email"] != carl.Email || body["created_at"] == nil || body["role_id"] == nil {
+		t.Errorf("el administrador ve %v", body)
+	}
+}
+
+func TestUpdateUserRoleKeepsAPIKeys(t *testing.T) {
+	db := tenancy.Unscoped(newTestApp(t))
+
+	home, err := services.NewOrganizationService(db, config.Cache).Default()
+	if err != nil {
+		t.Fatalf("organización por defecto: %v", err)
+	}
+	roles := map[string]models.Role{}
+	for _, name := range []string{services.AdminRole, "editor", "user"} {
+		var role models.Role
+		if err := db.Where("name = ? AND tenant_id = 0", name).First(&role).Error; err != nil {
+			t.Fatalf("rol %s: %v", name, err)
+		}
+		roles[name] = role
+	}
+	ana := models.User{Username: "ana", Email: "ana@example.com", Password: "Secreta123!", RoleID: roles[services.AdminRole].ID, TenantID: home.ID}
+	carl := models.User{Username: "carl", Email: "carl@example.com", Password: "Secreta123!", RoleID: roles["user"].ID, TenantID: home.ID}
+	for _, user := range []*models.User{&ana, &carl} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("crear usuario: %v", err)
+		}
+	}
+	anaToken, err := auth.IssueAccessToken(ana.ID, home.ID, services.AdminRole, roles[services.AdminRole].ID, 0)
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+	carlToken, err := auth.IssueAccessToken(carl.ID, home.ID, "user", roles["user"].ID, 0)
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+	carlKey, _, err := services.NewAPITokenService(db, config.Cache).Create(carl.ID, "ci", []string{services.APITokenScopeRead}, 0)
+	if err != nil {
+		t.Fatalf("crear clave: %v", err)
+	}
+
+	router := gin.New()
+	router.Use(middleware.Tenant())
+	router.GET("/api/principal", middleware.AuthMiddleware(), func(c *gin.Context) {
+		principal, _ := auth.CurrentUser(c)
+		c.JSON(http.StatusOK, gin.H{"role": principal.Role})
+	})
+	router.PUT("/api/users/:id/role", middleware.AuthMiddleware(), middleware.RequirePermission("users:assign_role"), UpdateUserRole)
+
+	path := fmt.Sprintf("/api/users/%d/role", carl.ID)
+	if status, body := requestJSON(t, router, http.MethodPut, path, map[string]uint{"role_id": roles["editor"].ID}, bearer(anaToken)); status != http.StatusOK {
+		t.Fatalf("PUT %s = %d: %v", path, status, body)
+	}
+
+	if status, body := getJSON(t, router, "/api/principal", bearer(carlToken)); status != http.StatusUnauthorized {
+		t.Errorf("token de acceso emitido antes del cambio de rol = %d: %v", status, body)
+	}
+	status, body := getJSON(t, router, "/api/principal", bearer(carlKey))
+	if status != http.StatusOK || body["role"] != "editor" {
+		t.Errorf("clave de API tras el cambio de rol = %d: %v; se esperaba el rol editor", status, body)
+	}
+}
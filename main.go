@@ -3,10 +3,13 @@ package main
 import (
 	"log"
 	"os"
+	"time"
 
 	"go-api-orm/config"
+	"go-api-orm/middleware"
 	"go-api-orm/routes"
 	"go-api-orm/services"
+	"go-api-orm/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
@@ -22,21 +25,58 @@ func main() {
 		log.Printf("Error loading .env file: %v", err)
 	}
 
+	// Cargar las claves de firma JWT
+	if err := utils.InitJWTKeys(); err != nil {
+		log.Fatalf("Error loading JWT keys: %v", err)
+	}
+
 	// Configurar el modo de Gin
 	if os.Getenv("GIN_MODE") == "release" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	// Inicializar la base de datos
+	// Inicializar la base de datos, la caché, el motor de políticas y el envío de correos
 	config.InitDB()
+	config.InitCache()
+	config.InitPolicies()
+	config.InitMailer()
+
+	// Limpiar tokens revocados que ya expiraron
+	if err := services.NewTokenRevocationService(config.DB, config.Cache).PurgeExpired(); err != nil {
+		log.Printf("Error purging revoked tokens: %v", err)
+	}
+
+	// Limpiar sesiones cerradas o expiradas
+	if err := services.NewSessionService(config.DB, config.Cache).PurgeExpired(); err != nil {
+		log.Printf("Error purging sessions: %v", err)
+	}
+
+	// Limpiar intentos de login fallidos fuera de la ventana
+	if err := services.NewLoginThrottleService(config.DB, config.Cache).PurgeStale(); err != nil {
+		log.Printf("Error purging login attempts: %v", err)
+	}
+
+	// Publicar los posts programados cada POST_PUBLISH_INTERVAL_SECONDS
+	postWorkflow := services.NewPostWorkflowService(config.DB)
+	if _, err := postWorkflow.PublishDue(time.Now()); err != nil {
+		log.Printf("Error publishing scheduled posts: %v", err)
+	}
+	postWorkflow.StartScheduler(time.Second * time.Duration(utils.GetEnvInt("POST_PUBLISH_INTERVAL_SECONDS", 60)))
 
 	// Inicializar el router
 	r := gin.Default()
 
+	// Limitar las consultas de cada petición a su organización
+	r.Use(middleware.Tenant())
+
 	// Configurar rutas
+	routes.SetupAuthRoutes(r)
 	routes.SetupUserRoutes(r)
 	routes.SetupPostRoutes(r)
+	routes.SetupTaxonomyRoutes(r)
 	routes.SetupRoleRoutes(r)
+	routes.SetupAdminRoutes(r)
+	routes.SetupOrganizationRoutes(r)
 
 	// Iniciar el servidor
 	port := os.Getenv("PORT")
@@ -1,11 +1,17 @@
 package middleware
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"go-api-orm/utils"
+	"go-api-orm/auth"
+	"go-api-orm/config"
+	"go-api-orm/models"
+	"go-api-orm/services"
+	"go-api-orm/tenancy"
 )
 
 // AuthMiddleware verifica el token JWT y autoriza el acceso
@@ -25,52 +31,205 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		claims, err := utils.ValidateToken(parts[1])
+		// Las claves de API se distinguen de los JWT por su prefijo
+		if services.IsAPIToken(parts[1]) {
+			authenticateAPIToken(c, parts[1])
+			return
+		}
+
+		claims, err := auth.ParseAccessToken(parts[1])
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			c.Abort()
 			return
 		}
 
-		// Almacenar los claims en el contexto
-		c.Set("user_id", claims["id"])
-		c.Set("user_role", claims["role"])
+		// Verificar que el token no haya sido revocado
+		revocationService := services.NewTokenRevocationService(config.DB, config.Cache)
+		revoked, err := revocationService.IsRevoked(claims.ID, claims.UserID(), claims.IssuedAtTime())
+		if err == nil && !revoked && claims.ActorID() != 0 {
+			// Una revocación global de los tokens del administrador también termina la suplantación
+			revoked, err = revocationService.IsRevoked("", claims.ActorID(), claims.IssuedAtTime())
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not verify token status"})
+			c.Abort()
+			return
+		}
+		if revoked {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+			c.Abort()
+			return
+		}
+
+		// Rechazar los tokens cuya sesión fue cerrada. Durante una suplantación la sesión
+		// pertenece al administrador.
+		if claims.SessionID != 0 {
+			sessionOwner := claims.UserID()
+			if claims.ActorID() != 0 {
+				sessionOwner = claims.ActorID()
+			}
+			if err := services.NewSessionService(config.DB, config.Cache).Validate(claims.SessionID, sessionOwner, c.ClientIP()); err != nil {
+				status, response := services.ErrorResponse(err)
+				c.JSON(status, response)
+				c.Abort()
+				return
+			}
+		}
+
+		principal := &auth.Principal{
+			UserID:    claims.UserID(),
+			Role:      claims.Role,
+			RoleID:    claims.RoleID,
+			TenantID:  claims.TenantID,
+			Method:    auth.MethodJWT,
+			SessionID: claims.SessionID,
+			TokenID:   claims.ID,
+			ExpiresAt: claims.ExpiresAtTime(),
+			ActorID:   claims.ActorID(),
+		}
+		auth.SetPrincipal(c, principal)
+		setTenant(c, principal.TenantID)
+
+		if principal.IsImpersonated() {
+			c.Header(ImpersonationHeader, strconv.FormatUint(uint64(principal.ActorID), 10))
+			markImpersonation(c, principal.ActorID)
+			auditImpersonatedRequest(c, principal)
+			return
+		}
 		c.Next()
 	}
 }
 
-// RoleMiddleware verifica si el usuario tiene el rol requerido
-func RoleMiddleware(roles ...string) gin.HandlerFunc {
+// OptionalAuth autentica la petición si envía la cabecera Authorization y la deja pasar
+// sin Principal si no la envía. Las rutas públicas lo usan para adaptar la respuesta al usuario.
+func OptionalAuth() gin.HandlerFunc {
+	authenticate := AuthMiddleware()
 	return func(c *gin.Context) {
-		userRole, exists := c.Get("user_role")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User role not found in context"})
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		authenticate(c)
+	}
+}
+
+// authenticateAPIToken autentica la petición con una clave de API y verifica su scope
+func authenticateAPIToken(c *gin.Context, token string) {
+	apiToken, err := services.NewAPITokenService(config.DB, config.Cache).Authenticate(token)
+	if err != nil {
+		status, response := services.ErrorResponse(err)
+		c.JSON(status, response)
+		c.Abort()
+		return
+	}
+
+	if scope := services.RequiredScope(c.Request.Method); !services.HasScope(apiToken, scope) {
+		status, response := services.ErrorResponse(services.ErrInsufficientScope(scope))
+		c.JSON(status, response)
+		c.Abort()
+		return
+	}
+
+	// El rol se obtiene del usuario en cada petición para reflejar cambios de rol o eliminaciones
+	var user models.User
+	if err := tenancy.Unscoped(config.DB).Preload("Role").First(&user, apiToken.UserID).Error; err != nil {
+		status, response := services.ErrorResponse(services.ErrInvalidAPIToken())
+		c.JSON(status, response)
+		c.Abort()
+		return
+	}
+
+	// Las claves de API actúan siempre en la organización propia del usuario
+	auth.SetPrincipal(c, &auth.Principal{
+		UserID:     user.ID,
+		Role:       user.Role.Name,
+		RoleID:     user.RoleID,
+		TenantID:   user.TenantID,
+		Method:     auth.MethodAPIToken,
+		Scopes:     apiToken.ScopeList(),
+		APITokenID: apiToken.ID,
+	})
+	setTenant(c, user.TenantID)
+	c.Next()
+}
+
+// RejectAPITokens impide el acceso con claves de API a rutas que requieren una sesión interactiva
+// (gestión de claves, segundo factor, etc.). Debe usarse después de AuthMiddleware.
+func RejectAPITokens() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if principal, ok := auth.CurrentUser(c); ok && principal.IsAPIToken() {
+			status, response := services.ErrorResponse(services.ErrForbidden("Esta operación no está disponible con una clave de API"))
+			c.JSON(status, response)
 			c.Abort()
 			return
 		}
 
-		// Verificar si el rol del usuario está en la lista de roles permitidos
-		roleStr, ok := userRole.(string)
+		c.Next()
+	}
+}
+
+// RequireRole verifica que el rol del usuario sea minimum o herede de él según la jerarquía
+// de roles (p. ej. RequireRole("editor") admite a editor y admin)
+func RequireRole(minimum string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := auth.CurrentUser(c)
 		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User role is not a string"})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User role not found in context"})
 			c.Abort()
 			return
 		}
 
-		allowed := false
-		for _, role := range roles {
-			if roleStr == role {
-				allowed = true
-				break
-			}
+		allowed, err := services.NewRoleService(config.DB.WithContext(c.Request.Context()), config.Cache).InheritsFrom(principal.RoleID, minimum)
+		if err != nil {
+			status, response := services.ErrorResponse(services.ErrInternal(err))
+			c.JSON(status, response)
+			c.Abort()
+			return
 		}
-
 		if !allowed {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			status, response := services.ErrorResponse(services.ErrForbidden(fmt.Sprintf("Se requiere el rol %s o superior", minimum)))
+			c.JSON(status, response)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequirePermission verifica que el rol del usuario tenga el permiso indicado (p. ej. "posts:delete")
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := auth.CurrentUser(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User role not found in context"})
 			c.Abort()
 			return
 		}
 
+		if !checkPermission(c, principal, permission) {
+			return
+		}
+
 		c.Next()
 	}
 }
+
+// checkPermission comprueba que el rol del usuario tenga el permiso; si no, responde y aborta la petición
+func checkPermission(c *gin.Context, principal *auth.Principal, permission string) bool {
+	allowed, err := services.NewPermissionService(config.DB.WithContext(c.Request.Context()), config.Cache).HasPermission(principal.RoleID, permission)
+	if err != nil {
+		status, response := services.ErrorResponse(services.ErrInternal(err))
+		c.JSON(status, response)
+		c.Abort()
+		return false
+	}
+	if !allowed {
+		status, response := services.ErrorResponse(services.ErrMissingPermission(permission))
+		c.JSON(status, response)
+		c.Abort()
+		return false
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go-api-orm/auth"
+	"go-api-orm/config"
+	"go-api-orm/models"
+	"go-api-orm/services"
+)
+
+// ImpersonationHeader marca las respuestas a peticiones hechas durante una suplantación
+// con el ID del administrador que la realiza
+const ImpersonationHeader = "X-Impersonated-By"
+
+// ImpersonationField es el campo que se añade al cuerpo de las respuestas JSON a peticiones
+// hechas durante una suplantación, con el mismo valor que ImpersonationHeader
+const ImpersonationField = "impersonated_by"
+
+// RejectImpersonation impide el acceso durante una suplantación a rutas que modifican
+// las credenciales o las sesiones del usuario. Debe usarse después de AuthMiddleware.
+func RejectImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if principal, ok := auth.CurrentUser(c); ok && principal.IsImpersonated() {
+			status, response := services.ErrorResponse(services.ErrForbidden("Esta operación no está disponible durante una suplantación"))
+			c.JSON(status, response)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// impersonationWriter retiene el cuerpo de la respuesta para poder marcarlo antes de enviarlo
+type impersonationWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *impersonationWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *impersonationWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// markImpersonation ejecuta la petición y añade ImpersonationField con el ID del
+// administrador al principio de la respuesta si es un objeto JSON
+func markImpersonation(c *gin.Context, actorID uint) {
+	writer := &impersonationWriter{ResponseWriter: c.Writer}
+	c.Writer = writer
+	c.Next()
+	c.Writer = writer.ResponseWriter
+
+	body := writer.body.Bytes()
+	if strings.HasPrefix(c.Writer.Header().Get("Content-Type"), "application/json") {
+		trimmed := bytes.TrimSpace(body)
+		if len(trimmed) > 1 && trimmed[0] == '{' {
+			rest := bytes.TrimLeft(trimmed[1:], " \t\r\n")
+			marked := fmt.Appendf(nil, `{"%s":%d`, ImpersonationField, actorID)
+			if rest[0] != '}' {
+				marked = append(marked, ',')
+			}
+			body = append(marked, rest...)
+		}
+	}
+
+	if len(body) > 0 {
+		if _, err := c.Writer.Write(body); err != nil {
+			log.Printf("Error writing impersonated response: %v", err)
+		}
+	}
+}
+
+// auditImpersonatedRequest registra en el historial de auditoría una petición hecha durante una suplantación
+func auditImpersonatedRequest(c *gin.Context, principal *auth.Principal) {
+	err := services.NewAuditService(config.DB).Record(&models.AuditLog{
+		ActorID:   principal.ActorID,
+		UserID:    principal.UserID,
+		Action:    services.AuditImpersonationRequest,
+		Method:    c.Request.Method,
+		Path:      c.Request.URL.Path,
+		Status:    c.Writer.Status(),
+		IPAddress: c.ClientIP(),
+	})
+	if err != nil {
+		log.Printf("Error recording impersonation audit log: %v", err)
+	}
+}
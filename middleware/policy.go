@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-api-orm/auth"
+	"go-api-orm/config"
+	"go-api-orm/policies"
+	"go-api-orm/services"
+)
+
+// RequirePolicy autoriza la acción sobre el tipo de recurso de la ruta, sin un recurso concreto,
+// con el mismo criterio que la política de propiedad: si una regla del motor coincide decide ella
+// (deny tiene prioridad sobre allow) y si ninguna coincide se exige el permiso action al rol.
+// Reemplaza a RequirePermission(action) en las rutas que admiten reglas.
+func RequirePolicy(action, resourceType string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := auth.CurrentUser(c)
+		if !ok {
+			status, response := services.ErrorResponse(services.ErrUnauthorized("Usuario no autenticado"))
+			c.JSON(status, response)
+			c.Abort()
+			return
+		}
+
+		decision := config.Policies.Evaluate(policies.Request{
+			Subject:      policies.SubjectAttributes(principal),
+			Action:       action,
+			ResourceType: resourceType,
+			Resource:     policies.Attributes{},
+			Environment:  policies.EnvironmentAttributes(c.ClientIP(), time.Now()),
+		})
+		if decision.Denied() {
+			status, response := services.ErrorResponse(services.ErrForbidden(decision.Reason))
+			c.JSON(status, response)
+			c.Abort()
+			return
+		}
+		if !decision.Applicable && !checkPermission(c, principal, action) {
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"go-api-orm/config"
+	"go-api-orm/models"
+	"go-api-orm/services"
+	"go-api-orm/tenancy"
+)
+
+// OrganizationHeader permite elegir la organización en las peticiones sin autenticar (por su slug)
+const OrganizationHeader = "X-Organization"
+
+// Tenant asigna a la petición la organización de la cabecera X-Organization o, si no se envía,
+// la organización por defecto. AuthMiddleware la sustituye por la organización del token.
+// Los controladores consultan con config.DB.WithContext(c.Request.Context()) para que el
+// filtro por organización se aplique.
+func Tenant() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		slug := c.GetHeader(OrganizationHeader)
+		if slug == "" {
+			slug = models.DefaultOrganizationSlug
+		}
+
+		organization, err := services.NewOrganizationService(config.DB, config.Cache).FindBySlug(slug)
+		if err != nil {
+			status, response := services.ErrorResponse(err)
+			c.JSON(status, response)
+			c.Abort()
+			return
+		}
+
+		setTenant(c, organization.ID)
+		c.Next()
+	}
+}
+
+// setTenant limita las consultas de la petición a la organización indicada
+func setTenant(c *gin.Context, tenantID uint) {
+	c.Request = c.Request.WithContext(tenancy.WithTenant(c.Request.Context(), tenantID))
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"go-api-orm/auth"
+	"go-api-orm/config"
+	"go-api-orm/models"
+	"go-api-orm/services"
+	"go-api-orm/tenancy"
+)
+
+// RequireVerifiedEmail rechaza las peticiones de usuarios sin email verificado
+// cuando EMAIL_VERIFICATION_REQUIRED=routes. Debe usarse después de AuthMiddleware.
+func RequireVerifiedEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if services.EmailVerificationMode() != services.EmailVerificationRoutes {
+			c.Next()
+			return
+		}
+
+		userID, _ := auth.CurrentUserID(c)
+		cacheKey := fmt.Sprintf("email_verified:%d", userID)
+		if _, found := config.Cache.Get(cacheKey); found {
+			c.Next()
+			return
+		}
+
+		var user models.User
+		if err := tenancy.Unscoped(config.DB).Select("id", "verified_at").First(&user, userID).Error; err != nil || !user.IsVerified() {
+			status, response := services.ErrorResponse(services.ErrEmailNotVerified())
+			c.JSON(status, response)
+			c.Abort()
+			return
+		}
+
+		// Solo se cachea el estado verificado, que no puede revertirse sin cambiar el email
+		config.Cache.Set(cacheKey, true)
+		c.Next()
+	}
+}
@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"go-api-orm/models"
+	"gorm.io/gorm"
+)
+
+// SeedDefaultOrganization crea la organización por defecto si no existe y le asigna los
+// usuarios, posts y roles creados por el usuario que todavía no tienen organización.
+// Los roles del sistema siguen siendo globales (tenant_id 0).
+func SeedDefaultOrganization(db *gorm.DB) error {
+	var organization models.Organization
+	err := db.Where("slug = ?", models.DefaultOrganizationSlug).First(&organization).Error
+	if err == gorm.ErrRecordNotFound {
+		organization = models.Organization{Name: "Default", Slug: models.DefaultOrganizationSlug}
+		if err := db.Create(&organization).Error; err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	// Los slugs de los posts y los nombres de los roles pasan a ser únicos dentro de cada organización
+	if db.Migrator().HasIndex(&models.Post{}, "idx_posts_slug") {
+		if err := db.Migrator().DropIndex(&models.Post{}, "idx_posts_slug"); err != nil {
+			return err
+		}
+	}
+	if db.Migrator().HasIndex(&models.Role{}, "idx_roles_name") {
+		if err := db.Migrator().DropIndex(&models.Role{}, "idx_roles_name"); err != nil {
+			return err
+		}
+	}
+
+	// UpdateColumn no ejecuta los hooks BeforeUpdate, que esperan un único registro
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.User{}).Where("tenant_id = 0").UpdateColumn("tenant_id", organization.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.Post{}).Where("tenant_id = 0").UpdateColumn("tenant_id", organization.ID).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&models.Role{}).Where("tenant_id = 0 AND is_system = ?", false).UpdateColumn("tenant_id", organization.ID).Error
+	})
+}
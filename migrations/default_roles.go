@@ -5,29 +5,141 @@ import (
 	"gorm.io/gorm"
 )
 
-// SeedDefaultRoles crea los roles por defecto si no existen
+// defaultPermission es un permiso del catálogo y los roles por defecto que lo reciben
+type defaultPermission struct {
+	Name        string
+	Description string
+	Roles       []string
+}
+
+// defaultPermissions es el catálogo de permisos que usan las rutas de la API
+var defaultPermissions = []defaultPermission{
+	{Name: "users:read", Description: "Ver usuarios", Roles: []string{"admin", "editor", "user"}},
+	{Name: "users:delete", Description: "Eliminar usuarios", Roles: []string{"admin"}},
+	{Name: "users:manage", Description: "Revocar tokens y desbloquear cuentas de otros usuarios", Roles: []string{"admin"}},
+	{Name: "users:assign_role", Description: "Cambiar el rol de los usuarios", Roles: []string{"admin"}},
+	{Name: "users:invite", Description: "Invitar usuarios con un rol", Roles: []string{"admin"}},
+	{Name: "users:impersonate", Description: "Actuar en nombre de otro usuario", Roles: []string{"admin"}},
+	{Name: "roles:read", Description: "Ver roles y permisos", Roles: []string{"admin", "editor", "user"}},
+	{Name: "roles:create", Description: "Crear roles", Roles: []string{"admin"}},
+	{Name: "roles:update", Description: "Modificar roles y asignarles permisos", Roles: []string{"admin"}},
+	{Name: "roles:delete", Description: "Eliminar roles", Roles: []string{"admin"}},
+	{Name: "organizations:create", Description: "Crear organizaciones", Roles: []string{"admin"}},
+	{Name: "organizations:manage", Description: "Gestionar los miembros de la organización", Roles: []string{"admin"}},
+	{Name: "policies:read", Description: "Ver las políticas y explicar sus decisiones", Roles: []string{"admin"}},
+	{Name: "posts:create", Description: "Crear posts", Roles: []string{"admin", "editor", "user"}},
+	{Name: "posts:update", Description: "Modificar posts propios", Roles: []string{"admin", "editor", "user"}},
+	{Name: "posts:delete", Description: "Eliminar posts propios", Roles: []string{"admin", "editor", "user"}},
+	{Name: "posts:update_any", Description: "Modificar posts de cualquier autor", Roles: []string{"admin", "editor"}},
+	{Name: "posts:delete_any", Description: "Eliminar posts de cualquier autor", Roles: []string{"admin", "editor"}},
+	{Name: "posts:publish", Description: "Revisar, programar y publicar posts y ver los no publicados", Roles: []string{"admin", "editor"}},
+	{Name: "posts:hard_delete", Description: "Eliminar posts definitivamente", Roles: []string{"admin"}},
+	{Name: "tags:manage", Description: "Crear, modificar y eliminar etiquetas", Roles: []string{"admin", "editor"}},
+	{Name: "categories:manage", Description: "Crear, modificar y eliminar categorías", Roles: []string{"admin", "editor"}},
+}
+
+// defaultRoleParents es la jerarquía por defecto: admin > editor > user
+var defaultRoleParents = map[string]string{
+	"admin":  "editor",
+	"editor": "user",
+}
+
+// SeedDefaultRoles crea los roles y permisos por defecto si no existen y los marca como roles del sistema.
+// Un permiso nuevo se asigna a sus roles por defecto solo al crearlo, de modo que
+// los cambios hechos después desde la API se conservan entre reinicios.
 func SeedDefaultRoles(db *gorm.DB) error {
 	defaultRoles := []models.Role{
 		{
 			Name:        "admin",
 			Description: "Administrador del sistema",
+			System:      true,
 		},
 		{
 			Name:        "user",
 			Description: "Usuario regular",
+			System:      true,
 		},
 		{
 			Name:        "editor",
 			Description: "Editor de contenido",
+			System:      true,
 		},
 	}
 
+	roles := make(map[string]*models.Role, len(defaultRoles))
 	for _, role := range defaultRoles {
 		var existingRole models.Role
-		if err := db.Where("name = ?", role.Name).First(&existingRole).Error; err == gorm.ErrRecordNotFound {
+		if err := db.Where("name = ? AND tenant_id = 0", role.Name).First(&existingRole).Error; err == gorm.ErrRecordNotFound {
 			if err := db.Create(&role).Error; err != nil {
 				return err
 			}
+			existingRole = role
+		} else if err != nil {
+			return err
+		} else if !existingRole.System {
+			if err := db.Model(&existingRole).Update("is_system", true).Error; err != nil {
+				return err
+			}
+		}
+		roles[existingRole.Name] = &existingRole
+	}
+
+	if err := seedDefaultHierarchy(db, roles); err != nil {
+		return err
+	}
+	return seedDefaultPermissions(db, roles)
+}
+
+// seedDefaultHierarchy asigna la jerarquía por defecto si ningún rol tiene padre
+// (base de datos nueva o anterior a la jerarquía de roles)
+func seedDefaultHierarchy(db *gorm.DB, roles map[string]*models.Role) error {
+	var count int64
+	if err := db.Model(&models.Role{}).Where("parent_id IS NOT NULL").Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	for name, parentName := range defaultRoleParents {
+		role, ok := roles[name]
+		parent, parentOK := roles[parentName]
+		if !ok || !parentOK {
+			continue
+		}
+		if err := db.Model(role).Update("parent_id", parent.ID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seedDefaultPermissions crea los permisos del catálogo que no existen y los asigna a sus roles por defecto
+func seedDefaultPermissions(db *gorm.DB, roles map[string]*models.Role) error {
+	for _, def := range defaultPermissions {
+		var permission models.Permission
+		err := db.Where("name = ?", def.Name).First(&permission).Error
+		if err == nil {
+			continue
+		}
+		if err != gorm.ErrRecordNotFound {
+			return err
+		}
+
+		permission = models.Permission{Name: def.Name, Description: def.Description}
+		if err := db.Create(&permission).Error; err != nil {
+			return err
+		}
+
+		for _, roleName := range def.Roles {
+			role, ok := roles[roleName]
+			if !ok {
+				continue
+			}
+			// Se usa un rol sin permisos cargados para insertar solo la nueva asociación
+			if err := db.Model(&models.Role{ID: role.ID}).Association("Permissions").Append(&permission); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// APIToken representa una clave de API personal para clientes no interactivos.
+// Solo se almacena el hash; Prefix permite identificar la clave sin exponerla.
+type APIToken struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	Name       string     `json:"name" gorm:"type:varchar(100);not null"`
+	Prefix     string     `json:"prefix" gorm:"type:varchar(16);not null"`
+	TokenHash  string     `json:"-" gorm:"type:varchar(64);uniqueIndex;not null"`
+	Scopes     string     `json:"scopes" gorm:"type:varchar(255);not null"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// IsExpired indica si la clave ya expiró
+func (t *APIToken) IsExpired() bool {
+	return !time.Now().Before(t.ExpiresAt)
+}
+
+// ScopeList retorna los scopes de la clave (separados por espacios en la base de datos)
+func (t *APIToken) ScopeList() []string {
+	return strings.Fields(t.Scopes)
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// AuditLog registra una acción realizada por un administrador en nombre de un usuario
+type AuditLog struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ActorID   uint      `json:"actor_id" gorm:"not null;index"` // administrador que realiza la acción
+	UserID    uint      `json:"user_id" gorm:"not null;index"`  // usuario afectado o suplantado
+	Action    string    `json:"action" gorm:"type:varchar(64);not null"`
+	Method    string    `json:"method,omitempty" gorm:"type:varchar(10)"`
+	Path      string    `json:"path,omitempty" gorm:"type:varchar(255)"`
+	Status    int       `json:"status,omitempty"`
+	IPAddress string    `json:"ip_address" gorm:"type:varchar(45)"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
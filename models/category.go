@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Category agrupa posts en una jerarquía; su slug es único dentro de la organización
+type Category struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	Name        string     `json:"name" gorm:"type:varchar(100);not null"`
+	Slug        string     `json:"slug" gorm:"type:varchar(150);uniqueIndex:idx_categories_tenant_slug,priority:2;not null"`
+	Description string     `json:"description" gorm:"type:varchar(255)"`
+	ParentID    *uint      `json:"parent_id" gorm:"index"` // categoría de la que es subcategoría
+	Parent      *Category  `json:"parent,omitempty" gorm:"foreignKey:ParentID"`
+	Children    []Category `json:"children,omitempty" gorm:"foreignKey:ParentID"`
+	TenantID    uint       `json:"tenant_id" gorm:"not null;default:0;uniqueIndex:idx_categories_tenant_slug,priority:1"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// BeforeCreate genera el slug a partir del nombre si no se indicó uno
+func (c *Category) BeforeCreate(tx *gorm.DB) error {
+	if c.Slug == "" {
+		slug, err := uniqueSlug(tx, &Category{}, c.Name, 0)
+		if err != nil {
+			return err
+		}
+		c.Slug = slug
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// Tipos de invitación
+const (
+	InvitationRegistration = "registration" // registrarse en la organización con un rol
+	InvitationMembership   = "membership"   // un usuario existente se une a la organización con un rol
+)
+
+// Invitation permite registrarse con un rol distinto del rol por defecto o, si es de tipo
+// membership, unirse a otra organización con la cuenta existente
+type Invitation struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	Kind        string     `json:"kind" gorm:"type:varchar(20);not null;default:registration"`
+	Email       string     `json:"email" gorm:"type:varchar(255);not null;index"`
+	TenantID    uint       `json:"tenant_id" gorm:"not null;default:0;index"` // organización a la que se invita
+	RoleID      uint       `json:"role_id" gorm:"not null"`
+	Role        Role       `json:"role" gorm:"foreignKey:RoleID"`
+	InvitedByID uint       `json:"invited_by_id" gorm:"not null"`
+	TokenHash   string     `json:"-" gorm:"type:varchar(64);uniqueIndex;not null"`
+	ExpiresAt   time.Time  `json:"expires_at" gorm:"not null"`
+	AcceptedAt  *time.Time `json:"accepted_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// LoginAttempt registra los intentos de login fallidos de una cuenta (email:...) o de una IP (ip:...)
+type LoginAttempt struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	Identifier   string     `json:"identifier" gorm:"type:varchar(255);uniqueIndex;not null"`
+	Failures     int        `json:"failures" gorm:"not null;default:0"`
+	LastFailedAt time.Time  `json:"last_failed_at"`
+	LockedUntil  *time.Time `json:"locked_until"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" gorm:"index"`
+}
+
+// IsLocked indica si el bloqueo temporal sigue vigente
+func (a *LoginAttempt) IsLocked(now time.Time) bool {
+	return a.LockedUntil != nil && now.Before(*a.LockedUntil)
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultOrganizationSlug es el slug de la organización que se crea al iniciar. Recibe a los
+// usuarios que se registran sin invitación y los datos anteriores a las organizaciones.
+const DefaultOrganizationSlug = "default"
+
+// Organization es una organización (tenant). Los usuarios, posts y roles llevan su ID en
+// TenantID y las consultas de cada petición se limitan a la organización del token.
+type Organization struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	Name      string         `json:"name" gorm:"type:varchar(100);not null"`
+	Slug      string         `json:"slug" gorm:"type:varchar(100);uniqueIndex;not null"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+}
+
+// Membership da acceso a un usuario a una organización distinta de la suya con el rol indicado
+type Membership struct {
+	ID             uint         `json:"id" gorm:"primaryKey"`
+	OrganizationID uint         `json:"organization_id" gorm:"not null;uniqueIndex:idx_membership"`
+	Organization   Organization `json:"organization" gorm:"foreignKey:OrganizationID"`
+	UserID         uint         `json:"user_id" gorm:"not null;uniqueIndex:idx_membership"`
+	User           User         `json:"user" gorm:"foreignKey:UserID"`
+	RoleID         uint         `json:"role_id" gorm:"not null"`
+	Role           Role         `json:"role" gorm:"foreignKey:RoleID"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+// Permission representa una acción autorizable con el formato recurso:acción (p. ej. posts:delete)
+type Permission struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"type:varchar(100);uniqueIndex;not null"`
+	Description string    `json:"description" gorm:"type:varchar(255)"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
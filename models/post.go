@@ -1,27 +1,58 @@
 package models
 
 import (
-	"strconv"
 	"strings"
 	"time"
 	"unicode"
 	"gorm.io/gorm"
 )
 
+// Estados del flujo editorial de los posts. Solo los posts publicados son públicos.
+const (
+	PostStatusDraft     = "draft"
+	PostStatusInReview  = "in_review"
+	PostStatusScheduled = "scheduled"
+	PostStatusPublished = "published"
+	PostStatusArchived  = "archived"
+)
+
 type Post struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	Title     string         `json:"title" gorm:"not null"`
-	Slug      string         `json:"slug" gorm:"type:varchar(255);uniqueIndex;not null"`
-	Content   string         `json:"content"`
-	AuthorID  uint           `json:"author_id" gorm:"not null"`
-	Author    User           `json:"author" gorm:"foreignKey:AuthorID"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	Title       string         `json:"title" gorm:"not null"`
+	Slug        string         `json:"slug" gorm:"type:varchar(255);uniqueIndex:idx_posts_tenant_slug,priority:2;not null"` // único dentro de la organización
+	Content     string         `json:"content"`
+	AuthorID    uint           `json:"author_id" gorm:"not null"`
+	TenantID    uint           `json:"tenant_id" gorm:"not null;default:0;uniqueIndex:idx_posts_tenant_slug,priority:1"`
+	Author      User           `json:"author" gorm:"foreignKey:AuthorID"`
+	Status      string         `json:"status" gorm:"type:varchar(20);not null;default:published;index"` // los posts anteriores al flujo editorial quedan publicados; los nuevos empiezan como borrador
+	PublishAt   *time.Time     `json:"publish_at" gorm:"index"`                                         // publicación programada (estado scheduled)
+	PublishedAt *time.Time     `json:"published_at"`
+	Tags        []Tag          `json:"tags,omitempty" gorm:"many2many:post_tags"`
+	Categories  []Category     `json:"categories,omitempty" gorm:"many2many:post_categories"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+}
+
+// OwnerID retorna el autor del post, que es su propietario a efectos de autorización
+func (p *Post) OwnerID() uint {
+	return p.AuthorID
+}
+
+// PolicyAttributes retorna los atributos del post que evalúa el motor de políticas
+func (p *Post) PolicyAttributes() map[string]interface{} {
+	return map[string]interface{}{
+		"id":        p.ID,
+		"slug":      p.Slug,
+		"author_id": p.AuthorID,
+		"owner_id":  p.AuthorID,
+		"tenant_id": p.TenantID,
+		"status":    p.Status,
+	}
 }
 
-// generateSlug generates a URL-friendly slug from a title
-func generateSlug(title string) string {
+// GenerateSlug generates a URL-friendly slug from a title (posts, tags and categories)
+func GenerateSlug(title string) string {
 	// Convert to lowercase
 	slug := strings.ToLower(title)
 	
@@ -65,20 +96,13 @@ func generateSlug(title string) string {
 
 // BeforeCreate is a GORM hook that runs before creating a record
 func (p *Post) BeforeCreate(tx *gorm.DB) error {
+	if p.Status == "" {
+		p.Status = PostStatusDraft
+	}
 	if p.Slug == "" {
-		baseSlug := generateSlug(p.Title)
-		slug := baseSlug
-		counter := 1
-
-		// Check if slug exists and generate a unique one
-		for {
-			var count int64
-			tx.Model(&Post{}).Where("slug = ?", slug).Count(&count)
-			if count == 0 {
-				break
-			}
-			slug = baseSlug + "-" + strconv.Itoa(counter)
-			counter++
+		slug, err := uniqueSlug(tx, &Post{}, p.Title, 0)
+		if err != nil {
+			return err
 		}
 		p.Slug = slug
 	}
@@ -94,19 +118,9 @@ func (p *Post) BeforeUpdate(tx *gorm.DB) error {
 	}
 
 	if oldPost.Title != p.Title && p.Slug == oldPost.Slug {
-		baseSlug := generateSlug(p.Title)
-		slug := baseSlug
-		counter := 1
-
-		// Check if slug exists and generate a unique one
-		for {
-			var count int64
-			tx.Model(&Post{}).Where("slug = ? AND id != ?", slug, p.ID).Count(&count)
-			if count == 0 {
-				break
-			}
-			slug = baseSlug + "-" + strconv.Itoa(counter)
-			counter++
+		slug, err := uniqueSlug(tx, &Post{}, p.Title, p.ID)
+		if err != nil {
+			return err
 		}
 		p.Slug = slug
 	}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PostRevision es una copia inmutable del título, el slug y el contenido de un post tras cada cambio
+type PostRevision struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	PostID    uint      `json:"post_id" gorm:"not null;uniqueIndex:idx_post_revisions_number,priority:1"`
+	Number    uint      `json:"number" gorm:"not null;uniqueIndex:idx_post_revisions_number,priority:2"` // correlativo dentro del post
+	TenantID  uint      `json:"tenant_id" gorm:"not null;default:0;index"`
+	AuthorID  uint      `json:"author_id" gorm:"not null"` // usuario que hizo el cambio
+	Author    User      `json:"author" gorm:"foreignKey:AuthorID"`
+	Title     string    `json:"title" gorm:"not null"`
+	Slug      string    `json:"slug" gorm:"type:varchar(255);not null"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BeforeUpdate impide modificar una revisión ya guardada; solo se pueden eliminar las antiguas
+func (r *PostRevision) BeforeUpdate(tx *gorm.DB) error {
+	return errors.New("post revisions are immutable")
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// RefreshToken representa un token de refresco opaco almacenado como hash.
+// Todos los tokens obtenidos por rotación a partir del mismo login comparten FamilyID.
+type RefreshToken struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	UserID       uint       `json:"user_id" gorm:"not null;index"`
+	TokenHash    string     `json:"-" gorm:"type:varchar(64);uniqueIndex;not null"`
+	FamilyID     string     `json:"family_id" gorm:"type:varchar(64);index;not null"`
+	SessionID    *uint      `json:"session_id,omitempty" gorm:"index"`
+	ReplacedByID *uint      `json:"replaced_by_id,omitempty"`
+	ExpiresAt    time.Time  `json:"expires_at" gorm:"not null"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// IsActive indica si el token no ha sido revocado ni ha expirado
+func (t *RefreshToken) IsActive() bool {
+	return t.RevokedAt == nil && time.Now().Before(t.ExpiresAt)
+}
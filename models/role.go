@@ -7,13 +7,22 @@ import (
 
 type Role struct {
 	ID          uint      `json:"id" gorm:"primaryKey"`
-	Name        string    `json:"name" gorm:"type:varchar(50);uniqueIndex;not null"`
+	Name        string    `json:"name" gorm:"type:varchar(50);uniqueIndex:idx_roles_tenant_name,priority:2;not null"` // único dentro de la organización
 	Description string    `json:"description" gorm:"type:varchar(255)"`
+	ParentID    *uint     `json:"parent_id" gorm:"index"` // rol del que hereda los permisos
+	TenantID    uint      `json:"tenant_id" gorm:"not null;default:0;uniqueIndex:idx_roles_tenant_name,priority:1"` // 0: rol global, visible en todas las organizaciones
+	System      bool      `json:"system" gorm:"column:is_system;not null;default:false"` // rol creado al iniciar: no se puede eliminar ni renombrar
+	Permissions []Permission `json:"permissions,omitempty" gorm:"many2many:role_permissions;"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
 }
 
+// TenantShared indica que los roles con tenant_id 0 son visibles en todas las organizaciones
+func (r *Role) TenantShared() bool {
+	return true
+}
+
 // BeforeCreate es un hook de GORM que se ejecuta antes de crear un registro
 func (r *Role) BeforeCreate(tx *gorm.DB) error {
 	r.CreatedAt = time.Now()
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// Session representa un inicio de sesión de un usuario en un dispositivo.
+// Los tokens de acceso llevan su ID en el claim sid y los de refresco en SessionID.
+type Session struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	UserID         uint       `json:"user_id" gorm:"not null;index"`
+	OrganizationID uint       `json:"organization_id"` // organización activa; 0 es la organización del usuario
+	UserAgent      string     `json:"user_agent" gorm:"type:varchar(255)"`
+	IPAddress      string     `json:"ip_address" gorm:"type:varchar(45)"`
+	LastSeenAt     time.Time  `json:"last_seen_at"`
+	ExpiresAt      time.Time  `json:"expires_at" gorm:"not null;index"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// IsActive indica si la sesión no fue cerrada ni ha expirado
+func (s *Session) IsActive() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
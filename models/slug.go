@@ -0,0 +1,28 @@
+package models
+
+import (
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// uniqueSlug genera el slug de name y le agrega un sufijo numérico hasta que no lo use
+// otro registro de model distinto de excludeID (0 al crear). Lo usan los posts, las
+// etiquetas y las categorías.
+func uniqueSlug(tx *gorm.DB, model interface{}, name string, excludeID uint) (string, error) {
+	baseSlug := GenerateSlug(name)
+	slug := baseSlug
+	counter := 1
+
+	for {
+		var count int64
+		if err := tx.Model(model).Where("slug = ? AND id != ?", slug, excludeID).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return slug, nil
+		}
+		slug = baseSlug + "-" + strconv.Itoa(counter)
+		counter++
+	}
+}
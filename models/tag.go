@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Tag es una etiqueta libre que agrupa posts; su slug es único dentro de la organización
+type Tag struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"type:varchar(50);not null"`
+	Slug      string    `json:"slug" gorm:"type:varchar(100);uniqueIndex:idx_tags_tenant_slug,priority:2;not null"`
+	TenantID  uint      `json:"tenant_id" gorm:"not null;default:0;uniqueIndex:idx_tags_tenant_slug,priority:1"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// BeforeCreate genera el slug a partir del nombre si no se indicó uno
+func (t *Tag) BeforeCreate(tx *gorm.DB) error {
+	if t.Slug == "" {
+		slug, err := uniqueSlug(tx, &Tag{}, t.Name, 0)
+		if err != nil {
+			return err
+		}
+		t.Slug = slug
+	}
+	return nil
+}
@@ -2,36 +2,65 @@ package models
 
 import (
 	"time"
-	"golang.org/x/crypto/bcrypt"
+
+	"go-api-orm/utils"
 	"gorm.io/gorm"
 )
 
 type User struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	Username  string         `json:"username" gorm:"uniqueIndex:idx_username,length:255;not null;size:255"`
-	Email     string         `json:"email" gorm:"uniqueIndex:idx_email,length:255;not null;size:255"`
-	Password  string         `json:"-" gorm:"not null"`
-	RoleID    uint           `json:"role_id" gorm:"not null"`
-	Role      Role           `json:"role" gorm:"foreignKey:RoleID"`
-	Posts     []Post         `json:"posts,omitempty" gorm:"foreignKey:AuthorID"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	ID              uint           `json:"id" gorm:"primaryKey"`
+	Username        string         `json:"username" gorm:"uniqueIndex:idx_username,length:255;not null;size:255"`
+	Email           string         `json:"email" gorm:"uniqueIndex:idx_email,length:255;not null;size:255"`
+	Password        string         `json:"-" gorm:"not null"`
+	VerifiedAt      *time.Time     `json:"verified_at"`
+	TenantID        uint           `json:"tenant_id" gorm:"not null;default:0;index"` // organización propia del usuario
+	RoleID          uint           `json:"role_id" gorm:"not null"`
+	Role            Role           `json:"role" gorm:"foreignKey:RoleID"`
+	Posts           []Post         `json:"posts,omitempty" gorm:"foreignKey:AuthorID"`
+	TokensRevokedAt *time.Time     `json:"-"` // invalida los tokens de acceso emitidos hasta ese instante
+	TOTPSecret      string         `json:"-" gorm:"column:totp_secret;size:64"`
+	TOTPLastStep    int64          `json:"-" gorm:"column:totp_last_step"` // último intervalo usado, evita reutilizar un código
+	MFAEnabledAt    *time.Time     `json:"mfa_enabled_at,omitempty" gorm:"column:mfa_enabled_at"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
 }
 
 // BeforeCreate es un hook que se ejecuta antes de crear un usuario
 func (u *User) BeforeCreate(tx *gorm.DB) error {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
+	return u.SetPassword(u.Password)
+}
+
+// SetPassword hashea la contraseña proporcionada con el algoritmo configurado y la asigna al usuario
+func (u *User) SetPassword(password string) error {
+	hashedPassword, err := utils.HashPassword(password)
 	if err != nil {
 		return err
 	}
-	u.Password = string(hashedPassword)
+	u.Password = hashedPassword
 	return nil
 }
 
 // CheckPassword verifica si la contraseña proporcionada coincide con la almacenada
 func (u *User) CheckPassword(password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
+	_, err := u.VerifyPassword(password)
+	return err
+}
+
+// VerifyPassword verifica la contraseña e indica si el hash almacenado usa un algoritmo
+// o parámetros anteriores y debe regenerarse
+func (u *User) VerifyPassword(password string) (bool, error) {
+	return utils.VerifyPassword(password, u.Password)
+}
+
+// IsVerified indica si el usuario confirmó su dirección de email
+func (u *User) IsVerified() bool {
+	return u.VerifiedAt != nil
+}
+
+// MFAEnabled indica si el usuario completó la inscripción en la autenticación en dos pasos
+func (u *User) MFAEnabled() bool {
+	return u.MFAEnabledAt != nil
 }
 
 // TableName especifica el nombre de la tabla para GORM
@@ -0,0 +1,52 @@
+package policies
+
+import (
+	"time"
+
+	"go-api-orm/auth"
+	"go-api-orm/models"
+)
+
+// SubjectAttributes retorna los atributos del usuario autenticado
+func SubjectAttributes(principal *auth.Principal) Attributes {
+	return Attributes{
+		"id":           principal.UserID,
+		"role":         principal.Role,
+		"auth_method":  principal.Method,
+		"impersonated": principal.IsImpersonated(),
+		"actor_id":     principal.ActorID,
+		"tenant_id":    principal.TenantID,
+	}
+}
+
+// UserAttributes retorna los atributos de un usuario como sujeto (p. ej. para explicar una decisión)
+func UserAttributes(user *models.User) Attributes {
+	return Attributes{
+		"id":           user.ID,
+		"role":         user.Role.Name,
+		"auth_method":  auth.MethodJWT,
+		"impersonated": false,
+		"actor_id":     uint(0),
+		"tenant_id":    user.TenantID,
+	}
+}
+
+// ResourceAttributes retorna los atributos de un recurso que los expone
+func ResourceAttributes(resource interface{}) Attributes {
+	if provider, ok := resource.(interface{ PolicyAttributes() map[string]interface{} }); ok {
+		return Attributes(provider.PolicyAttributes())
+	}
+	if owned, ok := resource.(Owned); ok {
+		return Attributes{"owner_id": owned.OwnerID()}
+	}
+	return Attributes{}
+}
+
+// EnvironmentAttributes retorna los atributos del entorno de la petición
+func EnvironmentAttributes(ip string, now time.Time) Attributes {
+	return Attributes{
+		"ip":      ip,
+		"hour":    now.Hour(),
+		"weekday": now.Weekday().String(),
+	}
+}
@@ -0,0 +1,365 @@
+package policies
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Efectos de una regla
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+// Attributes son los atributos del sujeto, del recurso o del entorno de una petición
+type Attributes map[string]interface{}
+
+// Request es la petición de autorización que evalúa el motor
+type Request struct {
+	Subject      Attributes `json:"subject"`
+	Action       string     `json:"action"`
+	ResourceType string     `json:"resource_type"`
+	Resource     Attributes `json:"resource"`
+	Environment  Attributes `json:"environment,omitempty"`
+}
+
+// Condition compara un atributo con un valor literal (value) o con otro atributo (ref).
+// Los atributos se indican con su ámbito: subject.role, resource.author_id, environment.ip...
+type Condition struct {
+	Attribute string      `json:"attribute"`
+	Operator  string      `json:"operator"`
+	Value     interface{} `json:"value,omitempty"`
+	Ref       string      `json:"ref,omitempty"`
+}
+
+// Rule es una regla de la política. Una regla aplica a una petición cuando coinciden la acción,
+// el tipo de recurso y todas sus condiciones. Las listas vacías coinciden con cualquier valor.
+type Rule struct {
+	ID          string      `json:"id"`
+	Description string      `json:"description,omitempty"`
+	Effect      string      `json:"effect"`
+	Actions     []string    `json:"actions,omitempty"`   // "posts:publish", "posts:*" o "*"
+	Resources   []string    `json:"resources,omitempty"` // tipos de recurso: "post" o "*"
+	Conditions  []Condition `json:"conditions,omitempty"`
+}
+
+// Policy es el contenido del fichero de políticas
+type Policy struct {
+	Version int    `json:"version"`
+	Rules   []Rule `json:"rules"`
+}
+
+// ConditionTrace es el resultado de evaluar una condición
+type ConditionTrace struct {
+	Condition
+	Actual   interface{} `json:"actual"`
+	Expected interface{} `json:"expected"`
+	Matched  bool        `json:"matched"`
+}
+
+// RuleTrace es el resultado de evaluar una regla
+type RuleTrace struct {
+	ID         string           `json:"id"`
+	Effect     string           `json:"effect"`
+	Applicable bool             `json:"applicable"` // coinciden la acción y el tipo de recurso
+	Matched    bool             `json:"matched"`    // además se cumplen todas las condiciones
+	Reason     string           `json:"reason"`
+	Conditions []ConditionTrace `json:"conditions,omitempty"`
+}
+
+// Decision es el resultado de evaluar una petición. Las reglas deny tienen prioridad sobre
+// las allow; si ninguna regla coincide la decisión no es aplicable y el llamador debe
+// recurrir a la autorización por roles y permisos.
+type Decision struct {
+	Applicable bool        `json:"applicable"`
+	Allowed    bool        `json:"allowed"`
+	RuleID     string      `json:"rule_id,omitempty"`
+	Reason     string      `json:"reason"`
+	Trace      []RuleTrace `json:"trace"`
+}
+
+// Denied indica si alguna regla deniega la petición
+func (d *Decision) Denied() bool {
+	return d.Applicable && !d.Allowed
+}
+
+// Engine evalúa peticiones contra la política cargada desde un fichero JSON.
+// La política se recarga automáticamente cuando el fichero cambia.
+type Engine struct {
+	mu       sync.RWMutex
+	path     string
+	policy   Policy
+	modTime  time.Time
+	size     int64
+	loadedAt time.Time
+}
+
+// NewEngine crea un motor con la política del fichero indicado.
+// Con una ruta vacía el motor no tiene reglas y ninguna decisión es aplicable.
+func NewEngine(path string) (*Engine, error) {
+	engine := &Engine{path: path}
+	if path == "" {
+		return engine, nil
+	}
+	if err := engine.Reload(); err != nil {
+		return nil, err
+	}
+	return engine, nil
+}
+
+// Path retorna la ruta del fichero de políticas
+func (e *Engine) Path() string {
+	return e.path
+}
+
+// Policy retorna la política cargada y el instante en que se cargó
+func (e *Engine) Policy() (Policy, time.Time) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.policy, e.loadedAt
+}
+
+// Reload vuelve a leer el fichero de políticas. Si el fichero no es válido se conserva la política anterior.
+func (e *Engine) Reload() error {
+	info, err := os.Stat(e.path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(e.path)
+	if err != nil {
+		return err
+	}
+
+	var policy Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return fmt.Errorf("fichero de políticas inválido: %w", err)
+	}
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	e.policy = policy
+	e.modTime = info.ModTime()
+	e.size = info.Size()
+	e.loadedAt = time.Now()
+	e.mu.Unlock()
+	return nil
+}
+
+// Watch comprueba el fichero cada interval y recarga la política cuando cambia
+func (e *Engine) Watch(interval time.Duration) {
+	if e.path == "" || interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if !e.changed() {
+				continue
+			}
+			if err := e.Reload(); err != nil {
+				log.Printf("Error reloading policy file %s: %v", e.path, err)
+				// Evitar repetir el error hasta el siguiente cambio del fichero
+				e.markSeen()
+				continue
+			}
+			log.Printf("Policy file %s reloaded", e.path)
+		}
+	}()
+}
+
+func (e *Engine) changed() bool {
+	info, err := os.Stat(e.path)
+	if err != nil {
+		return false
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return !info.ModTime().Equal(e.modTime) || info.Size() != e.size
+}
+
+func (e *Engine) markSeen() {
+	info, err := os.Stat(e.path)
+	if err != nil {
+		return
+	}
+	e.mu.Lock()
+	e.modTime = info.ModTime()
+	e.size = info.Size()
+	e.mu.Unlock()
+}
+
+// Evaluate evalúa la petición contra todas las reglas y retorna la decisión con su traza
+func (e *Engine) Evaluate(req Request) Decision {
+	if e == nil {
+		return Decision{Reason: "No hay políticas cargadas", Trace: []RuleTrace{}}
+	}
+
+	e.mu.RLock()
+	rules := e.policy.Rules
+	e.mu.RUnlock()
+
+	decision := Decision{Trace: make([]RuleTrace, 0, len(rules))}
+	var allowRule, denyRule string
+
+	for _, rule := range rules {
+		trace := rule.evaluate(req)
+		decision.Trace = append(decision.Trace, trace)
+		if !trace.Matched {
+			continue
+		}
+		if rule.Effect == EffectDeny && denyRule == "" {
+			denyRule = rule.ID
+		}
+		if rule.Effect == EffectAllow && allowRule == "" {
+			allowRule = rule.ID
+		}
+	}
+
+	switch {
+	case denyRule != "":
+		decision.Applicable = true
+		decision.RuleID = denyRule
+		decision.Reason = fmt.Sprintf("Denegado por la regla %s", denyRule)
+	case allowRule != "":
+		decision.Applicable = true
+		decision.Allowed = true
+		decision.RuleID = allowRule
+		decision.Reason = fmt.Sprintf("Permitido por la regla %s", allowRule)
+	default:
+		decision.Reason = "Ninguna regla coincide; se aplican los roles y permisos"
+	}
+
+	return decision
+}
+
+// Validate comprueba que la política esté bien formada
+func (p *Policy) Validate() error {
+	seen := make(map[string]bool, len(p.Rules))
+	for i, rule := range p.Rules {
+		if rule.ID == "" {
+			return fmt.Errorf("la regla %d no tiene id", i)
+		}
+		if seen[rule.ID] {
+			return fmt.Errorf("el id de regla %s está repetido", rule.ID)
+		}
+		seen[rule.ID] = true
+
+		if rule.Effect != EffectAllow && rule.Effect != EffectDeny {
+			return fmt.Errorf("la regla %s tiene un efecto inválido: %q", rule.ID, rule.Effect)
+		}
+		for _, condition := range rule.Conditions {
+			if !validAttribute(condition.Attribute) {
+				return fmt.Errorf("la regla %s usa un atributo inválido: %q", rule.ID, condition.Attribute)
+			}
+			if condition.Ref != "" && !validAttribute(condition.Ref) {
+				return fmt.Errorf("la regla %s usa una referencia inválida: %q", rule.ID, condition.Ref)
+			}
+			if _, ok := operators[condition.Operator]; !ok {
+				return fmt.Errorf("la regla %s usa un operador inválido: %q", rule.ID, condition.Operator)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Rule) evaluate(req Request) RuleTrace {
+	trace := RuleTrace{ID: r.ID, Effect: r.Effect}
+
+	if !matchesPattern(r.Actions, req.Action) {
+		trace.Reason = "La acción no coincide"
+		return trace
+	}
+	if !matchesPattern(r.Resources, req.ResourceType) {
+		trace.Reason = "El tipo de recurso no coincide"
+		return trace
+	}
+	trace.Applicable = true
+
+	trace.Matched = true
+	for _, condition := range r.Conditions {
+		result := condition.evaluate(req)
+		trace.Conditions = append(trace.Conditions, result)
+		if !result.Matched {
+			trace.Matched = false
+		}
+	}
+
+	if trace.Matched {
+		trace.Reason = "Se cumplen todas las condiciones"
+	} else {
+		trace.Reason = "No se cumplen todas las condiciones"
+	}
+	return trace
+}
+
+func (c Condition) evaluate(req Request) ConditionTrace {
+	actual, _ := req.lookup(c.Attribute)
+	expected := c.Value
+	if c.Ref != "" {
+		expected, _ = req.lookup(c.Ref)
+	}
+
+	result := ConditionTrace{Condition: c, Actual: actual, Expected: expected}
+	if c.Operator == "exists" {
+		_, found := req.lookup(c.Attribute)
+		result.Matched = found && actual != nil
+		return result
+	}
+
+	result.Matched = operators[c.Operator](actual, expected)
+	return result
+}
+
+// lookup obtiene el valor de un atributo con ámbito (subject.x, resource.x, environment.x)
+func (r Request) lookup(path string) (interface{}, bool) {
+	scope, name, ok := strings.Cut(path, ".")
+	if !ok {
+		return nil, false
+	}
+
+	var attributes Attributes
+	switch scope {
+	case "subject":
+		attributes = r.Subject
+	case "resource":
+		attributes = r.Resource
+	case "environment":
+		attributes = r.Environment
+	}
+
+	value, found := attributes[name]
+	return value, found
+}
+
+func validAttribute(path string) bool {
+	scope, name, ok := strings.Cut(path, ".")
+	if !ok || name == "" {
+		return false
+	}
+	return scope == "subject" || scope == "resource" || scope == "environment"
+}
+
+// matchesPattern indica si el valor coincide con alguno de los patrones ("*", "posts:*" o exacto)
+func matchesPattern(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if pattern == "*" || pattern == value {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
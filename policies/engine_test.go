@@ -0,0 +1,294 @@
+package policies
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-api-orm/auth"
+	"go-api-orm/migrations"
+	"go-api-orm/models"
+	"go-api-orm/services"
+	"go-api-orm/tenancy"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// writePolicy escribe el fichero de políticas y retorna su ruta
+func writePolicy(t *testing.T, path, content string) string {
+	t.Helper()
+
+	if path == "" {
+		path = filepath.Join(t.TempDir(), "policies.json")
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("escribir políticas: %v", err)
+	}
+	return path
+}
+
+func newTestEngine(t *testing.T, content string) *Engine {
+	t.Helper()
+
+	engine, err := NewEngine(writePolicy(t, "", content))
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	return engine
+}
+
+func TestOperators(t *testing.T) {
+	for _, tc := range []struct {
+		operator string
+		actual   interface{}
+		expected interface{}
+		matched  bool
+	}{
+		{"eq", uint(3), float64(3), true}, // los números del JSON son float64
+		{"eq", "editor", "editor", true},
+		{"eq", "editor", "admin", false},
+		{"eq", nil, nil, true},
+		{"eq", nil, "admin", false},
+		{"ne", uint(3), float64(4), true},
+		{"ne", "editor", "editor", false},
+		{"in", "editor", []interface{}{"admin", "editor"}, true},
+		{"in", uint(2), []interface{}{float64(1), float64(2)}, true},
+		{"in", "user", []interface{}{"admin", "editor"}, false},
+		{"in", "user", "user", false}, // el valor esperado debe ser una lista
+		{"not_in", "user", []interface{}{"admin", "editor"}, true},
+		{"not_in", "admin", []interface{}{"admin", "editor"}, false},
+		{"contains", []interface{}{"go", "api"}, "api", true},
+		{"contains", []string{"go"}, "api", false},
+		{"gt", 23, float64(22), true},
+		{"gt", 22, float64(22), false},
+		{"gte", 22, float64(22), true},
+		{"lt", uint(1), float64(2), true},
+		{"lte", float64(2), 2, true},
+		{"lte", "3", float64(4), false}, // las comparaciones solo admiten números
+	} {
+		if matched := operators[tc.operator](tc.actual, tc.expected); matched != tc.matched {
+			t.Errorf("%v %s %v = %v, se esperaba %v", tc.actual, tc.operator, tc.expected, matched, tc.matched)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	engine := newTestEngine(t, `{
+  "version": 1,
+  "rules": [
+    {
+      "id": "editors-edit-posts",
+      "effect": "allow",
+      "actions": ["posts:update", "posts:delete"],
+      "resources": ["post"],
+      "conditions": [{"attribute": "subject.role", "operator": "eq", "value": "editor"}]
+    },
+    {
+      "id": "no-foreign-deletes",
+      "effect": "deny",
+      "actions": ["posts:delete"],
+      "resources": ["post"],
+      "conditions": [{"attribute": "resource.author_id", "operator": "ne", "ref": "subject.id"}]
+    },
+    {
+      "id": "no-night-writes",
+      "effect": "deny",
+      "actions": ["posts:*"],
+      "conditions": [
+        {"attribute": "environment.hour", "operator": "gte", "value": 22},
+        {"attribute": "subject.impersonated", "operator": "exists"}
+      ]
+    }
+  ]
+}`)
+
+	request := func(role, action string, authorID uint, hour int) Request {
+		return Request{
+			Subject:      Attributes{"id": uint(7), "role": role, "impersonated": false},
+			Action:       action,
+			ResourceType: "post",
+			Resource:     Attributes{"author_id": authorID},
+			Environment:  Attributes{"hour": hour},
+		}
+	}
+
+	for _, tc := range []struct {
+		name       string
+		request    Request
+		applicable bool
+		allowed    bool
+		ruleID     string
+	}{
+		{"una regla allow coincide", request("editor", "posts:update", 1, 10), true, true, "editors-edit-posts"},
+		{"deny tiene prioridad sobre allow", request("editor", "posts:delete", 1, 10), true, false, "no-foreign-deletes"},
+		{"la referencia a otro atributo coincide", request("editor", "posts:delete", 7, 10), true, true, "editors-edit-posts"},
+		{"las acciones admiten prefijos", request("user", "posts:create", 7, 23), true, false, "no-night-writes"},
+		{"ninguna regla coincide", request("user", "posts:update", 7, 10), false, false, ""},
+		{"otro tipo de recurso", Request{Subject: Attributes{"role": "editor"}, Action: "posts:update", ResourceType: "user"}, false, false, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			decision := engine.Evaluate(tc.request)
+			if decision.Applicable != tc.applicable || decision.Allowed != tc.allowed || decision.RuleID != tc.ruleID {
+				t.Errorf("decisión = %+v, se esperaba aplicable=%v permitido=%v regla=%q", decision, tc.applicable, tc.allowed, tc.ruleID)
+			}
+			if len(decision.Trace) != 3 {
+				t.Errorf("la traza tiene %d reglas, se esperaban 3", len(decision.Trace))
+			}
+		})
+	}
+
+	var none *Engine
+	if decision := none.Evaluate(request("editor", "posts:update", 1, 10)); decision.Applicable {
+		t.Errorf("un motor sin política decidió %+v", decision)
+	}
+}
+
+func TestValidateRejectsInvalidPolicies(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		content string
+		message string
+	}{
+		{"JSON inválido", `{"rules": [`, "inválido"},
+		{"regla sin id", `{"rules": [{"effect": "allow"}]}`, "no tiene id"},
+		{"id repetido", `{"rules": [{"id": "a", "effect": "allow"}, {"id": "a", "effect": "deny"}]}`, "repetido"},
+		{"efecto desconocido", `{"rules": [{"id": "a", "effect": "maybe"}]}`, "efecto inválido"},
+		{"atributo sin ámbito", `{"rules": [{"id": "a", "effect": "deny", "conditions": [{"attribute": "role", "operator": "eq", "value": "x"}]}]}`, "atributo inválido"},
+		{"ámbito desconocido", `{"rules": [{"id": "a", "effect": "deny", "conditions": [{"attribute": "request.ip", "operator": "eq", "value": "x"}]}]}`, "atributo inválido"},
+		{"referencia inválida", `{"rules": [{"id": "a", "effect": "deny", "conditions": [{"attribute": "subject.id", "operator": "eq", "ref": "id"}]}]}`, "referencia inválida"},
+		{"operador desconocido", `{"rules": [{"id": "a", "effect": "deny", "conditions": [{"attribute": "subject.id", "operator": "like", "value": 1}]}]}`, "operador inválido"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewEngine(writePolicy(t, "", tc.content))
+			if err == nil || !strings.Contains(err.Error(), tc.message) {
+				t.Errorf("NewEngine = %v, se esperaba un error con %q", err, tc.message)
+			}
+		})
+	}
+}
+
+func TestReloadKeepsTheLastValidPolicy(t *testing.T) {
+	path := writePolicy(t, "", `{"version": 1, "rules": [{"id": "first", "effect": "deny"}]}`)
+	engine, err := NewEngine(path)
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	ruleIDs := func() string {
+		policy, _ := engine.Policy()
+		ids := make([]string, 0, len(policy.Rules))
+		for _, rule := range policy.Rules {
+			ids = append(ids, rule.ID)
+		}
+		return strings.Join(ids, ",")
+	}
+
+	writePolicy(t, path, `{"version": 1, "rules": [{"id": "broken", "effect": "maybe"}]}`)
+	if err := engine.Reload(); err == nil {
+		t.Fatal("Reload aceptó una política inválida")
+	}
+	if ids := ruleIDs(); ids != "first" {
+		t.Errorf("tras un fichero inválido las reglas son %q, se esperaba first", ids)
+	}
+
+	// Watch detecta el cambio del fichero y carga la política nueva
+	engine.Watch(10 * time.Millisecond)
+	writePolicy(t, path, `{"version": 1, "rules": [{"id": "second", "effect": "deny"}, {"id": "third", "effect": "allow"}]}`)
+	deadline := time.Now().Add(2 * time.Second)
+	for ruleIDs() != "second,third" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if ids := ruleIDs(); ids != "second,third" {
+		t.Errorf("Watch no recargó la política: reglas %q", ids)
+	}
+}
+
+// newPermissionsDB crea una base de datos SQLite temporal con los roles y permisos por defecto
+func newPermissionsDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := db.Use(tenancy.Plugin{}); err != nil {
+		t.Fatalf("tenancy plugin: %v", err)
+	}
+	db = tenancy.Unscoped(db)
+	if err := db.AutoMigrate(&models.Role{}, &models.Permission{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if err := migrations.SeedDefaultRoles(db); err != nil {
+		t.Fatalf("seed roles: %v", err)
+	}
+	return db
+}
+
+func TestOwnershipPolicyFallsBackToRolePermissions(t *testing.T) {
+	db := newPermissionsDB(t)
+	roles := map[string]models.Role{}
+	for _, name := range []string{"editor", "user"} {
+		var role models.Role
+		if err := db.Where("name = ? AND tenant_id = 0", name).First(&role).Error; err != nil {
+			t.Fatalf("rol %s: %v", name, err)
+		}
+		roles[name] = role
+	}
+	principal := func(role string) *auth.Principal {
+		return &auth.Principal{UserID: 7, Role: role, RoleID: roles[role].ID, Method: auth.MethodJWT}
+	}
+	own := &models.Post{AuthorID: 7}
+	foreign := &models.Post{AuthorID: 8}
+
+	engine := newTestEngine(t, `{
+  "version": 1,
+  "rules": [
+    {"id": "users-update-any", "effect": "allow", "actions": ["posts:update"], "resources": ["post"],
+     "conditions": [{"attribute": "subject.role", "operator": "eq", "value": "user"}]},
+    {"id": "no-self-publish", "effect": "deny", "actions": ["posts:publish"], "resources": ["post"],
+     "conditions": [{"attribute": "resource.author_id", "operator": "eq", "ref": "subject.id"}]}
+  ]
+}`)
+	policy := NewPostPolicy(engine, db, services.NewCacheService(time.Minute, 0))
+
+	for _, tc := range []struct {
+		name       string
+		role       string
+		action     string
+		resource   Owned
+		allowed    bool
+		permission string // permiso del rol evaluado; vacío si decidió una regla
+	}{
+		{"una regla allow concede sin el permiso del rol", "user", ActionUpdate, foreign, true, ""},
+		{"una regla deny deniega aunque el rol tenga el permiso", "editor", ActionPublish, own, false, ""},
+		{"sin reglas el propietario necesita posts:delete", "user", ActionDelete, own, true, "posts:delete"},
+		{"sin reglas un post ajeno necesita posts:delete_any", "user", ActionDelete, foreign, false, "posts:delete_any"},
+		{"sin reglas el editor borra posts ajenos", "editor", ActionDelete, foreign, true, "posts:delete_any"},
+		{"publicar necesita posts:publish", "editor", ActionPublish, foreign, true, "posts:publish"},
+		{"el borrado definitivo necesita posts:hard_delete", "editor", ActionHardDelete, own, false, "posts:hard_delete"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			explanation, err := policy.Explain(principal(tc.role), tc.action, tc.resource, Attributes{})
+			if err != nil {
+				t.Fatalf("Explain: %v", err)
+			}
+			if explanation.Allowed != tc.allowed || explanation.Permission != tc.permission {
+				t.Errorf("explicación = %+v, se esperaba permitido=%v permiso=%q", explanation, tc.allowed, tc.permission)
+			}
+
+			err = policy.Authorize(principal(tc.role), tc.action, tc.resource, "127.0.0.1")
+			if (err == nil) != tc.allowed {
+				t.Errorf("Authorize = %v, se esperaba permitido=%v", err, tc.allowed)
+			}
+		})
+	}
+}
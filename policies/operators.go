@@ -0,0 +1,85 @@
+package policies
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// operator compara el valor de un atributo con el valor esperado de la condición
+type operator func(actual, expected interface{}) bool
+
+// operators son los operadores disponibles en las condiciones.
+// exists se resuelve en Condition.evaluate porque solo mira el atributo.
+var operators = map[string]operator{
+	"eq":       equals,
+	"ne":       func(actual, expected interface{}) bool { return !equals(actual, expected) },
+	"in":       func(actual, expected interface{}) bool { return contains(expected, actual) },
+	"not_in":   func(actual, expected interface{}) bool { return !contains(expected, actual) },
+	"contains": contains,
+	"gt":       compareWith(func(a, b float64) bool { return a > b }),
+	"gte":      compareWith(func(a, b float64) bool { return a >= b }),
+	"lt":       compareWith(func(a, b float64) bool { return a < b }),
+	"lte":      compareWith(func(a, b float64) bool { return a <= b }),
+	"exists":   nil,
+}
+
+// equals compara dos valores; los números se comparan por su valor sin importar su tipo
+// (los del fichero JSON son float64 y los de los modelos uint)
+func equals(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+	if x, ok := a.(string); ok {
+		return x == fmt.Sprint(b)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// contains indica si la lista contiene el valor
+func contains(list, value interface{}) bool {
+	items := reflect.ValueOf(list)
+	if list == nil || (items.Kind() != reflect.Slice && items.Kind() != reflect.Array) {
+		return false
+	}
+	for i := 0; i < items.Len(); i++ {
+		if equals(items.Index(i).Interface(), value) {
+			return true
+		}
+	}
+	return false
+}
+
+func compareWith(cmp func(a, b float64) bool) operator {
+	return func(actual, expected interface{}) bool {
+		x, ok := toFloat(actual)
+		if !ok {
+			return false
+		}
+		y, ok := toFloat(expected)
+		return ok && cmp(x, y)
+	}
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	}
+	return 0, false
+}
@@ -0,0 +1,137 @@
+package policies
+
+import (
+	"fmt"
+	"time"
+
+	"go-api-orm/auth"
+	"go-api-orm/services"
+	"gorm.io/gorm"
+)
+
+// Acciones evaluadas por las políticas de propiedad
+const (
+	ActionUpdate     = "update"
+	ActionDelete     = "delete"
+	ActionHardDelete = "hard_delete"
+	ActionPublish    = "publish" // cambios editoriales: revisar, programar, publicar y archivar
+)
+
+// Owned es un recurso que pertenece a un usuario (p. ej. un post a través de AuthorID)
+type Owned interface {
+	OwnerID() uint
+}
+
+// OwnershipPolicy autoriza acciones sobre recursos con propietario. Primero consulta el motor
+// de políticas: una regla deny deniega y una allow permite. Si ninguna regla coincide se
+// aplican los permisos del rol:
+//   - el propietario necesita <recurso>:<acción> (p. ej. posts:update)
+//   - sobre recursos ajenos se necesita <recurso>:<acción>_any (p. ej. posts:update_any)
+//   - el borrado definitivo siempre necesita <recurso>:hard_delete
+//   - publicar siempre necesita <recurso>:publish, también sobre los recursos propios
+type OwnershipPolicy struct {
+	resource     string
+	resourceType string
+	engine       *Engine
+	permissions  *services.PermissionService
+}
+
+// Explanation detalla cómo se decidió una autorización
+type Explanation struct {
+	Allowed           bool     `json:"allowed"`
+	Decision          Decision `json:"policy"`
+	Permission        string   `json:"permission,omitempty"` // permiso evaluado si ninguna regla coincide
+	PermissionGranted bool     `json:"permission_granted"`
+}
+
+// NewOwnershipPolicy crea la política de propiedad para el recurso indicado.
+// resource es el prefijo de los permisos (p. ej. "posts") y resourceType el tipo
+// de recurso en las reglas del motor (p. ej. "post").
+func NewOwnershipPolicy(resource, resourceType string, engine *Engine, db *gorm.DB, cache *services.CacheService) *OwnershipPolicy {
+	return &OwnershipPolicy{
+		resource:     resource,
+		resourceType: resourceType,
+		engine:       engine,
+		permissions:  services.NewPermissionService(db, cache),
+	}
+}
+
+// NewPostPolicy crea la política de propiedad de los posts
+func NewPostPolicy(engine *Engine, db *gorm.DB, cache *services.CacheService) *OwnershipPolicy {
+	return NewOwnershipPolicy("posts", "post", engine, db, cache)
+}
+
+// RequiredPermission retorna el permiso que necesita el usuario para realizar la acción sobre el recurso
+func (p *OwnershipPolicy) RequiredPermission(principal *auth.Principal, action string, resource Owned) string {
+	if action == ActionHardDelete || action == ActionPublish || principal.UserID == resource.OwnerID() {
+		return fmt.Sprintf("%s:%s", p.resource, action)
+	}
+	return fmt.Sprintf("%s:%s_any", p.resource, action)
+}
+
+// Request construye la petición que se evalúa en el motor para la acción sobre el recurso
+func (p *OwnershipPolicy) Request(principal *auth.Principal, action string, resource Owned, environment Attributes) Request {
+	return Request{
+		Subject:      SubjectAttributes(principal),
+		Action:       fmt.Sprintf("%s:%s", p.resource, action),
+		ResourceType: p.resourceType,
+		Resource:     ResourceAttributes(resource),
+		Environment:  environment,
+	}
+}
+
+// Explain evalúa la acción sobre el recurso y retorna la decisión con su justificación
+func (p *OwnershipPolicy) Explain(principal *auth.Principal, action string, resource Owned, environment Attributes) (*Explanation, error) {
+	return p.ExplainRequest(principal, action, resource, p.Request(principal, action, resource, environment))
+}
+
+// ExplainRequest evalúa una petición ya construida con Request, p. ej. con atributos
+// sustituidos para simular una decisión, y si ninguna regla coincide aplica los permisos del rol
+func (p *OwnershipPolicy) ExplainRequest(principal *auth.Principal, action string, resource Owned, request Request) (*Explanation, error) {
+	decision := p.engine.Evaluate(request)
+
+	explanation := &Explanation{Decision: decision}
+	if decision.Applicable {
+		explanation.Allowed = decision.Allowed
+		return explanation, nil
+	}
+
+	explanation.Permission = p.RequiredPermission(principal, action, resource)
+	granted, err := p.permissions.HasPermission(principal.RoleID, explanation.Permission)
+	if err != nil {
+		return nil, err
+	}
+	explanation.PermissionGranted = granted
+	explanation.Allowed = granted
+	return explanation, nil
+}
+
+// Authorize retorna ErrForbidden si el usuario no puede realizar la acción sobre el recurso
+func (p *OwnershipPolicy) Authorize(principal *auth.Principal, action string, resource Owned, ip string) error {
+	explanation, err := p.Explain(principal, action, resource, EnvironmentAttributes(ip, time.Now()))
+	if err != nil {
+		return services.ErrInternal(err)
+	}
+	if explanation.Allowed {
+		return nil
+	}
+
+	if explanation.Decision.Applicable {
+		return services.ErrForbidden(explanation.Decision.Reason)
+	}
+	if action == ActionPublish {
+		return services.ErrMissingPermission(explanation.Permission)
+	}
+	return services.ErrForbidden(forbiddenDetail(action, principal.UserID == resource.OwnerID()))
+}
+
+func forbiddenDetail(action string, owner bool) string {
+	switch {
+	case action == ActionHardDelete:
+		return "No tienes permisos para eliminar definitivamente este recurso"
+	case owner:
+		return "No tienes permisos para realizar esta operación sobre tus recursos"
+	default:
+		return "Solo puedes modificar tus propios recursos"
+	}
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"go-api-orm/controllers"
+	"go-api-orm/middleware"
+)
+
+func SetupAdminRoutes(router *gin.Engine) {
+	api := router.Group("/api")
+
+	// Rutas de administración
+	admin := api.Group("/admin")
+	admin.Use(middleware.AuthMiddleware(), middleware.RejectAPITokens())
+	{
+		admin.POST("/impersonate/:id", middleware.RejectImpersonation(), middleware.RequirePermission("users:impersonate"), controllers.StartImpersonation)
+		// Se autentica con el token de suplantación, cuyo rol es el del usuario suplantado
+		admin.DELETE("/impersonate", controllers.EndImpersonation)
+		admin.POST("/invitations", middleware.RejectImpersonation(), middleware.RequirePermission("users:invite"), controllers.CreateInvitation)
+		admin.GET("/policies", middleware.RequirePermission("policies:read"), controllers.GetPolicies)
+		admin.POST("/policies/explain", middleware.RequirePermission("policies:read"), controllers.ExplainPolicy)
+	}
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"go-api-orm/controllers"
+)
+
+func SetupAuthRoutes(router *gin.Engine) {
+	// Claves públicas para que otros servicios verifiquen los tokens
+	router.GET("/.well-known/jwks.json", controllers.GetJWKS)
+
+	api := router.Group("/api")
+
+	// Rutas públicas de autenticación
+	auth := api.Group("/auth")
+	{
+		auth.POST("/refresh", controllers.RefreshToken)
+		auth.GET("/oidc/:provider", controllers.OIDCLogin)
+		auth.GET("/oidc/:provider/callback", controllers.OIDCCallback)
+	}
+}
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"go-api-orm/controllers"
+	"go-api-orm/middleware"
+)
+
+func SetupOrganizationRoutes(router *gin.Engine) {
+	api := router.Group("/api")
+
+	// Rutas de organizaciones (los miembros se gestionan desde dentro de cada organización)
+	organizations := api.Group("/organizations")
+	organizations.Use(middleware.AuthMiddleware())
+	{
+		organizations.GET("", controllers.GetOrganizations)
+		organizations.POST("", middleware.RejectAPITokens(), middleware.RejectImpersonation(), middleware.RequirePermission("organizations:create"), controllers.CreateOrganization)
+		organizations.POST("/:id/switch", middleware.RejectAPITokens(), middleware.RejectImpersonation(), controllers.SwitchOrganization)
+		organizations.GET("/:id/members", middleware.RequirePermission("organizations:manage"), controllers.GetOrganizationMembers)
+		organizations.POST("/invitations/accept", middleware.RejectAPITokens(), middleware.RejectImpersonation(), controllers.AcceptOrganizationInvitation)
+		organizations.POST("/:id/members", middleware.RejectAPITokens(), middleware.RejectImpersonation(), middleware.RequirePermission("organizations:manage"), controllers.InviteOrganizationMember)
+		organizations.PUT("/:id/members/:user_id", middleware.RejectAPITokens(), middleware.RejectImpersonation(), middleware.RequirePermission("organizations:manage"), controllers.UpdateOrganizationMember)
+		organizations.DELETE("/:id/members/:user_id", middleware.RejectAPITokens(), middleware.RejectImpersonation(), middleware.RequirePermission("organizations:manage"), controllers.RemoveOrganizationMember)
+	}
+}
@@ -12,16 +12,26 @@ func SetupPostRoutes(router *gin.Engine) {
 	// Rutas públicas de posts
 	posts := api.Group("/posts")
 	{
-		posts.GET("", controllers.GetPosts)
-		posts.GET("/:slug", controllers.GetPostBySlug)
+		// Con un token la respuesta incluye los campos que el usuario puede ver
+		posts.GET("", middleware.OptionalAuth(), controllers.GetPosts)
+		posts.GET("/:slug", middleware.OptionalAuth(), controllers.GetPostBySlug)
 
 		// Rutas protegidas que requieren autenticación
 		protected := posts.Group("")
-		protected.Use(middleware.AuthMiddleware())
+		protected.Use(middleware.AuthMiddleware(), middleware.RequireVerifiedEmail())
 		{
-			protected.POST("", controllers.CreatePost)
+			protected.POST("", middleware.RequirePolicy("posts:create", "post"), controllers.CreatePost)
+			// La edición y el borrado se autorizan en el controlador según el autor del post
 			protected.PUT("/:slug", controllers.UpdatePost)
 			protected.DELETE("/:slug", controllers.DeletePost)
+			// Enviar a revisión lo puede hacer el autor; programar y publicar requieren posts:publish
+			protected.PUT("/:slug/status", controllers.ChangePostStatus)
+			// El historial lo consultan y restauran quienes pueden editar el post
+			protected.GET("/:slug/revisions", controllers.GetPostRevisions)
+			protected.GET("/:slug/revisions/diff", controllers.DiffPostRevisions)
+			protected.GET("/:slug/revisions/:rev", controllers.GetPostRevision)
+			protected.POST("/:slug/revisions/:rev/restore", controllers.RestorePostRevision)
+			protected.DELETE("/:slug/revisions", controllers.PrunePostRevisions)
 		}
 	}
 }
@@ -9,14 +9,18 @@ import (
 func SetupRoleRoutes(router *gin.Engine) {
 	api := router.Group("/api")
 
-	// Rutas de roles (todas protegidas ya que solo los administradores deberían manejar roles)
+	// Rutas de roles (todas protegidas; cada operación requiere su permiso)
 	roles := api.Group("/roles")
 	roles.Use(middleware.AuthMiddleware())
 	{
-		roles.POST("", controllers.CreateRole)
-		roles.GET("", controllers.GetRoles)
-		roles.GET("/:id", controllers.GetRole)
-		roles.PUT("/:id", controllers.UpdateRole)
-		roles.DELETE("/:id", controllers.DeleteRole)
+		roles.POST("", middleware.RequirePermission("roles:create"), controllers.CreateRole)
+		roles.GET("", middleware.RequirePermission("roles:read"), controllers.GetRoles)
+		roles.GET("/:id", middleware.RequirePermission("roles:read"), controllers.GetRole)
+		roles.PUT("/:id", middleware.RequirePermission("roles:update"), controllers.UpdateRole)
+		roles.DELETE("/:id", middleware.RequirePermission("roles:delete"), controllers.DeleteRole)
+		roles.POST("/:id/permissions", middleware.RequirePermission("roles:update"), controllers.GrantRolePermissions)
+		roles.DELETE("/:id/permissions/:permission", middleware.RequirePermission("roles:update"), controllers.RevokeRolePermission)
 	}
+
+	api.GET("/permissions", middleware.AuthMiddleware(), middleware.RequirePermission("roles:read"), controllers.GetPermissions)
 }
@@ -0,0 +1,41 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"go-api-orm/controllers"
+	"go-api-orm/middleware"
+)
+
+func SetupTaxonomyRoutes(router *gin.Engine) {
+	api := router.Group("/api")
+
+	// Rutas de etiquetas: la consulta es pública y la gestión requiere tags:manage
+	tags := api.Group("/tags")
+	{
+		tags.GET("", controllers.GetTags)
+		tags.GET("/:slug", controllers.GetTag)
+
+		protected := tags.Group("")
+		protected.Use(middleware.AuthMiddleware(), middleware.RequirePermission("tags:manage"))
+		{
+			protected.POST("", controllers.CreateTag)
+			protected.PUT("/:slug", controllers.UpdateTag)
+			protected.DELETE("/:slug", controllers.DeleteTag)
+		}
+	}
+
+	// Rutas de categorías: la consulta es pública y la gestión requiere categories:manage
+	categories := api.Group("/categories")
+	{
+		categories.GET("", controllers.GetCategories)
+		categories.GET("/:slug", controllers.GetCategory)
+
+		protected := categories.Group("")
+		protected.Use(middleware.AuthMiddleware(), middleware.RequirePermission("categories:manage"))
+		{
+			protected.POST("", controllers.CreateCategory)
+			protected.PUT("/:slug", controllers.UpdateCategory)
+			protected.DELETE("/:slug", controllers.DeleteCategory)
+		}
+	}
+}
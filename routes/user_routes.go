@@ -12,14 +12,37 @@ func SetupUserRoutes(router *gin.Engine) {
 	// Rutas públicas
 	api.POST("/register", controllers.Register)
 	api.POST("/login", controllers.Login)
+	api.POST("/login/mfa", controllers.LoginMFA)
+	api.POST("/login/mfa/enroll", controllers.LoginMFAEnroll)
+	api.POST("/login/mfa/enroll/confirm", controllers.LoginMFAEnrollConfirm)
+	api.POST("/password/forgot", controllers.ForgotPassword)
+	api.POST("/password/reset", controllers.ResetPassword)
+	api.POST("/email/verify", controllers.VerifyEmail)
+	api.POST("/email/resend", controllers.ResendVerificationEmail)
+
+	api.POST("/logout", middleware.AuthMiddleware(), middleware.RejectAPITokens(), middleware.RejectImpersonation(), controllers.Logout)
 
 	// Rutas protegidas
 	protected := api.Group("/users")
 	protected.Use(middleware.AuthMiddleware())
 	{
-		protected.GET("", controllers.GetUsers)
+		protected.GET("", middleware.RequirePermission("users:read"), controllers.GetUsers)
+		protected.PUT("/me/password", middleware.RejectAPITokens(), middleware.RejectImpersonation(), controllers.ChangePassword)
+		protected.POST("/me/mfa/enroll", middleware.RejectAPITokens(), middleware.RejectImpersonation(), controllers.EnrollMFA)
+		protected.POST("/me/mfa/confirm", middleware.RejectAPITokens(), middleware.RejectImpersonation(), controllers.ConfirmMFA)
+		protected.POST("/me/mfa/recovery-codes", middleware.RejectAPITokens(), middleware.RejectImpersonation(), controllers.RegenerateRecoveryCodes)
+		protected.DELETE("/me/mfa", middleware.RejectAPITokens(), middleware.RejectImpersonation(), controllers.DisableMFA)
+		protected.GET("/me/sessions", middleware.RejectAPITokens(), controllers.ListSessions)
+		protected.DELETE("/me/sessions", middleware.RejectAPITokens(), middleware.RejectImpersonation(), controllers.DeleteOtherSessions)
+		protected.DELETE("/me/sessions/:id", middleware.RejectAPITokens(), middleware.RejectImpersonation(), controllers.DeleteSession)
 		protected.GET("/:id", controllers.GetUser)
-		protected.PUT("/:id", controllers.UpdateUser)
-		protected.DELETE("/:id", middleware.RoleMiddleware("admin"), controllers.DeleteUser)
+		protected.PUT("/:id", middleware.RejectAPITokens(), middleware.RejectImpersonation(), controllers.UpdateUser)
+		protected.DELETE("/:id", middleware.RequirePermission("users:delete"), controllers.DeleteUser)
+		protected.PUT("/:id/role", middleware.RejectAPITokens(), middleware.RejectImpersonation(), middleware.RequirePermission("users:assign_role"), controllers.UpdateUserRole)
+		protected.POST("/:id/revoke-tokens", middleware.RequirePermission("users:manage"), controllers.RevokeUserTokens)
+		protected.POST("/:id/unlock", middleware.RequirePermission("users:manage"), controllers.UnlockUser)
+		protected.GET("/:id/tokens", middleware.RejectAPITokens(), middleware.RejectImpersonation(), controllers.ListAPITokens)
+		protected.POST("/:id/tokens", middleware.RejectAPITokens(), middleware.RejectImpersonation(), controllers.CreateAPIToken)
+		protected.DELETE("/:id/tokens/:token_id", middleware.RejectAPITokens(), middleware.RejectImpersonation(), controllers.DeleteAPIToken)
 	}
 }
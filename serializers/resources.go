@@ -0,0 +1,182 @@
+package serializers
+
+// Role muestra el nombre del rol; su configuración solo es visible para los administradores
+var Role = &Serializer{
+	Fields: []Field{
+		{Name: "id", Visibility: Public},
+		{Name: "name", Visibility: Public},
+		{Name: "description", Visibility: Admin},
+		{Name: "parent_id", Visibility: Admin},
+		{Name: "tenant_id", Visibility: Admin},
+		{Name: "system", Visibility: Admin},
+		{Name: "permissions", Visibility: Admin},
+		{Name: "created_at", Visibility: Admin},
+		{Name: "updated_at", Visibility: Admin},
+		{Name: "deleted_at", Visibility: Admin},
+	},
+}
+
+// Permission es un permiso del catálogo
+var Permission = &Serializer{
+	Fields: []Field{
+		{Name: "id", Visibility: Public},
+		{Name: "name", Visibility: Public},
+		{Name: "description", Visibility: Public},
+		{Name: "created_at", Visibility: Admin},
+		{Name: "updated_at", Visibility: Admin},
+	},
+}
+
+// RoleDetail es el rol en los endpoints de roles, que solo consultan quienes tienen roles:read:
+// su configuración es visible, la organización y las marcas de tiempo solo para los administradores
+var RoleDetail = &Serializer{
+	Fields: []Field{
+		{Name: "id", Visibility: Public},
+		{Name: "name", Visibility: Public},
+		{Name: "description", Visibility: Public},
+		{Name: "parent_id", Visibility: Public},
+		{Name: "system", Visibility: Public},
+		{Name: "permissions", Visibility: Public, Nested: Permission},
+		{Name: "inherits_from", Visibility: Public},
+		{Name: "effective_permissions", Visibility: Public},
+		{Name: "tenant_id", Visibility: Admin},
+		{Name: "created_at", Visibility: Admin},
+		{Name: "updated_at", Visibility: Admin},
+		{Name: "deleted_at", Visibility: Admin},
+	},
+}
+
+// User: el email y el estado de la cuenta solo los ven el propio usuario y los administradores,
+// y los IDs internos y las marcas de tiempo solo los administradores
+var User = &Serializer{
+	OwnerField: "id",
+	Fields: []Field{
+		{Name: "id", Visibility: Public},
+		{Name: "username", Visibility: Public},
+		{Name: "email", Visibility: Owner, Redact: true},
+		{Name: "role", Visibility: Public, Nested: Role},
+		{Name: "verified_at", Visibility: Owner},
+		{Name: "mfa_enabled_at", Visibility: Owner},
+		{Name: "role_id", Visibility: Admin},
+		{Name: "tenant_id", Visibility: Admin},
+		{Name: "created_at", Visibility: Admin},
+		{Name: "updated_at", Visibility: Admin},
+		{Name: "deleted_at", Visibility: Admin},
+	},
+}
+
+// Author es el autor incluido en un post
+var Author = &Serializer{
+	OwnerField: "id",
+	Fields: []Field{
+		{Name: "id", Visibility: Public},
+		{Name: "username", Visibility: Public},
+		{Name: "email", Visibility: Owner, Redact: true},
+	},
+}
+
+// Member es la membresía de un usuario de otra organización; su email sigue las reglas de User
+var Member = &Serializer{
+	OwnerField: "user_id",
+	Fields: []Field{
+		{Name: "id", Visibility: Public},
+		{Name: "user_id", Visibility: Public},
+		{Name: "user", Visibility: Public, Nested: User},
+		{Name: "role_id", Visibility: Public},
+		{Name: "role", Visibility: Public, Nested: Role},
+		{Name: "organization_id", Visibility: Public},
+		{Name: "created_at", Visibility: Public},
+		{Name: "updated_at", Visibility: Admin},
+	},
+}
+
+// Invitation: el email invitado solo lo ven quien envió la invitación y los administradores
+var Invitation = &Serializer{
+	OwnerField: "invited_by_id",
+	Fields: []Field{
+		{Name: "id", Visibility: Public},
+		{Name: "kind", Visibility: Public},
+		{Name: "email", Visibility: Owner, Redact: true},
+		{Name: "role_id", Visibility: Public},
+		{Name: "role", Visibility: Public, Nested: Role},
+		{Name: "invited_by_id", Visibility: Public},
+		{Name: "expires_at", Visibility: Public},
+		{Name: "accepted_at", Visibility: Public},
+		{Name: "created_at", Visibility: Public},
+		{Name: "tenant_id", Visibility: Admin},
+	},
+}
+
+// Tag es una etiqueta de posts
+var Tag = &Serializer{
+	Fields: []Field{
+		{Name: "id", Visibility: Public},
+		{Name: "name", Visibility: Public},
+		{Name: "slug", Visibility: Public},
+		{Name: "created_at", Visibility: Public},
+		{Name: "updated_at", Visibility: Public},
+		{Name: "tenant_id", Visibility: Admin},
+	},
+}
+
+// Category es una categoría de posts con su categoría padre y sus subcategorías
+var Category = &Serializer{
+	Fields: []Field{
+		{Name: "id", Visibility: Public},
+		{Name: "name", Visibility: Public},
+		{Name: "slug", Visibility: Public},
+		{Name: "description", Visibility: Public},
+		{Name: "parent_id", Visibility: Public},
+		{Name: "created_at", Visibility: Public},
+		{Name: "updated_at", Visibility: Public},
+		{Name: "tenant_id", Visibility: Admin},
+	},
+}
+
+func init() {
+	// La jerarquía es recursiva, así que se declara después de crear el serializador
+	Category.Fields = append(Category.Fields,
+		Field{Name: "parent", Visibility: Public, Nested: Category},
+		Field{Name: "children", Visibility: Public, Nested: Category},
+	)
+}
+
+// Post: las fechas de creación y edición forman parte del contenido; la organización y
+// la fecha de borrado son internas
+var Post = &Serializer{
+	OwnerField: "author_id",
+	Fields: []Field{
+		{Name: "id", Visibility: Public},
+		{Name: "title", Visibility: Public},
+		{Name: "slug", Visibility: Public},
+		{Name: "content", Visibility: Public},
+		{Name: "author_id", Visibility: Public},
+		{Name: "author", Visibility: Public, Nested: Author},
+		{Name: "status", Visibility: Public},
+		{Name: "publish_at", Visibility: Public}, // solo el autor y quien puede publicar ven los posts programados
+		{Name: "published_at", Visibility: Public},
+		{Name: "tags", Visibility: Public, Nested: Tag},
+		{Name: "categories", Visibility: Public, Nested: Category},
+		{Name: "created_at", Visibility: Public},
+		{Name: "updated_at", Visibility: Public},
+		{Name: "tenant_id", Visibility: Admin},
+		{Name: "deleted_at", Visibility: Admin},
+	},
+}
+
+// PostRevision: las revisiones solo las consultan quienes pueden editar el post
+var PostRevision = &Serializer{
+	OwnerField: "author_id",
+	Fields: []Field{
+		{Name: "id", Visibility: Public},
+		{Name: "post_id", Visibility: Public},
+		{Name: "number", Visibility: Public},
+		{Name: "title", Visibility: Public},
+		{Name: "slug", Visibility: Public},
+		{Name: "content", Visibility: Public},
+		{Name: "author_id", Visibility: Public},
+		{Name: "author", Visibility: Public, Nested: Author},
+		{Name: "created_at", Visibility: Public},
+		{Name: "tenant_id", Visibility: Admin},
+	},
+}
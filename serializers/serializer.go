@@ -0,0 +1,125 @@
+package serializers
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+)
+
+// Visibility indica quién puede ver un campo de la respuesta
+type Visibility int
+
+const (
+	// Public: cualquiera que pueda consultar el recurso
+	Public Visibility = iota
+	// Owner: el propietario del recurso (p. ej. el propio usuario) y los administradores
+	Owner
+	// Admin: solo los administradores
+	Admin
+)
+
+// Viewer es el usuario que recibe la respuesta
+type Viewer struct {
+	UserID uint // 0 si la petición no está autenticada
+	Admin  bool // rol admin o que hereda de él
+}
+
+// Field declara la visibilidad de un campo del recurso por su nombre en JSON
+type Field struct {
+	Name       string
+	Visibility Visibility
+	Redact     bool        // si no es visible se responde null en lugar de omitir el campo
+	Nested     *Serializer // serializador del objeto o la lista que contiene el campo
+}
+
+// Serializer declara una vez qué campos de un recurso puede ver cada usuario y se aplica
+// igual a los listados y al detalle. Los campos no declarados nunca se incluyen.
+type Serializer struct {
+	OwnerField string // campo con el ID del propietario del recurso (p. ej. id, author_id)
+	Fields     []Field
+}
+
+// Serialize convierte el valor (un modelo, un mapa o una lista de ellos) en su representación
+// JSON y elimina o redacta los campos que el usuario no puede ver
+func (s *Serializer) Serialize(viewer Viewer, value interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	// UseNumber conserva los IDs como números exactos para comparar el propietario
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var node interface{}
+	if err := decoder.Decode(&node); err != nil {
+		return nil, err
+	}
+	return s.apply(viewer, node), nil
+}
+
+// Filterable indica si el usuario puede filtrar u ordenar un listado por el campo. Como cada
+// fila tiene un propietario distinto, los campos de Owner solo los filtran los administradores.
+func (s *Serializer) Filterable(viewer Viewer, name string) bool {
+	for _, field := range s.Fields {
+		if field.Name == name {
+			return field.Nested == nil && field.visibleTo(viewer, false)
+		}
+	}
+	return false
+}
+
+func (s *Serializer) apply(viewer Viewer, node interface{}) interface{} {
+	switch value := node.(type) {
+	case []interface{}:
+		for i, item := range value {
+			value[i] = s.apply(viewer, item)
+		}
+		return value
+	case map[string]interface{}:
+		return s.filter(viewer, value)
+	default:
+		return node
+	}
+}
+
+func (s *Serializer) filter(viewer Viewer, object map[string]interface{}) map[string]interface{} {
+	owner := s.isOwner(viewer, object)
+
+	result := make(map[string]interface{}, len(s.Fields))
+	for _, field := range s.Fields {
+		value, present := object[field.Name]
+		if !present {
+			continue
+		}
+		if !field.visibleTo(viewer, owner) {
+			if field.Redact {
+				result[field.Name] = nil
+			}
+			continue
+		}
+		if field.Nested != nil {
+			value = field.Nested.apply(viewer, value)
+		}
+		result[field.Name] = value
+	}
+	return result
+}
+
+func (s *Serializer) isOwner(viewer Viewer, object map[string]interface{}) bool {
+	if viewer.UserID == 0 || s.OwnerField == "" {
+		return false
+	}
+	id, ok := object[s.OwnerField].(json.Number)
+	return ok && id.String() == strconv.FormatUint(uint64(viewer.UserID), 10)
+}
+
+func (f Field) visibleTo(viewer Viewer, owner bool) bool {
+	switch f.Visibility {
+	case Public:
+		return true
+	case Owner:
+		return owner || viewer.Admin
+	default:
+		return viewer.Admin
+	}
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-api-orm/models"
+	"go-api-orm/utils"
+	"gorm.io/gorm"
+)
+
+// APITokenPrefix identifica las claves de API frente a los JWT en la cabecera Authorization
+const APITokenPrefix = "pat_"
+
+// Scopes disponibles para las claves de API
+const (
+	APITokenScopeRead  = "read"
+	APITokenScopeWrite = "write"
+)
+
+// lastUsedResolution evita escribir en la base de datos en cada petición autenticada
+const lastUsedResolution = time.Minute
+
+// APITokenService maneja las claves de API personales de los usuarios
+type APITokenService struct {
+	db            *gorm.DB
+	cache         *CacheService
+	defaultExpiry int
+	maxExpiry     int
+}
+
+// NewAPITokenService crea una nueva instancia del servicio de claves de API
+func NewAPITokenService(db *gorm.DB, cache *CacheService) *APITokenService {
+	return &APITokenService{
+		db:            db,
+		cache:         cache,
+		defaultExpiry: utils.GetEnvInt("API_TOKEN_DEFAULT_EXPIRATION_DAYS", 90),
+		maxExpiry:     utils.GetEnvInt("API_TOKEN_MAX_EXPIRATION_DAYS", 365),
+	}
+}
+
+// ErrInvalidAPIToken se retorna cuando la clave no existe o expiró
+var ErrInvalidAPIToken = func() *APIError {
+	return ErrUnauthorized("Clave de API inválida o expirada")
+}
+
+// ErrInsufficientScope se retorna cuando la clave no tiene el scope necesario para la petición
+var ErrInsufficientScope = func(scope string) *APIError {
+	return NewAPIError(
+		http.StatusForbidden,
+		"INSUFFICIENT_SCOPE",
+		"La clave de API no tiene permisos para esta operación",
+		fmt.Sprintf("Se requiere el scope %s", scope),
+		nil,
+	)
+}
+
+func apiTokenCacheKey(hash string) string {
+	return "api_token:" + hash
+}
+
+// IsAPIToken indica si el valor de la cabecera Authorization corresponde a una clave de API
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, APITokenPrefix)
+}
+
+// RequiredScope retorna el scope necesario para un método HTTP
+func RequiredScope(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return APITokenScopeRead
+	}
+	return APITokenScopeWrite
+}
+
+// HasScope indica si la clave concede el scope indicado (write implica read)
+func HasScope(token *models.APIToken, scope string) bool {
+	for _, granted := range token.ScopeList() {
+		if granted == scope || (granted == APITokenScopeWrite && scope == APITokenScopeRead) {
+			return true
+		}
+	}
+	return false
+}
+
+// Create genera una nueva clave de API y retorna su valor en claro (solo se muestra una vez)
+func (s *APITokenService) Create(userID uint, name string, scopes []string, expiresInDays int) (string, *models.APIToken, error) {
+	normalized, err := normalizeScopes(scopes)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if expiresInDays == 0 {
+		expiresInDays = s.defaultExpiry
+	}
+	if expiresInDays < 1 || (s.maxExpiry > 0 && expiresInDays > s.maxExpiry) {
+		return "", nil, ErrInvalidInput(fmt.Sprintf("expires_in_days debe estar entre 1 y %d", s.maxExpiry))
+	}
+
+	secret, _, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+	token := APITokenPrefix + secret
+
+	record := models.APIToken{
+		UserID:    userID,
+		Name:      strings.TrimSpace(name),
+		Prefix:    token[:len(APITokenPrefix)+8],
+		TokenHash: utils.HashToken(token),
+		Scopes:    strings.Join(normalized, " "),
+		ExpiresAt: time.Now().AddDate(0, 0, expiresInDays),
+	}
+	if err := s.db.Create(&record).Error; err != nil {
+		return "", nil, err
+	}
+
+	return token, &record, nil
+}
+
+// List retorna las claves de API de un usuario
+func (s *APITokenService) List(userID uint) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// Revoke elimina una clave de API del usuario
+func (s *APITokenService) Revoke(userID, tokenID uint) error {
+	var token models.APIToken
+	if err := s.db.Where("id = ? AND user_id = ?", tokenID, userID).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound("Clave de API")
+		}
+		return err
+	}
+
+	if err := s.db.Delete(&token).Error; err != nil {
+		return err
+	}
+
+	s.cache.Delete(apiTokenCacheKey(token.TokenHash))
+	return nil
+}
+
+// RevokeAllForUser elimina todas las claves de API de un usuario
+func (s *APITokenService) RevokeAllForUser(userID uint) error {
+	tokens, err := s.List(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.Where("user_id = ?", userID).Delete(&models.APIToken{}).Error; err != nil {
+		return err
+	}
+
+	for _, token := range tokens {
+		s.cache.Delete(apiTokenCacheKey(token.TokenHash))
+	}
+	return nil
+}
+
+// Authenticate valida una clave de API y registra su último uso
+func (s *APITokenService) Authenticate(token string) (*models.APIToken, error) {
+	hash := utils.HashToken(token)
+
+	record, found := GetTyped[models.APIToken](s.cache, apiTokenCacheKey(hash))
+	if !found {
+		if err := s.db.Where("token_hash = ?", hash).First(&record).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrInvalidAPIToken()
+			}
+			return nil, err
+		}
+	}
+
+	if record.IsExpired() {
+		s.cache.Delete(apiTokenCacheKey(hash))
+		return nil, ErrInvalidAPIToken()
+	}
+
+	now := time.Now()
+	if record.LastUsedAt == nil || now.Sub(*record.LastUsedAt) >= lastUsedResolution {
+		if err := s.db.Model(&models.APIToken{}).Where("id = ?", record.ID).Update("last_used_at", now).Error; err != nil {
+			return nil, err
+		}
+		record.LastUsedAt = &now
+	}
+
+	s.cache.Set(apiTokenCacheKey(hash), record)
+	return &record, nil
+}
+
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return []string{APITokenScopeRead}, nil
+	}
+
+	seen := make(map[string]bool)
+	normalized := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if scope != APITokenScopeRead && scope != APITokenScopeWrite {
+			return nil, ErrInvalidInput(fmt.Sprintf("Scope desconocido: %s", scope))
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			normalized = append(normalized, scope)
+		}
+	}
+	return normalized, nil
+}
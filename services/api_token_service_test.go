@@ -0,0 +1,135 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go-api-orm/models"
+)
+
+func isInvalidAPIToken(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Detail == ErrInvalidAPIToken().Detail
+}
+
+func TestAPITokenAuthenticate(t *testing.T) {
+	db := newTestDB(t)
+	tokens := NewAPITokenService(db, NewCacheService(time.Minute, 0))
+
+	plain, created, err := tokens.Create(1, "ci", []string{"Write", "read", "write"}, 0)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if !IsAPIToken(plain) || created.Scopes != "write read" || !strings.HasPrefix(plain, created.Prefix) {
+		t.Fatalf("Create = %q, %+v", plain, created)
+	}
+
+	// Solo se guarda el hash: la clave en claro no aparece en ninguna columna
+	var stored models.APIToken
+	if err := db.First(&stored, created.ID).Error; err != nil {
+		t.Fatalf("leer clave: %v", err)
+	}
+	if stored.TokenHash == plain || strings.Contains(stored.TokenHash+stored.Prefix+stored.Name+stored.Scopes, plain) {
+		t.Fatalf("la clave se guardó en claro: %+v", stored)
+	}
+	var byPlain int64
+	if err := db.Model(&models.APIToken{}).Where("token_hash = ?", plain).Count(&byPlain).Error; err != nil || byPlain != 0 {
+		t.Fatalf("la clave en claro se encuentra como hash (%d, %v)", byPlain, err)
+	}
+
+	authenticated, err := tokens.Authenticate(plain)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if authenticated.ID != created.ID || authenticated.LastUsedAt == nil {
+		t.Fatalf("Authenticate = %+v", authenticated)
+	}
+	for _, token := range []string{"pat_desconocida", created.Prefix, stored.TokenHash} {
+		if _, err := tokens.Authenticate(token); !isInvalidAPIToken(err) {
+			t.Errorf("Authenticate(%q) = %v, se esperaba una clave inválida", token, err)
+		}
+	}
+
+	// last_used_at se escribe como mucho una vez por minuto, aunque la caché esté vacía
+	lastUsed := func() time.Time {
+		t.Helper()
+		var token models.APIToken
+		if err := db.First(&token, created.ID).Error; err != nil || token.LastUsedAt == nil {
+			t.Fatalf("leer last_used_at: %v", err)
+		}
+		return *token.LastUsedAt
+	}
+	first := lastUsed()
+	for _, service := range []*APITokenService{tokens, NewAPITokenService(db, NewCacheService(time.Minute, 0))} {
+		if _, err := service.Authenticate(plain); err != nil {
+			t.Fatalf("Authenticate: %v", err)
+		}
+	}
+	if !lastUsed().Equal(first) {
+		t.Errorf("last_used_at cambió antes de un minuto: %v -> %v", first, lastUsed())
+	}
+	stale := time.Now().Add(-2 * lastUsedResolution)
+	if err := db.Model(&models.APIToken{}).Where("id = ?", created.ID).Update("last_used_at", stale).Error; err != nil {
+		t.Fatalf("actualizar last_used_at: %v", err)
+	}
+	if _, err := NewAPITokenService(db, NewCacheService(time.Minute, 0)).Authenticate(plain); err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if !lastUsed().After(first) {
+		t.Errorf("last_used_at no se actualizó pasado un minuto")
+	}
+
+	// Una clave expirada se rechaza
+	if err := db.Model(&models.APIToken{}).Where("id = ?", created.ID).Update("expires_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatalf("expirar clave: %v", err)
+	}
+	if _, err := NewAPITokenService(db, NewCacheService(time.Minute, 0)).Authenticate(plain); !isInvalidAPIToken(err) {
+		t.Errorf("Authenticate de una clave expirada = %v", err)
+	}
+
+	// Revocar la clave la elimina también de la caché
+	if err := tokens.Revoke(1, created.ID); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if _, err := tokens.Authenticate(plain); !isInvalidAPIToken(err) {
+		t.Errorf("Authenticate de una clave revocada = %v", err)
+	}
+}
+
+func TestAPITokenScopes(t *testing.T) {
+	for _, tc := range []struct {
+		method string
+		scope  string
+	}{
+		{"GET", APITokenScopeRead},
+		{"HEAD", APITokenScopeRead},
+		{"OPTIONS", APITokenScopeRead},
+		{"POST", APITokenScopeWrite},
+		{"PUT", APITokenScopeWrite},
+		{"PATCH", APITokenScopeWrite},
+		{"DELETE", APITokenScopeWrite},
+	} {
+		if scope := RequiredScope(tc.method); scope != tc.scope {
+			t.Errorf("RequiredScope(%s) = %s, se esperaba %s", tc.method, scope, tc.scope)
+		}
+	}
+
+	read := &models.APIToken{Scopes: "read"}
+	write := &models.APIToken{Scopes: "write"}
+	if !HasScope(read, APITokenScopeRead) || HasScope(read, APITokenScopeWrite) {
+		t.Errorf("una clave read debe conceder solo lectura")
+	}
+	if !HasScope(write, APITokenScopeRead) || !HasScope(write, APITokenScopeWrite) {
+		t.Errorf("una clave write debe conceder lectura y escritura")
+	}
+
+	tokens := NewAPITokenService(newTestDB(t), NewCacheService(time.Minute, 0))
+	if _, created, err := tokens.Create(1, "por defecto", nil, 0); err != nil || created.Scopes != APITokenScopeRead {
+		t.Errorf("Create sin scopes = %+v, %v; se esperaba read", created, err)
+	}
+	if _, _, err := tokens.Create(1, "admin", []string{"admin"}, 0); err == nil {
+		t.Errorf("Create aceptó un scope desconocido")
+	}
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"log"
+
+	"go-api-orm/models"
+	"gorm.io/gorm"
+)
+
+// Acciones registradas en el historial de auditoría
+const (
+	AuditImpersonationStart   = "impersonation.start"
+	AuditImpersonationEnd     = "impersonation.end"
+	AuditImpersonationRequest = "impersonation.request"
+)
+
+// AuditService guarda el historial de acciones realizadas por administradores
+type AuditService struct {
+	db *gorm.DB
+}
+
+// NewAuditService crea una nueva instancia del servicio de auditoría
+func NewAuditService(db *gorm.DB) *AuditService {
+	return &AuditService{db: db}
+}
+
+// Record guarda la entrada en la base de datos y la escribe en el log
+func (s *AuditService) Record(entry *models.AuditLog) error {
+	log.Printf("[AUDIT] %s actor=%d user=%d %s %s status=%d ip=%s",
+		entry.Action, entry.ActorID, entry.UserID, entry.Method, entry.Path, entry.Status, entry.IPAddress)
+
+	if len(entry.Path) > 255 {
+		entry.Path = entry.Path[:255]
+	}
+	return s.db.Create(entry).Error
+}
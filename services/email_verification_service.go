@@ -0,0 +1,164 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"go-api-orm/models"
+	"go-api-orm/tenancy"
+	"go-api-orm/utils"
+	"gorm.io/gorm"
+)
+
+// Modos de EMAIL_VERIFICATION_REQUIRED
+const (
+	EmailVerificationOff    = "off"
+	EmailVerificationLogin  = "login"
+	EmailVerificationRoutes = "routes"
+)
+
+// EmailVerificationService maneja la verificación de emails mediante enlaces firmados
+type EmailVerificationService struct {
+	db     *gorm.DB
+	mailer Mailer
+	secret []byte
+	ttl    time.Duration
+}
+
+// emailVerificationPayload es el contenido firmado del enlace de verificación
+type emailVerificationPayload struct {
+	UserID    uint   `json:"uid"`
+	Email     string `json:"email"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// NewEmailVerificationService crea una nueva instancia del servicio de verificación.
+// Los emails son únicos en toda la aplicación y el enlace se abre sin sesión, por lo que
+// no filtra por organización.
+func NewEmailVerificationService(db *gorm.DB, mailer Mailer) *EmailVerificationService {
+	return &EmailVerificationService{
+		db:     tenancy.Unscoped(db),
+		mailer: mailer,
+		secret: []byte(utils.GetEnvString("EMAIL_VERIFICATION_SECRET", os.Getenv("JWT_SECRET_KEY"))),
+		ttl:    time.Hour * time.Duration(utils.GetEnvInt("EMAIL_VERIFICATION_EXPIRATION_HOURS", 48)),
+	}
+}
+
+// EmailVerificationMode retorna dónde se exige un email verificado: off, login o routes
+func EmailVerificationMode() string {
+	mode := strings.ToLower(utils.GetEnvString("EMAIL_VERIFICATION_REQUIRED", EmailVerificationOff))
+	switch mode {
+	case EmailVerificationLogin, EmailVerificationRoutes:
+		return mode
+	}
+	return EmailVerificationOff
+}
+
+// ErrEmailNotVerified se retorna cuando la cuenta aún no confirmó su email
+var ErrEmailNotVerified = func() *APIError {
+	return NewAPIError(
+		http.StatusForbidden,
+		"EMAIL_NOT_VERIFIED",
+		"Debes verificar tu email antes de continuar",
+		"",
+		nil,
+	)
+}
+
+// ErrInvalidVerificationToken se retorna cuando el enlace es inválido o expiró
+var ErrInvalidVerificationToken = func() *APIError {
+	return ErrInvalidInput("El enlace de verificación es inválido o ha expirado")
+}
+
+// SendVerification envía al usuario un enlace firmado para verificar su email
+func (s *EmailVerificationService) SendVerification(user *models.User) error {
+	if len(s.secret) == 0 {
+		return errors.New("EMAIL_VERIFICATION_SECRET o JWT_SECRET_KEY es requerido")
+	}
+
+	token, err := utils.SignPayload(s.secret, emailVerificationPayload{
+		UserID:    user.ID,
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(s.ttl).Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(MailMessage{
+		To:      []string{user.Email},
+		Subject: "Verifica tu email",
+		Body: fmt.Sprintf(
+			"Hola %s,\n\nConfirma tu dirección de email visitando el siguiente enlace:\n\n%s\n\nEl enlace expira en %d horas.\n",
+			user.Username,
+			s.verificationURL(token),
+			int(s.ttl.Hours()),
+		),
+	})
+}
+
+// Resend reenvía el enlace si el email pertenece a una cuenta sin verificar.
+// No revela si el email está registrado.
+func (s *EmailVerificationService) Resend(email string) error {
+	var user models.User
+	if err := s.db.Where("email = ?", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if user.IsVerified() {
+		return nil
+	}
+
+	return s.SendVerification(&user)
+}
+
+// Verify valida el enlace firmado y marca el email del usuario como verificado
+func (s *EmailVerificationService) Verify(token string) (*models.User, error) {
+	var payload emailVerificationPayload
+	if len(s.secret) == 0 || utils.VerifyPayload(s.secret, token, &payload) != nil {
+		return nil, ErrInvalidVerificationToken()
+	}
+
+	if time.Now().Unix() > payload.ExpiresAt {
+		return nil, ErrInvalidVerificationToken()
+	}
+
+	var user models.User
+	if err := s.db.First(&user, payload.UserID).Error; err != nil {
+		return nil, ErrInvalidVerificationToken()
+	}
+
+	// Si el email cambió después de enviar el enlace, el enlace deja de ser válido
+	if !strings.EqualFold(user.Email, payload.Email) {
+		return nil, ErrInvalidVerificationToken()
+	}
+
+	if user.IsVerified() {
+		return &user, nil
+	}
+
+	now := time.Now()
+	if err := s.db.Model(&user).Update("verified_at", now).Error; err != nil {
+		return nil, err
+	}
+	user.VerifiedAt = &now
+
+	return &user, nil
+}
+
+func (s *EmailVerificationService) verificationURL(token string) string {
+	base := utils.GetEnvString("EMAIL_VERIFICATION_URL", strings.TrimRight(utils.GetEnvString("APP_URL", "http://localhost:8080"), "/")+"/verify-email")
+	separator := "?"
+	if strings.Contains(base, "?") {
+		separator = "&"
+	}
+	return base + separator + "token=" + url.QueryEscape(token)
+}
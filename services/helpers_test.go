@@ -0,0 +1,67 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+
+	"go-api-orm/migrations"
+	"go-api-orm/models"
+	"go-api-orm/tenancy"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB crea una base de datos SQLite temporal con el esquema, los roles por defecto
+// y la organización por defecto, configurada como config.InitDB. Como config.DB, exige
+// organización en el contexto o tenancy.Unscoped para operar sobre los modelos con TenantID.
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	if err := db.Use(tenancy.Plugin{}); err != nil {
+		t.Fatalf("tenancy plugin: %v", err)
+	}
+	if err := db.AutoMigrate(
+		&models.User{},
+		&models.Post{},
+		&models.Role{},
+		&models.Permission{},
+		&models.RefreshToken{},
+		&models.RevokedToken{},
+		&models.PasswordResetToken{},
+		&models.RecoveryCode{},
+		&models.LoginAttempt{},
+		&models.APIToken{},
+		&models.UserIdentity{},
+		&models.Session{},
+		&models.PasswordHistory{},
+		&models.AuditLog{},
+		&models.Invitation{},
+		&models.Organization{},
+		&models.Membership{},
+		&models.PostRevision{},
+		&models.Tag{},
+		&models.Category{},
+	); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if err := migrations.SeedDefaultRoles(tenancy.Unscoped(db)); err != nil {
+		t.Fatalf("seed roles: %v", err)
+	}
+	if err := migrations.SeedDefaultOrganization(tenancy.Unscoped(db)); err != nil {
+		t.Fatalf("seed organization: %v", err)
+	}
+	return db
+}
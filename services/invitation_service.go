@@ -0,0 +1,273 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"go-api-orm/models"
+	"go-api-orm/tenancy"
+	"go-api-orm/utils"
+	"gorm.io/gorm"
+)
+
+// InvitationService emite y consume las invitaciones que permiten registrarse con un rol
+// distinto del rol por defecto
+type InvitationService struct {
+	db     *gorm.DB
+	mailer Mailer
+	ttl    time.Duration
+}
+
+// NewInvitationService crea una nueva instancia del servicio de invitaciones
+func NewInvitationService(db *gorm.DB, mailer Mailer) *InvitationService {
+	return &InvitationService{
+		db:     db,
+		mailer: mailer,
+		ttl:    time.Hour * time.Duration(utils.GetEnvInt("INVITATION_EXPIRATION_HOURS", 72)),
+	}
+}
+
+// ErrInvalidInvitation se retorna cuando la invitación no existe, expiró, ya fue usada o es de otro email
+var ErrInvalidInvitation = func() *APIError {
+	return ErrInvalidInput("La invitación es inválida o ha expirado")
+}
+
+// Invite crea una invitación con el rol indicado y la envía por correo. Con una organización en
+// el contexto de la conexión el usuario se registrará en ella.
+// Solo la última invitación enviada a un email es válida.
+func (s *InvitationService) Invite(email string, roleID, invitedByID uint) (*models.Invitation, error) {
+	// El email no puede existir en ninguna organización
+	var existing int64
+	if err := tenancy.Unscoped(s.db).Model(&models.User{}).Where("email = ?", email).Count(&existing).Error; err != nil {
+		return nil, err
+	}
+	if existing > 0 {
+		return nil, ErrInvalidInput("Ya existe un usuario con ese email")
+	}
+
+	var role models.Role
+	if err := s.db.First(&role, roleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRole()
+		}
+		return nil, err
+	}
+
+	token, hash, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation := models.Invitation{
+		Kind:        models.InvitationRegistration,
+		Email:       email,
+		RoleID:      role.ID,
+		Role:        role,
+		InvitedByID: invitedByID,
+		TokenHash:   hash,
+		ExpiresAt:   time.Now().Add(s.ttl),
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("kind = ? AND email = ? AND accepted_at IS NULL", models.InvitationRegistration, email).Delete(&models.Invitation{}).Error; err != nil {
+			return err
+		}
+		return tx.Omit("Role").Create(&invitation).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.mailer.Send(MailMessage{
+		To:      []string{email},
+		Subject: "Invitación",
+		Body: fmt.Sprintf(
+			"Hola,\n\nHas sido invitado a registrarte con el rol %s. Para crear tu cuenta visita el siguiente enlace:\n\n%s\n\nEl enlace expira en %d horas.\n",
+			role.Name,
+			s.invitationURL(token),
+			int(s.ttl.Hours()),
+		),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// Accept consume la invitación de registro para el email indicado. Debe llamarse dentro de la
+// transacción que crea el usuario para que la invitación solo se use si el registro termina.
+func (s *InvitationService) Accept(token, email string) (*models.Invitation, error) {
+	return s.consume(s.db, models.InvitationRegistration, token, email)
+}
+
+// InviteMember invita al email indicado a unirse a la organización con el rol indicado y le envía
+// el enlace por correo. No revela si el email tiene cuenta: la invitación se envía igualmente y
+// solo la acepta, con AcceptMembership, el usuario autenticado con ese email. La conexión debe
+// tener la organización en el contexto. Solo la última invitación enviada a un email es válida.
+func (s *InvitationService) InviteMember(organizationID uint, email string, roleID, invitedByID uint) (*models.Invitation, error) {
+	// Los usuarios de la organización ya tienen acceso
+	var existing int64
+	if err := s.db.Model(&models.User{}).Where("email = ?", email).Count(&existing).Error; err != nil {
+		return nil, err
+	}
+	if existing > 0 {
+		return nil, ErrInvalidInput("El usuario ya pertenece a la organización")
+	}
+	if err := tenancy.Unscoped(s.db).Model(&models.Membership{}).
+		Joins("JOIN users ON users.id = memberships.user_id").
+		Where("memberships.organization_id = ? AND users.email = ?", organizationID, email).
+		Count(&existing).Error; err != nil {
+		return nil, err
+	}
+	if existing > 0 {
+		return nil, ErrInvalidInput("El usuario ya es miembro de la organización; cambia su rol desde sus miembros")
+	}
+
+	// Los roles visibles en la organización son los globales y los suyos
+	var role models.Role
+	if err := s.db.First(&role, roleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRole()
+		}
+		return nil, err
+	}
+
+	var organization models.Organization
+	if err := s.db.First(&organization, organizationID).Error; err != nil {
+		return nil, err
+	}
+
+	token, hash, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation := models.Invitation{
+		Kind:        models.InvitationMembership,
+		Email:       email,
+		TenantID:    organizationID,
+		RoleID:      role.ID,
+		Role:        role,
+		InvitedByID: invitedByID,
+		TokenHash:   hash,
+		ExpiresAt:   time.Now().Add(s.ttl),
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("kind = ? AND email = ? AND accepted_at IS NULL", models.InvitationMembership, email).Delete(&models.Invitation{}).Error; err != nil {
+			return err
+		}
+		return tx.Omit("Role").Create(&invitation).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.mailer.Send(MailMessage{
+		To:      []string{email},
+		Subject: "Invitación a " + organization.Name,
+		Body: fmt.Sprintf(
+			"Hola,\n\nHas sido invitado a unirte a la organización %s con el rol %s. Para aceptar, inicia sesión con este email y visita el siguiente enlace:\n\n%s\n\nEl enlace expira en %d horas.\n",
+			organization.Name,
+			role.Name,
+			s.membershipURL(token),
+			int(s.ttl.Hours()),
+		),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// AcceptMembership consume una invitación a una organización para el usuario autenticado, que
+// debe tener el email invitado, y le da acceso a ella con el rol de la invitación. Si ya era
+// miembro se le asigna ese rol.
+func (s *InvitationService) AcceptMembership(token string, user *models.User) (*models.Membership, error) {
+	// La invitación y la membresía son de otra organización que la del usuario
+	db := tenancy.Unscoped(s.db)
+
+	var membership models.Membership
+	err := db.Transaction(func(tx *gorm.DB) error {
+		invitation, err := s.consume(tx, models.InvitationMembership, token, user.Email)
+		if err != nil {
+			return err
+		}
+		if invitation.TenantID == user.TenantID {
+			return ErrInvalidInput("Ya perteneces a la organización")
+		}
+
+		// El rol pudo eliminarse después de enviar la invitación
+		var role models.Role
+		if err := tx.Where("id = ? AND tenant_id IN ?", invitation.RoleID, []uint{0, invitation.TenantID}).First(&role).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidRole()
+			}
+			return err
+		}
+
+		err = tx.Where("organization_id = ? AND user_id = ?", invitation.TenantID, user.ID).First(&membership).Error
+		switch {
+		case err == nil:
+			return tx.Model(&models.Membership{}).Where("id = ?", membership.ID).Update("role_id", role.ID).Error
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			membership = models.Membership{OrganizationID: invitation.TenantID, UserID: user.ID, RoleID: role.ID}
+			return tx.Create(&membership).Error
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Preload("Organization").Preload("Role").First(&membership, membership.ID).Error; err != nil {
+		return nil, err
+	}
+	return &membership, nil
+}
+
+// consume marca como usada la invitación del tipo indicado si sigue vigente y es para el email
+func (s *InvitationService) consume(db *gorm.DB, kind, token, email string) (*models.Invitation, error) {
+	var invitation models.Invitation
+	if err := db.Where("token_hash = ? AND kind = ?", utils.HashToken(token), kind).First(&invitation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidInvitation()
+		}
+		return nil, err
+	}
+
+	if invitation.AcceptedAt != nil || time.Now().After(invitation.ExpiresAt) || !strings.EqualFold(invitation.Email, email) {
+		return nil, ErrInvalidInvitation()
+	}
+
+	// Marcar la invitación como usada de forma atómica para que sea de un solo uso
+	result := db.Model(&models.Invitation{}).
+		Where("id = ? AND accepted_at IS NULL", invitation.ID).
+		Update("accepted_at", time.Now())
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrInvalidInvitation()
+	}
+
+	return &invitation, nil
+}
+
+func (s *InvitationService) invitationURL(token string) string {
+	return withInviteToken(utils.GetEnvString("INVITATION_URL", strings.TrimRight(utils.GetEnvString("APP_URL", "http://localhost:8080"), "/")+"/register"), token)
+}
+
+func (s *InvitationService) membershipURL(token string) string {
+	return withInviteToken(utils.GetEnvString("MEMBERSHIP_INVITATION_URL", strings.TrimRight(utils.GetEnvString("APP_URL", "http://localhost:8080"), "/")+"/organizations/accept"), token)
+}
+
+// withInviteToken añade el token de la invitación a la URL base
+func withInviteToken(base, token string) string {
+	separator := "?"
+	if strings.Contains(base, "?") {
+		separator = "&"
+	}
+	return base + separator + "invite_token=" + url.QueryEscape(token)
+}
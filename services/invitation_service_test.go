@@ -0,0 +1,129 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"go-api-orm/models"
+	"go-api-orm/tenancy"
+)
+
+var inviteTokenPattern = regexp.MustCompile(`invite_token=([^\s]+)`)
+
+// lastInviteToken retorna el token del último enlace de invitación enviado
+func lastInviteToken(t *testing.T, mail *bytes.Buffer) string {
+	t.Helper()
+
+	matches := inviteTokenPattern.FindAllStringSubmatch(mail.String(), -1)
+	if len(matches) == 0 {
+		t.Fatalf("no se envió ninguna invitación: %q", mail.String())
+	}
+	token, err := url.QueryUnescape(matches[len(matches)-1][1])
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+	return token
+}
+
+func TestMembershipInvitationMustBeAcceptedByTheInvitedUser(t *testing.T) {
+	db := newTestDB(t)
+	unscoped := tenancy.Unscoped(db)
+
+	home, err := NewOrganizationService(unscoped, NewCacheService(time.Minute, 0)).Default()
+	if err != nil {
+		t.Fatalf("Default: %v", err)
+	}
+	acme := models.Organization{Name: "Acme", Slug: "acme"}
+	if err := unscoped.Create(&acme).Error; err != nil {
+		t.Fatalf("crear organización: %v", err)
+	}
+	role, err := DefaultRole(unscoped)
+	if err != nil {
+		t.Fatalf("DefaultRole: %v", err)
+	}
+	ana := models.User{Username: "ana", Email: "ana@example.com", Password: "Secreta123!", RoleID: role.ID, TenantID: home.ID}
+	eve := models.User{Username: "eve", Email: "eve@example.com", Password: "Secreta123!", RoleID: role.ID, TenantID: home.ID}
+	for _, user := range []*models.User{&ana, &eve} {
+		if err := unscoped.Create(user).Error; err != nil {
+			t.Fatalf("crear usuario: %v", err)
+		}
+	}
+
+	var mail bytes.Buffer
+	acmeDB := db.WithContext(tenancy.WithTenant(context.Background(), acme.ID))
+	invitations := NewInvitationService(acmeDB, NewLogMailer(&mail, "no-reply@example.com"))
+
+	// Invitar no da acceso ni revela si el email tiene cuenta
+	if _, err := invitations.InviteMember(acme.ID, "nadie@example.com", role.ID, 1); err != nil {
+		t.Fatalf("InviteMember a un email sin cuenta: %v", err)
+	}
+	if _, err := invitations.InviteMember(acme.ID, ana.Email, role.ID, 1); err != nil {
+		t.Fatalf("InviteMember: %v", err)
+	}
+	token := lastInviteToken(t, &mail)
+	var count int64
+	if err := unscoped.Model(&models.Membership{}).Count(&count).Error; err != nil || count != 0 {
+		t.Fatalf("la invitación creó %d membresías (%v)", count, err)
+	}
+
+	accepting := NewInvitationService(db, nil)
+	if _, err := accepting.AcceptMembership(token, &eve); !isInvalidInvitation(err) {
+		t.Errorf("AcceptMembership con otro usuario = %v, se esperaba la invitación inválida", err)
+	}
+	if _, err := NewInvitationService(unscoped, nil).Accept(token, ana.Email); !isInvalidInvitation(err) {
+		t.Errorf("registrarse con una invitación a una organización = %v", err)
+	}
+
+	membership, err := accepting.AcceptMembership(token, &ana)
+	if err != nil {
+		t.Fatalf("AcceptMembership: %v", err)
+	}
+	if membership.OrganizationID != acme.ID || membership.UserID != ana.ID || membership.Role.ID != role.ID || membership.Organization.Slug != "acme" {
+		t.Errorf("membresía = %+v", membership)
+	}
+	if _, err := accepting.AcceptMembership(token, &ana); !isInvalidInvitation(err) {
+		t.Errorf("reutilizar la invitación = %v", err)
+	}
+
+	var apiErr *APIError
+	if _, err := invitations.InviteMember(acme.ID, ana.Email, role.ID, 1); !errors.As(err, &apiErr) || apiErr.Code != "INVALID_INPUT" {
+		t.Errorf("invitar a un miembro = %v, se esperaba INVALID_INPUT", err)
+	}
+}
+
+func isInvalidInvitation(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Detail == ErrInvalidInvitation().Detail
+}
+
+func TestRevokeForOrganizationKeepsOtherSessions(t *testing.T) {
+	db := tenancy.Unscoped(newTestDB(t))
+	sessions := NewSessionService(db, NewCacheService(time.Minute, 0))
+
+	home, err := sessions.Create(1, "navegador", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	member, err := sessions.Create(1, "navegador", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := sessions.SetOrganization(member.ID, 2); err != nil {
+		t.Fatalf("SetOrganization: %v", err)
+	}
+
+	if err := sessions.RevokeForOrganization(1, 2); err != nil {
+		t.Fatalf("RevokeForOrganization: %v", err)
+	}
+	if err := sessions.Validate(member.ID, 1, "127.0.0.1"); err == nil {
+		t.Error("la sesión en la organización sigue activa")
+	}
+	if err := sessions.Validate(home.ID, 1, "127.0.0.1"); err != nil {
+		t.Errorf("se cerró la sesión en la organización propia: %v", err)
+	}
+}
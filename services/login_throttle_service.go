@@ -0,0 +1,236 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go-api-orm/models"
+	"go-api-orm/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// loginThrottleMu serializa la lectura y escritura de los contadores entre peticiones concurrentes
+var loginThrottleMu sync.Mutex
+
+// LoginThrottleService limita los intentos de login fallidos por cuenta y por IP.
+// Los contadores viven en la caché y se persisten en la base de datos para sobrevivir reinicios.
+type LoginThrottleService struct {
+	db            *gorm.DB
+	cache         *CacheService
+	maxAttempts   int
+	ipMaxAttempts int
+	lockout       time.Duration
+	window        time.Duration
+	backoffBase   time.Duration
+	backoffMax    time.Duration
+}
+
+// NewLoginThrottleService crea una nueva instancia del servicio de protección de login
+func NewLoginThrottleService(db *gorm.DB, cache *CacheService) *LoginThrottleService {
+	return &LoginThrottleService{
+		db:            db,
+		cache:         cache,
+		maxAttempts:   utils.GetEnvInt("LOGIN_MAX_ATTEMPTS", 5),
+		ipMaxAttempts: utils.GetEnvInt("LOGIN_IP_MAX_ATTEMPTS", 20),
+		lockout:       time.Minute * time.Duration(utils.GetEnvInt("LOGIN_LOCKOUT_MINUTES", 15)),
+		window:        time.Minute * time.Duration(utils.GetEnvInt("LOGIN_ATTEMPT_WINDOW_MINUTES", 15)),
+		backoffBase:   time.Second * time.Duration(utils.GetEnvInt("LOGIN_BACKOFF_BASE_SECONDS", 1)),
+		backoffMax:    time.Second * time.Duration(utils.GetEnvInt("LOGIN_BACKOFF_MAX_SECONDS", 60)),
+	}
+}
+
+// ErrAccountLocked se retorna cuando la cuenta está bloqueada temporalmente
+var ErrAccountLocked = func(retryAfter time.Duration) *APIError {
+	return NewAPIError(
+		http.StatusLocked,
+		"ACCOUNT_LOCKED",
+		"Cuenta bloqueada temporalmente por demasiados intentos fallidos",
+		fmt.Sprintf("Intenta de nuevo en %d segundos", retrySeconds(retryAfter)),
+		nil,
+	)
+}
+
+// ErrTooManyLoginAttempts se retorna cuando se debe esperar antes de un nuevo intento
+var ErrTooManyLoginAttempts = func(retryAfter time.Duration) *APIError {
+	return NewAPIError(
+		http.StatusTooManyRequests,
+		"TOO_MANY_ATTEMPTS",
+		"Demasiados intentos de login",
+		fmt.Sprintf("Intenta de nuevo en %d segundos", retrySeconds(retryAfter)),
+		nil,
+	)
+}
+
+func retrySeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+func loginAccountKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func loginIPKey(ip string) string {
+	return "ip:" + ip
+}
+
+func loginAttemptCacheKey(key string) string {
+	return "login_attempts:" + key
+}
+
+// Check verifica si se permite un intento de login para el email y la IP.
+// Retorna el tiempo de espera restante junto con el error cuando el intento se rechaza.
+func (s *LoginThrottleService) Check(email, ip string) (time.Duration, error) {
+	loginThrottleMu.Lock()
+	defer loginThrottleMu.Unlock()
+
+	now := time.Now()
+
+	account, err := s.load(loginAccountKey(email))
+	if err != nil {
+		return 0, err
+	}
+	if account.IsLocked(now) {
+		retryAfter := account.LockedUntil.Sub(now)
+		return retryAfter, ErrAccountLocked(retryAfter)
+	}
+
+	client, err := s.load(loginIPKey(ip))
+	if err != nil {
+		return 0, err
+	}
+	if client.IsLocked(now) {
+		retryAfter := client.LockedUntil.Sub(now)
+		return retryAfter, ErrTooManyLoginAttempts(retryAfter)
+	}
+
+	// Backoff exponencial: cada fallo consecutivo duplica la espera mínima entre intentos
+	for _, attempt := range []*models.LoginAttempt{account, client} {
+		if retryAfter := s.backoffRemaining(attempt, now); retryAfter > 0 {
+			return retryAfter, ErrTooManyLoginAttempts(retryAfter)
+		}
+	}
+
+	return 0, nil
+}
+
+// RecordFailure registra un intento fallido y bloquea la cuenta o la IP al alcanzar el umbral
+func (s *LoginThrottleService) RecordFailure(email, ip string) error {
+	loginThrottleMu.Lock()
+	defer loginThrottleMu.Unlock()
+
+	if err := s.recordFailure(loginAccountKey(email), s.maxAttempts); err != nil {
+		return err
+	}
+	return s.recordFailure(loginIPKey(ip), s.ipMaxAttempts)
+}
+
+// RecordSuccess reinicia los contadores de la cuenta tras un login correcto.
+// Los de la IP se mantienen para que una cuenta válida no sirva para reiniciarlos.
+func (s *LoginThrottleService) RecordSuccess(email string) error {
+	return s.reset(loginAccountKey(email))
+}
+
+// Unlock elimina el bloqueo y los intentos fallidos de una cuenta
+func (s *LoginThrottleService) Unlock(email string) error {
+	return s.reset(loginAccountKey(email))
+}
+
+// PurgeStale elimina los registros cuyos intentos ya salieron de la ventana y no están bloqueados
+func (s *LoginThrottleService) PurgeStale() error {
+	now := time.Now()
+	return s.db.
+		Where("last_failed_at < ?", now.Add(-s.window)).
+		Where("locked_until IS NULL OR locked_until < ?", now).
+		Delete(&models.LoginAttempt{}).Error
+}
+
+func (s *LoginThrottleService) recordFailure(key string, threshold int) error {
+	attempt, err := s.load(key)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if attempt.LockedUntil != nil && !attempt.IsLocked(now) {
+		// El bloqueo anterior expiró: se empieza de nuevo
+		attempt.Failures = 0
+		attempt.LockedUntil = nil
+	}
+
+	attempt.Failures++
+	attempt.LastFailedAt = now
+	if threshold > 0 && attempt.Failures >= threshold {
+		lockedUntil := now.Add(s.lockout)
+		attempt.LockedUntil = &lockedUntil
+	}
+
+	if attempt.ID == 0 {
+		err = s.db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "identifier"}},
+			DoUpdates: clause.AssignmentColumns([]string{"failures", "last_failed_at", "locked_until", "updated_at"}),
+		}).Create(attempt).Error
+	} else {
+		err = s.db.Model(attempt).Select("failures", "last_failed_at", "locked_until").Updates(attempt).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	s.cache.Set(loginAttemptCacheKey(key), *attempt)
+	return nil
+}
+
+func (s *LoginThrottleService) reset(key string) error {
+	if err := s.db.Where("identifier = ?", key).Delete(&models.LoginAttempt{}).Error; err != nil {
+		return err
+	}
+
+	s.cache.Delete(loginAttemptCacheKey(key))
+	return nil
+}
+
+// load obtiene los intentos de la caché o, si no están, de la base de datos.
+// Los fallos fuera de la ventana se descartan.
+func (s *LoginThrottleService) load(key string) (*models.LoginAttempt, error) {
+	attempt, found := GetTyped[models.LoginAttempt](s.cache, loginAttemptCacheKey(key))
+	if !found {
+		if err := s.db.Where("identifier = ?", key).First(&attempt).Error; err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, err
+			}
+			attempt = models.LoginAttempt{Identifier: key}
+		}
+		s.cache.Set(loginAttemptCacheKey(key), attempt)
+	}
+
+	now := time.Now()
+	if !attempt.IsLocked(now) && attempt.Failures > 0 && now.Sub(attempt.LastFailedAt) > s.window {
+		attempt.Failures = 0
+		attempt.LockedUntil = nil
+	}
+
+	return &attempt, nil
+}
+
+func (s *LoginThrottleService) backoffRemaining(attempt *models.LoginAttempt, now time.Time) time.Duration {
+	if attempt.Failures == 0 || s.backoffBase <= 0 {
+		return 0
+	}
+
+	exponent := attempt.Failures - 1
+	if exponent > 20 {
+		exponent = 20
+	}
+	delay := s.backoffBase * time.Duration(1<<exponent)
+	if s.backoffMax > 0 && delay > s.backoffMax {
+		delay = s.backoffMax
+	}
+
+	return attempt.LastFailedAt.Add(delay).Sub(now)
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-api-orm/utils"
+)
+
+// MailMessage representa un correo de texto plano
+type MailMessage struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+// Mailer define cómo se envían los correos de la aplicación
+type Mailer interface {
+	Send(msg MailMessage) error
+}
+
+// NewMailerFromEnv crea el Mailer configurado en MAIL_DRIVER (log por defecto). Con MAIL_LOG_PATH
+// abre el archivo y lo mantiene abierto, así que se crea una sola vez al iniciar (config.InitMailer).
+func NewMailerFromEnv() (Mailer, error) {
+	from := utils.GetEnvString("MAIL_FROM", "no-reply@localhost")
+
+	switch strings.ToLower(utils.GetEnvString("MAIL_DRIVER", "log")) {
+	case "smtp":
+		return NewSMTPMailer(
+			os.Getenv("SMTP_HOST"),
+			utils.GetEnvInt("SMTP_PORT", 25),
+			os.Getenv("SMTP_USERNAME"),
+			os.Getenv("SMTP_PASSWORD"),
+			from,
+		), nil
+	case "log":
+		path := os.Getenv("MAIL_LOG_PATH")
+		if path == "" {
+			return NewLogMailer(os.Stdout, from), nil
+		}
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, err
+		}
+		return NewLogMailer(file, from), nil
+	}
+
+	return nil, fmt.Errorf("MAIL_DRIVER no soportado: %s", os.Getenv("MAIL_DRIVER"))
+}
+
+// LogMailer escribe los correos en un archivo o en la salida estándar (para desarrollo)
+type LogMailer struct {
+	mu     sync.Mutex
+	writer io.Writer
+	from   string
+}
+
+// NewLogMailer crea un Mailer que escribe los correos en writer
+func NewLogMailer(writer io.Writer, from string) *LogMailer {
+	return &LogMailer{
+		writer: writer,
+		from:   from,
+	}
+}
+
+// Send escribe el correo completo en el writer configurado
+func (m *LogMailer) Send(msg MailMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := m.writer.Write(append(buildMailMessage(m.from, msg), []byte("\r\n\r\n")...))
+	return err
+}
+
+// SMTPMailer envía los correos a través de un servidor SMTP
+type SMTPMailer struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+}
+
+// NewSMTPMailer crea un Mailer SMTP; si username está vacío no se usa autenticación
+func NewSMTPMailer(host string, port int, username, password, from string) *SMTPMailer {
+	return &SMTPMailer{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
+
+// Send envía el correo usando STARTTLS cuando el servidor lo soporta
+func (m *SMTPMailer) Send(msg MailMessage) error {
+	if m.host == "" {
+		return fmt.Errorf("SMTP_HOST no está configurado")
+	}
+
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+
+	addr := net.JoinHostPort(m.host, strconv.Itoa(m.port))
+	return smtp.SendMail(addr, auth, m.from, msg.To, buildMailMessage(m.from, msg))
+}
+
+// buildMailMessage construye el mensaje RFC 5322 con cabeceras y cuerpo en UTF-8
+func buildMailMessage(from string, msg MailMessage) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("From: " + from + "\r\n")
+	buf.WriteString("To: " + strings.Join(msg.To, ", ") + "\r\n")
+	buf.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	buf.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return buf.Bytes()
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"bufio"
+	"encoding/base64"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// smtpEnvelope es lo que recibió el servidor SMTP falso en una sesión
+type smtpEnvelope struct {
+	auth string
+	from string
+	to   []string
+	data string
+}
+
+// startFakeSMTP arranca un servidor SMTP mínimo en un puerto local que acepta una sesión
+// y envía su contenido por el canal retornado
+func startFakeSMTP(t *testing.T, advertiseAuth bool) (string, int, <-chan smtpEnvelope) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan smtpEnvelope, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		var envelope smtpEnvelope
+		reply("220 localhost fake SMTP")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			command := strings.ToUpper(line)
+
+			switch {
+			case strings.HasPrefix(command, "EHLO"):
+				if advertiseAuth {
+					reply("250-localhost")
+					reply("250 AUTH PLAIN")
+				} else {
+					reply("250 localhost")
+				}
+			case strings.HasPrefix(command, "AUTH PLAIN"):
+				envelope.auth = strings.TrimSpace(line[len("AUTH PLAIN"):])
+				reply("235 2.7.0 Authentication successful")
+			case strings.HasPrefix(command, "MAIL FROM:"):
+				envelope.from = strings.Trim(line[len("MAIL FROM:"):], "<> ")
+				reply("250 OK")
+			case strings.HasPrefix(command, "RCPT TO:"):
+				envelope.to = append(envelope.to, strings.Trim(line[len("RCPT TO:"):], "<> "))
+				reply("250 OK")
+			case command == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for {
+					dataLine, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if dataLine == ".\r\n" {
+						break
+					}
+					data.WriteString(dataLine)
+				}
+				envelope.data = data.String()
+				reply("250 OK: queued")
+			case command == "QUIT":
+				reply("221 Bye")
+				received <- envelope
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	host, portText, _ := net.SplitHostPort(listener.Addr().String())
+	port, _ := strconv.Atoi(portText)
+	return host, port, received
+}
+
+func TestSMTPMailerSendsEnvelopeAndBody(t *testing.T) {
+	host, port, received := startFakeSMTP(t, false)
+
+	mailer := NewSMTPMailer(host, port, "", "", "no-reply@example.com")
+	err := mailer.Send(MailMessage{
+		To:      []string{"ana@example.com", "luis@example.com"},
+		Subject: "Restablecer contraseña",
+		Body:    "Hola\nUsa este enlace",
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	envelope := <-received
+	if envelope.from != "no-reply@example.com" {
+		t.Errorf("MAIL FROM = %q", envelope.from)
+	}
+	if strings.Join(envelope.to, ",") != "ana@example.com,luis@example.com" {
+		t.Errorf("RCPT TO = %v", envelope.to)
+	}
+	if envelope.auth != "" {
+		t.Errorf("no se esperaba autenticación, se recibió %q", envelope.auth)
+	}
+
+	for _, header := range []string{
+		"From: no-reply@example.com\r\n",
+		"To: ana@example.com, luis@example.com\r\n",
+		"Subject: =?utf-8?q?Restablecer_contrase=C3=B1a?=\r\n",
+		"Content-Type: text/plain; charset=UTF-8\r\n",
+	} {
+		if !strings.Contains(envelope.data, header) {
+			t.Errorf("falta la cabecera %q en:\n%s", header, envelope.data)
+		}
+	}
+	if !strings.HasSuffix(envelope.data, "\r\n\r\nHola\r\nUsa este enlace\r\n") {
+		t.Errorf("cuerpo inesperado:\n%q", envelope.data)
+	}
+}
+
+func TestSMTPMailerAuthenticates(t *testing.T) {
+	host, port, received := startFakeSMTP(t, true)
+
+	mailer := NewSMTPMailer(host, port, "user", "secret", "no-reply@example.com")
+	if err := mailer.Send(MailMessage{To: []string{"ana@example.com"}, Subject: "Hola", Body: "Hola"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	envelope := <-received
+	credentials, err := base64.StdEncoding.DecodeString(envelope.auth)
+	if err != nil {
+		t.Fatalf("AUTH PLAIN inválido %q: %v", envelope.auth, err)
+	}
+	if string(credentials) != "\x00user\x00secret" {
+		t.Errorf("credenciales = %q", credentials)
+	}
+}
+
+func TestSMTPMailerRequiresHost(t *testing.T) {
+	if err := NewSMTPMailer("", 25, "", "", "no-reply@example.com").Send(MailMessage{To: []string{"ana@example.com"}}); err == nil {
+		t.Fatal("se esperaba un error sin SMTP_HOST")
+	}
+}
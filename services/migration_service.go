@@ -36,7 +36,7 @@ func (s *MigrationService) SeedDefaultRoles() error {
 
 	for _, role := range defaultRoles {
 		var existingRole models.Role
-		if err := s.db.Where("name = ?", role.Name).First(&existingRole).Error; err == gorm.ErrRecordNotFound {
+		if err := s.db.Where("name = ? AND tenant_id = 0", role.Name).First(&existingRole).Error; err == gorm.ErrRecordNotFound {
 			if err := s.db.Create(&role).Error; err != nil {
 				return err
 			}
@@ -0,0 +1,489 @@
+package services
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go-api-orm/models"
+	"go-api-orm/tenancy"
+	"go-api-orm/utils"
+	"gorm.io/gorm"
+)
+
+// oidcSigningMethods son los algoritmos aceptados en los id_token (nunca HMAC)
+var oidcSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+var oidcProviderName = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// OIDCProvider contiene la configuración de un proveedor de identidad (OIDC_<NOMBRE>_*)
+type OIDCProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// OIDCClaims son los datos de identidad extraídos de un id_token validado
+type OIDCClaims struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+}
+
+// oidcDiscovery es el subconjunto usado del documento /.well-known/openid-configuration
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcAuthState se guarda en caché entre la redirección al proveedor y el callback
+type oidcAuthState struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+}
+
+// OIDCService implementa el flujo authorization code con PKCE (RFC 7636)
+type OIDCService struct {
+	db       *gorm.DB
+	cache    *CacheService
+	client   *http.Client
+	stateTTL time.Duration
+}
+
+// NewOIDCService crea una nueva instancia del servicio de login con proveedores externos.
+// Como el login con contraseña, resuelve al usuario en cualquier organización.
+func NewOIDCService(db *gorm.DB, cache *CacheService) *OIDCService {
+	return &OIDCService{
+		db:       tenancy.Unscoped(db),
+		cache:    cache,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		stateTTL: time.Minute * time.Duration(utils.GetEnvInt("OIDC_STATE_EXPIRATION_MINUTES", 10)),
+	}
+}
+
+// ErrOIDCLoginFailed se retorna cuando el proveedor rechaza el login o su respuesta no es válida
+var ErrOIDCLoginFailed = func(detail string) *APIError {
+	return NewAPIError(
+		http.StatusUnauthorized,
+		"OIDC_LOGIN_FAILED",
+		"No se pudo iniciar sesión con el proveedor externo",
+		detail,
+		nil,
+	)
+}
+
+// ErrOIDCAccountConflict se retorna cuando existe una cuenta local con el email y el proveedor no lo verificó
+var ErrOIDCAccountConflict = func() *APIError {
+	return NewAPIError(
+		http.StatusConflict,
+		"ACCOUNT_EXISTS",
+		"Ya existe una cuenta con este email",
+		"El proveedor no verificó el email, por lo que no puede vincularse automáticamente",
+		nil,
+	)
+}
+
+// OIDCProviderNames retorna los proveedores habilitados en OIDC_PROVIDERS
+func OIDCProviderNames() []string {
+	var names []string
+	for _, name := range strings.Split(utils.GetEnvString("OIDC_PROVIDERS", ""), ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// LoadOIDCProvider obtiene la configuración de un proveedor habilitado
+func LoadOIDCProvider(name string) (*OIDCProvider, error) {
+	name = strings.ToLower(name)
+	enabled := false
+	for _, candidate := range OIDCProviderNames() {
+		if candidate == name {
+			enabled = true
+			break
+		}
+	}
+	if !enabled || !oidcProviderName.MatchString(name) {
+		return nil, ErrNotFound("Proveedor")
+	}
+
+	prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+	provider := &OIDCProvider{
+		Name:         name,
+		Issuer:       strings.TrimRight(utils.GetEnvString(prefix+"ISSUER", ""), "/"),
+		ClientID:     utils.GetEnvString(prefix+"CLIENT_ID", ""),
+		ClientSecret: utils.GetEnvString(prefix+"CLIENT_SECRET", ""),
+		RedirectURL: utils.GetEnvString(prefix+"REDIRECT_URL",
+			strings.TrimRight(utils.GetEnvString("APP_URL", "http://localhost:8080"), "/")+"/api/auth/oidc/"+name+"/callback"),
+		Scopes: strings.Fields(utils.GetEnvString(prefix+"SCOPES", "openid email profile")),
+	}
+	if provider.Issuer == "" || provider.ClientID == "" {
+		return nil, fmt.Errorf("%sISSUER y %sCLIENT_ID son requeridos", prefix, prefix)
+	}
+
+	return provider, nil
+}
+
+// AuthorizationURL genera la URL del proveedor a la que se redirige al usuario.
+// El state, el nonce y el code_verifier quedan en caché hasta el callback.
+func (s *OIDCService) AuthorizationURL(provider *OIDCProvider) (string, error) {
+	discovery, err := s.discover(provider)
+	if err != nil {
+		return "", err
+	}
+
+	state, err := randomURLString(32)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := randomURLString(32)
+	if err != nil {
+		return "", err
+	}
+	verifier, err := randomURLString(32)
+	if err != nil {
+		return "", err
+	}
+
+	s.cache.SetWithTTL(oidcStateKey(state), oidcAuthState{
+		Provider:     provider.Name,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+	}, s.stateTTL)
+
+	challenge := sha256.Sum256([]byte(verifier))
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", provider.ClientID)
+	query.Set("redirect_uri", provider.RedirectURL)
+	query.Set("scope", strings.Join(provider.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange valida el state, canjea el código por tokens y verifica el id_token
+func (s *OIDCService) Exchange(provider *OIDCProvider, code, state string) (*OIDCClaims, error) {
+	// El state es de un solo uso
+	authState, found := GetTyped[oidcAuthState](s.cache, oidcStateKey(state))
+	if state == "" || !found || authState.Provider != provider.Name {
+		return nil, ErrOIDCLoginFailed("state inválido o expirado")
+	}
+	s.cache.Delete(oidcStateKey(state))
+
+	discovery, err := s.discover(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", provider.RedirectURL)
+	form.Set("client_id", provider.ClientID)
+	form.Set("code_verifier", authState.CodeVerifier)
+
+	req, err := http.NewRequest(http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if provider.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(provider.ClientID), url.QueryEscape(provider.ClientSecret))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var tokens struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&tokens); err != nil {
+		return nil, ErrOIDCLoginFailed("respuesta inválida del endpoint de tokens")
+	}
+	if resp.StatusCode != http.StatusOK || tokens.IDToken == "" {
+		return nil, ErrOIDCLoginFailed(strings.TrimSpace(tokens.Error + " " + tokens.ErrorDescription))
+	}
+
+	return s.verifyIDToken(provider, discovery, tokens.IDToken, authState.Nonce)
+}
+
+// ResolveUser obtiene el usuario vinculado a la identidad externa. Si no existe, vincula
+// la cuenta con el mismo email verificado o crea un usuario nuevo con el rol por defecto.
+// Retorna true cuando el usuario fue creado.
+func (s *OIDCService) ResolveUser(provider *OIDCProvider, claims *OIDCClaims) (*models.User, bool, error) {
+	var user models.User
+	created := false
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var identity models.UserIdentity
+		err := tx.Where("provider = ? AND subject = ?", provider.Name, claims.Subject).First(&identity).Error
+		if err == nil {
+			if err := tx.Preload("Role").First(&user, identity.UserID).Error; err != nil {
+				return ErrOIDCLoginFailed("la cuenta vinculada ya no existe")
+			}
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if claims.Email == "" {
+			return ErrOIDCLoginFailed("el proveedor no entregó un email")
+		}
+
+		err = tx.Where("LOWER(email) = ?", strings.ToLower(claims.Email)).First(&user).Error
+		switch {
+		case err == nil:
+			// Vincular automáticamente solo si el proveedor verificó el email
+			if !claims.EmailVerified {
+				return ErrOIDCAccountConflict()
+			}
+			if !user.IsVerified() {
+				now := time.Now()
+				if err := tx.Model(&user).Update("verified_at", now).Error; err != nil {
+					return err
+				}
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := s.provisionUser(tx, &user, claims); err != nil {
+				return err
+			}
+			created = true
+		default:
+			return err
+		}
+
+		if err := tx.Create(&models.UserIdentity{
+			UserID:   user.ID,
+			Provider: provider.Name,
+			Subject:  claims.Subject,
+			Email:    claims.Email,
+		}).Error; err != nil {
+			return err
+		}
+
+		return tx.Preload("Role").First(&user, user.ID).Error
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &user, created, nil
+}
+
+// provisionUser crea un usuario en la organización por defecto con el rol por defecto, como Register.
+// La contraseña es aleatoria; el usuario puede asignar una con el flujo de restablecimiento.
+func (s *OIDCService) provisionUser(tx *gorm.DB, user *models.User, claims *OIDCClaims) error {
+	defaultRole, err := DefaultRole(tx)
+	if err != nil {
+		return err
+	}
+	organization, err := NewOrganizationService(tx, s.cache).Default()
+	if err != nil {
+		return err
+	}
+
+	username, err := s.uniqueUsername(tx, claims)
+	if err != nil {
+		return err
+	}
+
+	password, _, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		return err
+	}
+
+	*user = models.User{
+		Username: username,
+		Email:    claims.Email,
+		Password: password,
+		TenantID: organization.ID,
+		RoleID:   defaultRole.ID,
+	}
+	if claims.EmailVerified {
+		now := time.Now()
+		user.VerifiedAt = &now
+	}
+
+	return tx.Create(user).Error
+}
+
+// uniqueUsername deriva un nombre de usuario libre a partir de los claims del proveedor
+func (s *OIDCService) uniqueUsername(tx *gorm.DB, claims *OIDCClaims) (string, error) {
+	base := claims.PreferredUsername
+	if base == "" {
+		base = strings.SplitN(claims.Email, "@", 2)[0]
+	}
+	base = strings.TrimSpace(base)
+	if base == "" {
+		base = "user"
+	}
+
+	candidate := base
+	for i := 0; i < 5; i++ {
+		var count int64
+		if err := tx.Unscoped().Model(&models.User{}).Where("username = ?", candidate).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+
+		suffix, err := randomURLString(3)
+		if err != nil {
+			return "", err
+		}
+		candidate = base + "-" + strings.ToLower(suffix)
+	}
+
+	return "", errors.New("no se pudo generar un nombre de usuario único")
+}
+
+func (s *OIDCService) verifyIDToken(provider *OIDCProvider, discovery *oidcDiscovery, idToken, nonce string) (*OIDCClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return s.publicKey(discovery.JWKSURI, kid)
+	},
+		jwt.WithValidMethods(oidcSigningMethods),
+		jwt.WithIssuer(discovery.Issuer),
+		jwt.WithAudience(provider.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, ErrOIDCLoginFailed("id_token inválido")
+	}
+
+	if claimNonce, _ := claims["nonce"].(string); claimNonce != nonce {
+		return nil, ErrOIDCLoginFailed("nonce inválido")
+	}
+	if azp, ok := claims["azp"].(string); ok && azp != provider.ClientID {
+		return nil, ErrOIDCLoginFailed("azp inválido")
+	}
+
+	subject, _ := claims.GetSubject()
+	if subject == "" {
+		return nil, ErrOIDCLoginFailed("el id_token no tiene sub")
+	}
+
+	result := &OIDCClaims{Subject: subject}
+	result.Email, _ = claims["email"].(string)
+	result.Name, _ = claims["name"].(string)
+	result.PreferredUsername, _ = claims["preferred_username"].(string)
+	// Algunos proveedores envían email_verified como cadena
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		result.EmailVerified = verified
+	case string:
+		result.EmailVerified = verified == "true"
+	}
+
+	return result, nil
+}
+
+// discover obtiene (y cachea) el documento de descubrimiento del proveedor
+func (s *OIDCService) discover(provider *OIDCProvider) (*oidcDiscovery, error) {
+	cacheKey := "oidc_discovery:" + provider.Issuer
+	if discovery, found := GetTyped[oidcDiscovery](s.cache, cacheKey); found {
+		return &discovery, nil
+	}
+
+	var discovery oidcDiscovery
+	if err := s.getJSON(provider.Issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, err
+	}
+	if strings.TrimRight(discovery.Issuer, "/") != provider.Issuer {
+		return nil, fmt.Errorf("el issuer %q no coincide con el configurado %q", discovery.Issuer, provider.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, errors.New("documento de descubrimiento OIDC incompleto")
+	}
+
+	s.cache.SetWithTTL(cacheKey, discovery, time.Hour)
+	return &discovery, nil
+}
+
+// publicKey busca la clave del kid en el JWKS del proveedor, recargándolo si no la encuentra
+// (el proveedor pudo haber rotado sus claves)
+func (s *OIDCService) publicKey(jwksURI, kid string) (crypto.PublicKey, error) {
+	cacheKey := "oidc_jwks:" + jwksURI
+	set, found := GetTyped[utils.JWKSet](s.cache, cacheKey)
+
+	for attempt := 0; attempt < 2; attempt++ {
+		if !found || attempt > 0 {
+			set = utils.JWKSet{}
+			if err := s.getJSON(jwksURI, &set); err != nil {
+				return nil, err
+			}
+			s.cache.SetWithTTL(cacheKey, set, time.Hour)
+		}
+
+		for _, key := range set.Keys {
+			if key.Kid == kid || (kid == "" && len(set.Keys) == 1) {
+				return key.PublicKey()
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("clave %q no encontrada en el JWKS", kid)
+}
+
+func (s *OIDCService) getJSON(endpoint string, target interface{}) error {
+	resp, err := s.client.Get(endpoint)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", endpoint, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(target)
+}
+
+func oidcStateKey(state string) string {
+	return "oidc_state:" + state
+}
+
+func randomURLString(size int) (string, error) {
+	bytes := make([]byte, size)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
@@ -0,0 +1,289 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go-api-orm/models"
+	"go-api-orm/tools/mock_oidc/provider"
+)
+
+// oidcTestEnv conecta OIDCService con el proveedor de tools/mock_oidc servido con httptest
+type oidcTestEnv struct {
+	service  *OIDCService
+	provider *OIDCProvider
+
+	// claims modifica el próximo id_token que emite el proveedor
+	claims func(jwt.MapClaims)
+}
+
+func newOIDCTestEnv(t *testing.T, email string, emailVerified bool) *oidcTestEnv {
+	t.Helper()
+
+	env := &oidcTestEnv{}
+	var handler http.Handler
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	mock, err := provider.New(provider.Config{
+		Issuer:        server.URL,
+		ClientID:      "api",
+		ClientSecret:  "secreto",
+		Subject:       "mock-user-1",
+		Email:         email,
+		EmailVerified: emailVerified,
+		Claims: func(claims jwt.MapClaims) {
+			if env.claims != nil {
+				env.claims(claims)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("mock provider: %v", err)
+	}
+	handler = mock
+
+	cache := NewCacheService(time.Minute, 0)
+	env.service = NewOIDCService(newTestDB(t), cache)
+	env.provider = &OIDCProvider{
+		Name:         "mock",
+		Issuer:       server.URL,
+		ClientID:     "api",
+		ClientSecret: "secreto",
+		RedirectURL:  "http://app.test/api/auth/oidc/mock/callback",
+		Scopes:       []string{"openid", "email", "profile"},
+	}
+	return env
+}
+
+// authorize sigue la redirección al proveedor y retorna el código y el state del callback
+func (env *oidcTestEnv) authorize(t *testing.T) (code, state string) {
+	t.Helper()
+
+	authURL, err := env.service.AuthorizationURL(env.provider)
+	if err != nil {
+		t.Fatalf("AuthorizationURL: %v", err)
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("GET authorize: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize respondió %d", resp.StatusCode)
+	}
+
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("Location inválida: %v", err)
+	}
+	return callback.Query().Get("code"), callback.Query().Get("state")
+}
+
+// login ejecuta el flujo completo hasta obtener los claims del id_token
+func (env *oidcTestEnv) login(t *testing.T) (*OIDCClaims, error) {
+	t.Helper()
+	code, state := env.authorize(t)
+	return env.service.Exchange(env.provider, code, state)
+}
+
+func requireAPIError(t *testing.T, err error, code string) {
+	t.Helper()
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != code {
+		t.Fatalf("se esperaba el error %s, se obtuvo %v", code, err)
+	}
+}
+
+func TestOIDCAuthorizationURLUsesPKCE(t *testing.T) {
+	env := newOIDCTestEnv(t, "ana@example.com", true)
+
+	authURL, err := env.service.AuthorizationURL(env.provider)
+	if err != nil {
+		t.Fatalf("AuthorizationURL: %v", err)
+	}
+	parsed, _ := url.Parse(authURL)
+	query := parsed.Query()
+
+	state, found := GetTyped[oidcAuthState](env.service.cache, oidcStateKey(query.Get("state")))
+	if !found {
+		t.Fatal("el state no quedó en caché")
+	}
+	challenge := sha256.Sum256([]byte(state.CodeVerifier))
+	if query.Get("code_challenge_method") != "S256" ||
+		query.Get("code_challenge") != base64.RawURLEncoding.EncodeToString(challenge[:]) {
+		t.Errorf("code_challenge inválido: %v", query)
+	}
+	if query.Get("nonce") == "" || query.Get("nonce") != state.Nonce {
+		t.Errorf("nonce = %q, en caché %q", query.Get("nonce"), state.Nonce)
+	}
+	if query.Get("redirect_uri") != env.provider.RedirectURL || query.Get("client_id") != "api" {
+		t.Errorf("parámetros inesperados: %v", query)
+	}
+}
+
+func TestOIDCExchangeReturnsVerifiedClaims(t *testing.T) {
+	env := newOIDCTestEnv(t, "ana@example.com", true)
+
+	code, state := env.authorize(t)
+	claims, err := env.service.Exchange(env.provider, code, state)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if claims.Subject != "mock-user-1" || claims.Email != "ana@example.com" || !claims.EmailVerified {
+		t.Errorf("claims inesperados: %+v", claims)
+	}
+
+	// El state es de un solo uso
+	_, err = env.service.Exchange(env.provider, code, state)
+	requireAPIError(t, err, "OIDC_LOGIN_FAILED")
+}
+
+func TestOIDCExchangeRejectsInvalidState(t *testing.T) {
+	env := newOIDCTestEnv(t, "ana@example.com", true)
+
+	code, _ := env.authorize(t)
+	_, err := env.service.Exchange(env.provider, code, "desconocido")
+	requireAPIError(t, err, "OIDC_LOGIN_FAILED")
+
+	// Un state emitido para otro proveedor no sirve
+	code, state := env.authorize(t)
+	other := *env.provider
+	other.Name = "otro"
+	_, err = env.service.Exchange(&other, code, state)
+	requireAPIError(t, err, "OIDC_LOGIN_FAILED")
+}
+
+func TestOIDCExchangeRequiresCodeVerifier(t *testing.T) {
+	env := newOIDCTestEnv(t, "ana@example.com", true)
+
+	code, state := env.authorize(t)
+	authState, _ := GetTyped[oidcAuthState](env.service.cache, oidcStateKey(state))
+	authState.CodeVerifier = "otro-verificador"
+	env.service.cache.SetWithTTL(oidcStateKey(state), authState, time.Minute)
+
+	_, err := env.service.Exchange(env.provider, code, state)
+	requireAPIError(t, err, "OIDC_LOGIN_FAILED")
+}
+
+func TestOIDCExchangeValidatesIDToken(t *testing.T) {
+	cases := map[string]func(jwt.MapClaims){
+		"nonce":    func(claims jwt.MapClaims) { claims["nonce"] = "otro" },
+		"audience": func(claims jwt.MapClaims) { claims["aud"] = "otro-cliente" },
+		"issuer":   func(claims jwt.MapClaims) { claims["iss"] = "https://otro.example.com" },
+		"expired":  func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"no exp":   func(claims jwt.MapClaims) { delete(claims, "exp") },
+		"azp":      func(claims jwt.MapClaims) { claims["azp"] = "otro-cliente" },
+		"no sub":   func(claims jwt.MapClaims) { delete(claims, "sub") },
+	}
+
+	for name, mutate := range cases {
+		t.Run(name, func(t *testing.T) {
+			env := newOIDCTestEnv(t, "ana@example.com", true)
+			env.claims = mutate
+
+			_, err := env.login(t)
+			requireAPIError(t, err, "OIDC_LOGIN_FAILED")
+		})
+	}
+}
+
+func TestOIDCResolveUserLinksVerifiedEmail(t *testing.T) {
+	env := newOIDCTestEnv(t, "Ana@Example.com", true)
+
+	role, err := DefaultRole(env.service.db)
+	if err != nil {
+		t.Fatalf("DefaultRole: %v", err)
+	}
+	existing := models.User{Username: "ana", Email: "ana@example.com", Password: "Secreta123!", RoleID: role.ID}
+	if err := env.service.db.Create(&existing).Error; err != nil {
+		t.Fatalf("crear usuario: %v", err)
+	}
+
+	claims, err := env.login(t)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	user, created, err := env.service.ResolveUser(env.provider, claims)
+	if err != nil {
+		t.Fatalf("ResolveUser: %v", err)
+	}
+	if created || user.ID != existing.ID {
+		t.Fatalf("se esperaba vincular el usuario %d, se obtuvo %d (creado %v)", existing.ID, user.ID, created)
+	}
+	if !user.IsVerified() {
+		t.Error("el email verificado por el proveedor debería marcar la cuenta como verificada")
+	}
+
+	var identity models.UserIdentity
+	if err := env.service.db.Where("provider = ? AND subject = ?", "mock", "mock-user-1").First(&identity).Error; err != nil {
+		t.Fatalf("no se creó la identidad: %v", err)
+	}
+	if identity.UserID != existing.ID {
+		t.Errorf("identidad vinculada al usuario %d", identity.UserID)
+	}
+
+	// Los siguientes logins usan la identidad vinculada
+	claims, err = env.login(t)
+	if err != nil {
+		t.Fatalf("segundo login: %v", err)
+	}
+	again, created, err := env.service.ResolveUser(env.provider, claims)
+	if err != nil || created || again.ID != existing.ID {
+		t.Fatalf("segundo ResolveUser = %v, %v, %v", again, created, err)
+	}
+}
+
+func TestOIDCResolveUserRejectsUnverifiedEmail(t *testing.T) {
+	env := newOIDCTestEnv(t, "ana@example.com", false)
+
+	role, _ := DefaultRole(env.service.db)
+	if err := env.service.db.Create(&models.User{Username: "ana", Email: "ana@example.com", Password: "Secreta123!", RoleID: role.ID}).Error; err != nil {
+		t.Fatalf("crear usuario: %v", err)
+	}
+
+	claims, err := env.login(t)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	_, _, err = env.service.ResolveUser(env.provider, claims)
+	requireAPIError(t, err, "ACCOUNT_EXISTS")
+
+	var count int64
+	env.service.db.Model(&models.UserIdentity{}).Count(&count)
+	if count != 0 {
+		t.Errorf("no debería vincularse la identidad, hay %d", count)
+	}
+}
+
+func TestOIDCResolveUserProvisionsNewUser(t *testing.T) {
+	env := newOIDCTestEnv(t, "nuevo@example.com", true)
+
+	claims, err := env.login(t)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	user, created, err := env.service.ResolveUser(env.provider, claims)
+	if err != nil {
+		t.Fatalf("ResolveUser: %v", err)
+	}
+	if !created || user.Email != "nuevo@example.com" || user.Username != "nuevo" || !user.IsVerified() {
+		t.Errorf("usuario inesperado: %+v (creado %v)", user, created)
+	}
+	if user.Role.Name != DefaultRoleName() {
+		t.Errorf("rol = %q, se esperaba %q", user.Role.Name, DefaultRoleName())
+	}
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"go-api-orm/models"
+	"go-api-orm/tenancy"
+	"gorm.io/gorm"
+)
+
+// OrganizationService administra las organizaciones (tenants) y las membresías de los usuarios.
+// Cada usuario pertenece a su organización (users.tenant_id), donde tiene su rol, y puede
+// acceder a otras organizaciones mediante una membresía con un rol propio en cada una.
+type OrganizationService struct {
+	db    *gorm.DB
+	cache *CacheService
+}
+
+// NewOrganizationService crea una nueva instancia del servicio de organizaciones
+func NewOrganizationService(db *gorm.DB, cache *CacheService) *OrganizationService {
+	return &OrganizationService{db: db, cache: cache}
+}
+
+// OrganizationAccess es una organización a la que el usuario tiene acceso y su rol en ella
+type OrganizationAccess struct {
+	Organization models.Organization `json:"organization"`
+	Role         string              `json:"role"`
+	Home         bool                `json:"home"` // organización propia del usuario
+}
+
+// ErrNotMember se retorna cuando el usuario no tiene acceso a la organización
+var ErrNotMember = func() *APIError {
+	return NewAPIError(
+		http.StatusForbidden,
+		"NOT_A_MEMBER",
+		"No perteneces a esta organización",
+		"",
+		nil,
+	)
+}
+
+func organizationSlugKey(slug string) string {
+	return fmt.Sprintf("organization:%s", slug)
+}
+
+// FindBySlug retorna la organización con el slug indicado
+func (s *OrganizationService) FindBySlug(slug string) (*models.Organization, error) {
+	if organization, found := GetTyped[models.Organization](s.cache, organizationSlugKey(slug)); found {
+		return &organization, nil
+	}
+
+	var organization models.Organization
+	if err := s.db.Where("slug = ?", slug).First(&organization).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound("Organización")
+		}
+		return nil, err
+	}
+
+	s.cache.Set(organizationSlugKey(slug), organization)
+	return &organization, nil
+}
+
+// Default retorna la organización por defecto
+func (s *OrganizationService) Default() (*models.Organization, error) {
+	return s.FindBySlug(models.DefaultOrganizationSlug)
+}
+
+// Create crea una organización. El creador recibe el rol admin en ella mediante una membresía.
+func (s *OrganizationService) Create(name, slug string, creatorID uint) (*models.Organization, error) {
+	var existing int64
+	if err := s.db.Model(&models.Organization{}).Where("slug = ?", slug).Count(&existing).Error; err != nil {
+		return nil, err
+	}
+	if existing > 0 {
+		return nil, ErrInvalidInput("Ya existe una organización con ese slug")
+	}
+
+	var admin models.Role
+	if err := tenancy.Unscoped(s.db).Where("name = ? AND tenant_id = 0", AdminRole).First(&admin).Error; err != nil {
+		return nil, err
+	}
+
+	organization := models.Organization{Name: name, Slug: slug}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&organization).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.Membership{
+			OrganizationID: organization.ID,
+			UserID:         creatorID,
+			RoleID:         admin.ID,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &organization, nil
+}
+
+// Access retorna el rol del usuario en la organización: su rol en la organización propia o
+// el de su membresía. El usuario debe tener su rol precargado.
+func (s *OrganizationService) Access(user *models.User, organizationID uint) (*models.Role, error) {
+	if organizationID == user.TenantID {
+		return &user.Role, nil
+	}
+
+	var membership models.Membership
+	err := tenancy.Unscoped(s.db).Preload("Role").
+		Where("organization_id = ? AND user_id = ?", organizationID, user.ID).
+		First(&membership).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotMember()
+		}
+		return nil, err
+	}
+	return &membership.Role, nil
+}
+
+// ForUser retorna las organizaciones a las que el usuario tiene acceso, empezando por la suya.
+// El usuario debe tener su rol precargado.
+func (s *OrganizationService) ForUser(user *models.User) ([]OrganizationAccess, error) {
+	var home models.Organization
+	if err := s.db.First(&home, user.TenantID).Error; err != nil {
+		return nil, err
+	}
+	organizations := []OrganizationAccess{{Organization: home, Role: user.Role.Name, Home: true}}
+
+	var memberships []models.Membership
+	if err := tenancy.Unscoped(s.db).Preload("Organization").Preload("Role").Where("user_id = ?", user.ID).Find(&memberships).Error; err != nil {
+		return nil, err
+	}
+	for _, membership := range memberships {
+		organizations = append(organizations, OrganizationAccess{
+			Organization: membership.Organization,
+			Role:         membership.Role.Name,
+		})
+	}
+	return organizations, nil
+}
+
+// Members retorna las membresías de la organización (usuarios de otras organizaciones con acceso)
+func (s *OrganizationService) Members(organizationID uint) ([]models.Membership, error) {
+	var memberships []models.Membership
+	err := tenancy.Unscoped(s.db).Preload("User").Preload("Role").
+		Where("organization_id = ?", organizationID).
+		Order("id").
+		Find(&memberships).Error
+	return memberships, err
+}
+
+// UpdateMemberRole cambia el rol de un miembro de la organización. El rol debe ser global o de la
+// organización. Para dar acceso a un usuario nuevo se le invita (InvitationService.InviteMember).
+// Falla con ErrLastAdmin si el miembro es el último administrador y el nuevo rol no lo es.
+func (s *OrganizationService) UpdateMemberRole(organizationID, userID, roleID uint) (*models.Membership, error) {
+	var membership models.Membership
+	err := tenancy.Unscoped(s.db).Transaction(func(tx *gorm.DB) error {
+		var role models.Role
+		if err := tx.Where("id = ? AND tenant_id IN ?", roleID, []uint{0, organizationID}).First(&role).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidRole()
+			}
+			return err
+		}
+
+		if err := tx.Preload("User").Where("organization_id = ? AND user_id = ?", organizationID, userID).First(&membership).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound("Miembro")
+			}
+			return err
+		}
+
+		roles := NewRoleService(tx, s.cache)
+		wasAdmin, err := roles.InheritsFrom(membership.RoleID, AdminRole)
+		if err != nil {
+			return err
+		}
+		isAdmin, err := roles.InheritsFrom(role.ID, AdminRole)
+		if err != nil {
+			return err
+		}
+		if wasAdmin && !isAdmin {
+			if err := roles.EnsureAnotherAdmin(organizationID, userID); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&models.Membership{}).Where("id = ?", membership.ID).Update("role_id", role.ID).Error; err != nil {
+			return err
+		}
+		membership.RoleID = role.ID
+		membership.Role = role
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &membership, nil
+}
+
+// RemoveMember quita el acceso del usuario a la organización. Falla con ErrLastAdmin si es su
+// último administrador.
+func (s *OrganizationService) RemoveMember(organizationID, userID uint) error {
+	return tenancy.Unscoped(s.db).Transaction(func(tx *gorm.DB) error {
+		var membership models.Membership
+		if err := tx.Where("organization_id = ? AND user_id = ?", organizationID, userID).First(&membership).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound("Miembro")
+			}
+			return err
+		}
+
+		roles := NewRoleService(tx, s.cache)
+		isAdmin, err := roles.InheritsFrom(membership.RoleID, AdminRole)
+		if err != nil {
+			return err
+		}
+		if isAdmin {
+			if err := roles.EnsureAnotherAdmin(organizationID, userID); err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&membership).Error
+	})
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-api-orm/models"
+	"go-api-orm/tenancy"
+)
+
+func isLastAdmin(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == "LAST_ADMIN"
+}
+
+func TestOrganizationKeepsAnAdministrator(t *testing.T) {
+	db := newTestDB(t)
+	unscoped := tenancy.Unscoped(db)
+	cache := NewCacheService(time.Minute, 0)
+	organizations := NewOrganizationService(unscoped, cache)
+
+	home, err := organizations.Default()
+	if err != nil {
+		t.Fatalf("Default: %v", err)
+	}
+	member, err := DefaultRole(unscoped)
+	if err != nil {
+		t.Fatalf("DefaultRole: %v", err)
+	}
+	var admin models.Role
+	if err := unscoped.Where("name = ? AND tenant_id = 0", AdminRole).First(&admin).Error; err != nil {
+		t.Fatalf("rol admin: %v", err)
+	}
+	ana := models.User{Username: "ana", Email: "ana@example.com", Password: "Secreta123!", RoleID: member.ID, TenantID: home.ID}
+	bob := models.User{Username: "bob", Email: "bob@example.com", Password: "Secreta123!", RoleID: member.ID, TenantID: home.ID}
+	for _, user := range []*models.User{&ana, &bob} {
+		if err := unscoped.Create(user).Error; err != nil {
+			t.Fatalf("crear usuario: %v", err)
+		}
+	}
+
+	// La creadora es la única administradora de acme, a través de su membresía
+	acme, err := organizations.Create("Acme", "acme", ana.ID)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := organizations.UpdateMemberRole(acme.ID, ana.ID, member.ID); !isLastAdmin(err) {
+		t.Errorf("degradar a la última administradora = %v, se esperaba LAST_ADMIN", err)
+	}
+	if err := organizations.RemoveMember(acme.ID, ana.ID); !isLastAdmin(err) {
+		t.Errorf("quitar a la última administradora = %v, se esperaba LAST_ADMIN", err)
+	}
+
+	// Con otro administrador miembro sí se puede degradar a la creadora
+	if err := unscoped.Create(&models.Membership{OrganizationID: acme.ID, UserID: bob.ID, RoleID: member.ID}).Error; err != nil {
+		t.Fatalf("crear membresía: %v", err)
+	}
+	if _, err := organizations.UpdateMemberRole(acme.ID, bob.ID, admin.ID); err != nil {
+		t.Fatalf("UpdateMemberRole: %v", err)
+	}
+	if _, err := organizations.UpdateMemberRole(acme.ID, ana.ID, member.ID); err != nil {
+		t.Errorf("degradar con otro administrador: %v", err)
+	}
+
+	// Los administradores miembros cuentan al cambiar el rol de un usuario propio de acme
+	carl := models.User{Username: "carl", Email: "carl@example.com", Password: "Secreta123!", RoleID: admin.ID, TenantID: acme.ID}
+	if err := unscoped.Create(&carl).Error; err != nil {
+		t.Fatalf("crear usuario: %v", err)
+	}
+	acmeDB := db.WithContext(tenancy.WithTenant(context.Background(), acme.ID))
+	if _, err := NewRoleService(acmeDB, cache).AssignToUser(carl.ID, member.ID); err != nil {
+		t.Errorf("AssignToUser con un administrador miembro: %v", err)
+	}
+	if err := organizations.RemoveMember(acme.ID, bob.ID); !isLastAdmin(err) {
+		t.Errorf("quitar al último administrador miembro = %v, se esperaba LAST_ADMIN", err)
+	}
+	if err := organizations.RemoveMember(acme.ID, ana.ID); err != nil {
+		t.Errorf("quitar a un miembro sin rol de administrador: %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"go-api-orm/models"
+	"go-api-orm/tenancy"
+	"go-api-orm/utils"
+	"gorm.io/gorm"
+)
+
+// PasswordResetService maneja la emisión y el consumo de tokens para restablecer contraseñas
+type PasswordResetService struct {
+	db     *gorm.DB
+	mailer Mailer
+	ttl    time.Duration
+}
+
+// NewPasswordResetService crea una nueva instancia del servicio de restablecimiento.
+// Se usa sin sesión, así que busca al usuario por su email en todas las organizaciones.
+func NewPasswordResetService(db *gorm.DB, mailer Mailer) *PasswordResetService {
+	return &PasswordResetService{
+		db:     tenancy.Unscoped(db),
+		mailer: mailer,
+		ttl:    time.Minute * time.Duration(utils.GetEnvInt("PASSWORD_RESET_EXPIRATION_MINUTES", 60)),
+	}
+}
+
+// ErrInvalidResetToken se retorna cuando el token no existe, expiró o ya fue usado
+var ErrInvalidResetToken = func() *APIError {
+	return ErrInvalidInput("El token de restablecimiento es inválido o ha expirado")
+}
+
+// RequestReset genera un token y lo envía por correo. Si el email no existe no hace nada,
+// para no revelar qué cuentas están registradas.
+func (s *PasswordResetService) RequestReset(email string) error {
+	var user models.User
+	if err := s.db.Where("email = ?", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	token, hash, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Solo el último token solicitado es válido
+		if err := tx.Where("user_id = ? AND used_at IS NULL", user.ID).Delete(&models.PasswordResetToken{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.PasswordResetToken{
+			UserID:    user.ID,
+			TokenHash: hash,
+			ExpiresAt: time.Now().Add(s.ttl),
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(MailMessage{
+		To:      []string{user.Email},
+		Subject: "Restablecer contraseña",
+		Body: fmt.Sprintf(
+			"Hola %s,\n\nPara restablecer tu contraseña visita el siguiente enlace:\n\n%s\n\nEl enlace expira en %d minutos. Si no solicitaste este cambio puedes ignorar este correo.\n",
+			user.Username,
+			s.resetURL(token),
+			int(s.ttl.Minutes()),
+		),
+	})
+}
+
+// ResetPassword consume el token y asigna la nueva contraseña al usuario
+func (s *PasswordResetService) ResetPassword(token, newPassword string) (*models.User, error) {
+	var user models.User
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var resetToken models.PasswordResetToken
+		if err := tx.Where("token_hash = ?", utils.HashToken(token)).First(&resetToken).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidResetToken()
+			}
+			return err
+		}
+
+		if resetToken.UsedAt != nil || time.Now().After(resetToken.ExpiresAt) {
+			return ErrInvalidResetToken()
+		}
+
+		// Marcar el token como usado de forma atómica para que sea de un solo uso
+		result := tx.Model(&models.PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL", resetToken.ID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidResetToken()
+		}
+
+		if err := tx.First(&user, resetToken.UserID).Error; err != nil {
+			return ErrInvalidResetToken()
+		}
+
+		// Si la contraseña no cumple la política la transacción se deshace y el token sigue siendo válido
+		return NewPasswordService(tx).SetPassword(tx, &user, newPassword)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (s *PasswordResetService) resetURL(token string) string {
+	base := utils.GetEnvString("PASSWORD_RESET_URL", strings.TrimRight(utils.GetEnvString("APP_URL", "http://localhost:8080"), "/")+"/reset-password")
+	separator := "?"
+	if strings.Contains(base, "?") {
+		separator = "&"
+	}
+	return base + separator + "token=" + url.QueryEscape(token)
+}
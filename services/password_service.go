@@ -0,0 +1,171 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"go-api-orm/models"
+	"go-api-orm/tenancy"
+	"go-api-orm/utils"
+	"gorm.io/gorm"
+)
+
+// PasswordService cambia la contraseña de los usuarios aplicando la política de contraseñas
+// y el historial que impide reutilizar las últimas contraseñas
+type PasswordService struct {
+	db         *gorm.DB
+	validation *ValidationService
+	policy     PasswordPolicy
+}
+
+// NewPasswordService crea una nueva instancia del servicio de contraseñas. Las contraseñas
+// son de la cuenta, común a todas las organizaciones del usuario.
+func NewPasswordService(db *gorm.DB) *PasswordService {
+	validation := NewValidationService()
+	return &PasswordService{
+		db:         tenancy.Unscoped(db),
+		validation: validation,
+		policy:     validation.passwordPolicy,
+	}
+}
+
+// ErrInvalidCurrentPassword se retorna cuando la contraseña actual no es correcta
+var ErrInvalidCurrentPassword = func() *APIError {
+	return NewAPIError(
+		http.StatusBadRequest,
+		"INVALID_CURRENT_PASSWORD",
+		"La contraseña actual no es correcta",
+		"",
+		nil,
+	)
+}
+
+// ErrPasswordReused se retorna cuando la nueva contraseña coincide con una de las últimas utilizadas
+var ErrPasswordReused = func(historySize int) *APIError {
+	return NewAPIError(
+		http.StatusBadRequest,
+		"PASSWORD_REUSED",
+		"La nueva contraseña no puede coincidir con una contraseña reciente",
+		fmt.Sprintf("No se puede reutilizar ninguna de las últimas %d contraseñas", historySize),
+		nil,
+	)
+}
+
+// ChangePassword verifica la contraseña actual del usuario y la reemplaza por la nueva
+func (s *PasswordService) ChangePassword(userID uint, currentPassword, newPassword string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.First(&user, userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound("Usuario")
+			}
+			return err
+		}
+
+		if err := user.CheckPassword(currentPassword); err != nil {
+			return ErrInvalidCurrentPassword()
+		}
+
+		return s.update(tx, &user, newPassword)
+	})
+}
+
+// Verify comprueba la contraseña del usuario y, si su hash usa un algoritmo o parámetros
+// anteriores, lo regenera con los actuales. Un fallo al regenerarlo no impide el login.
+func (s *PasswordService) Verify(user *models.User, password string) error {
+	needsRehash, err := user.VerifyPassword(password)
+	if err != nil || !needsRehash {
+		return err
+	}
+
+	if err := user.SetPassword(password); err != nil {
+		log.Printf("Error rehashing password for user %d: %v", user.ID, err)
+		return nil
+	}
+	if err := s.db.Model(user).UpdateColumn("password", user.Password).Error; err != nil {
+		log.Printf("Error rehashing password for user %d: %v", user.ID, err)
+	}
+	return nil
+}
+
+// SetPassword asigna una nueva contraseña al usuario dentro de la transacción indicada,
+// sin pedir la contraseña actual (p. ej. al restablecerla con un token)
+func (s *PasswordService) SetPassword(tx *gorm.DB, user *models.User, newPassword string) error {
+	return s.update(tx, user, newPassword)
+}
+
+// update valida la nueva contraseña, la guarda y mueve la anterior al historial
+func (s *PasswordService) update(tx *gorm.DB, user *models.User, newPassword string) error {
+	if valid, message := s.validation.ValidatePassword(newPassword); !valid {
+		return ErrInvalidInput(message)
+	}
+
+	reused, err := s.isReused(tx, user, newPassword)
+	if err != nil {
+		return err
+	}
+	if reused {
+		return ErrPasswordReused(s.policy.HistorySize)
+	}
+
+	previousHash := user.Password
+	if err := user.SetPassword(newPassword); err != nil {
+		return err
+	}
+	if err := tx.Model(user).Update("password", user.Password).Error; err != nil {
+		return err
+	}
+
+	// La contraseña actual cuenta como una de las últimas N, así que el historial guarda N-1
+	if s.policy.HistorySize <= 1 {
+		return nil
+	}
+	if err := tx.Create(&models.PasswordHistory{UserID: user.ID, PasswordHash: previousHash}).Error; err != nil {
+		return err
+	}
+	return s.trimHistory(tx, user.ID)
+}
+
+// isReused indica si la contraseña coincide con la actual o con alguna del historial
+func (s *PasswordService) isReused(tx *gorm.DB, user *models.User, password string) (bool, error) {
+	if s.policy.HistorySize <= 0 {
+		return false, nil
+	}
+	if user.CheckPassword(password) == nil {
+		return true, nil
+	}
+	if s.policy.HistorySize == 1 {
+		return false, nil
+	}
+
+	var history []models.PasswordHistory
+	if err := tx.Where("user_id = ?", user.ID).
+		Order("created_at DESC, id DESC").
+		Limit(s.policy.HistorySize - 1).
+		Find(&history).Error; err != nil {
+		return false, err
+	}
+
+	for _, entry := range history {
+		if _, err := utils.VerifyPassword(password, entry.PasswordHash); err == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// trimHistory elimina las entradas del historial que exceden el tamaño configurado
+func (s *PasswordService) trimHistory(tx *gorm.DB, userID uint) error {
+	var keep []uint
+	if err := tx.Model(&models.PasswordHistory{}).
+		Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").
+		Limit(s.policy.HistorySize - 1).
+		Pluck("id", &keep).Error; err != nil {
+		return err
+	}
+
+	return tx.Where("user_id = ? AND id NOT IN ?", userID, keep).Delete(&models.PasswordHistory{}).Error
+}
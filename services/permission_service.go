@@ -0,0 +1,176 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"go-api-orm/models"
+	"gorm.io/gorm"
+)
+
+// PermissionService resuelve y administra los permisos asignados a cada rol.
+// Los permisos de un rol se cachean por su ID, que es el que viaja en el JWT: los nombres
+// solo son únicos dentro de cada organización.
+type PermissionService struct {
+	db    *gorm.DB
+	cache *CacheService
+}
+
+// NewPermissionService crea una nueva instancia del servicio de permisos
+func NewPermissionService(db *gorm.DB, cache *CacheService) *PermissionService {
+	return &PermissionService{db: db, cache: cache}
+}
+
+// ErrMissingPermission se retorna cuando el rol del usuario no tiene el permiso requerido
+var ErrMissingPermission = func(permission string) *APIError {
+	return NewAPIError(
+		http.StatusForbidden,
+		"MISSING_PERMISSION",
+		"No tienes permisos para realizar esta operación",
+		fmt.Sprintf("Se requiere el permiso %s", permission),
+		nil,
+	)
+}
+
+func rolePermissionsKey(roleID uint) string {
+	return fmt.Sprintf("role_permissions:%d", roleID)
+}
+
+// List retorna todos los permisos disponibles
+func (s *PermissionService) List() ([]models.Permission, error) {
+	var permissions []models.Permission
+	err := s.db.Order("name").Find(&permissions).Error
+	return permissions, err
+}
+
+// RolePermissions retorna los nombres de los permisos efectivos del rol indicado:
+// los asignados al propio rol y los heredados de sus ancestros
+func (s *PermissionService) RolePermissions(roleID uint) ([]string, error) {
+	if names, found := GetTyped[[]string](s.cache, rolePermissionsKey(roleID)); found {
+		return names, nil
+	}
+
+	lineage, err := NewRoleService(s.db, s.cache).Lineage(roleID)
+	if err != nil {
+		return nil, err
+	}
+	roleIDs := make([]uint, 0, len(lineage))
+	for _, role := range lineage {
+		roleIDs = append(roleIDs, role.ID)
+	}
+
+	names := []string{}
+	if len(roleIDs) > 0 {
+		err = s.db.Model(&models.Permission{}).
+			Distinct("permissions.name").
+			Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+			Where("role_permissions.role_id IN ?", roleIDs).
+			Order("permissions.name").
+			Pluck("permissions.name", &names).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.cache.Set(rolePermissionsKey(roleID), names)
+	return names, nil
+}
+
+// HasPermission indica si el rol indicado tiene el permiso
+func (s *PermissionService) HasPermission(roleID uint, permission string) (bool, error) {
+	names, err := s.RolePermissions(roleID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, name := range names {
+		if name == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Grant asigna los permisos indicados al rol. Todos los permisos deben existir.
+func (s *PermissionService) Grant(roleID uint, names []string) (*models.Role, error) {
+	role, err := s.findRole(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := s.findPermissions(names)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(role).Association("Permissions").Append(permissions); err != nil {
+		return nil, err
+	}
+
+	s.Invalidate(role.ID)
+	return s.findRole(roleID)
+}
+
+// Revoke quita un permiso al rol
+func (s *PermissionService) Revoke(roleID uint, name string) (*models.Role, error) {
+	role, err := s.findRole(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := s.findPermissions([]string{name})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(role).Association("Permissions").Delete(permissions); err != nil {
+		return nil, err
+	}
+
+	s.Invalidate(role.ID)
+	return s.findRole(roleID)
+}
+
+// Invalidate descarta los permisos cacheados de un rol y de los roles que heredan de él
+// (p. ej. al cambiar su padre, eliminarlo o modificar sus permisos)
+func (s *PermissionService) Invalidate(roleID uint) {
+	if err := NewRoleService(s.db, s.cache).Invalidate(roleID); err != nil {
+		log.Printf("Error invalidating permissions of role %d: %v", roleID, err)
+	}
+}
+
+func (s *PermissionService) findRole(roleID uint) (*models.Role, error) {
+	var role models.Role
+	if err := s.db.Preload("Permissions").First(&role, roleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound("Role")
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (s *PermissionService) findPermissions(names []string) ([]models.Permission, error) {
+	if len(names) == 0 {
+		return nil, ErrInvalidInput("Debes indicar al menos un permiso")
+	}
+
+	var permissions []models.Permission
+	if err := s.db.Where("name IN ?", names).Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool, len(permissions))
+	for _, permission := range permissions {
+		found[permission.Name] = true
+	}
+	for _, name := range names {
+		if !found[name] {
+			return nil, ErrInvalidInput(fmt.Sprintf("El permiso %s no existe", name))
+		}
+	}
+
+	return permissions, nil
+}
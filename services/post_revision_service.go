@@ -0,0 +1,468 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-api-orm/models"
+	"go-api-orm/tenancy"
+	"go-api-orm/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Operaciones de una línea del diff entre dos revisiones
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+// DiffLine es una línea del contenido que se mantiene, se añade o se elimina entre dos revisiones
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// FieldChange es el valor anterior y el nuevo de un campo que cambió entre dos revisiones
+type FieldChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// RevisionDiff es la diferencia entre dos revisiones de un post
+type RevisionDiff struct {
+	From    uint         `json:"from"`
+	To      uint         `json:"to"`
+	Title   *FieldChange `json:"title,omitempty"`
+	Slug    *FieldChange `json:"slug,omitempty"`
+	Content []DiffLine   `json:"content"`
+}
+
+// PostRevisionService guarda el historial de cambios de los posts y permite compararlo y restaurarlo
+type PostRevisionService struct {
+	db        *gorm.DB
+	retention int
+}
+
+// NewPostRevisionService crea una nueva instancia del servicio de revisiones.
+// POST_REVISION_RETENTION limita las revisiones que se conservan por post (0 = todas).
+func NewPostRevisionService(db *gorm.DB) *PostRevisionService {
+	return &PostRevisionService{
+		db:        db,
+		retention: utils.GetEnvInt("POST_REVISION_RETENTION", 0),
+	}
+}
+
+// ErrRevisionNotFound se retorna cuando el post no tiene la revisión solicitada
+var ErrRevisionNotFound = func() *APIError {
+	return NewAPIError(
+		http.StatusNotFound,
+		"REVISION_NOT_FOUND",
+		"Revisión no encontrada",
+		"",
+		nil,
+	)
+}
+
+// EnsureInitial guarda el estado actual del post como primera revisión si aún no tiene historial,
+// como ocurre con los posts creados antes de existir las revisiones
+func (s *PostRevisionService) EnsureInitial(post *models.Post) error {
+	if err := s.lock(post.ID); err != nil {
+		return err
+	}
+
+	var count int64
+	if err := s.db.Model(&models.PostRevision{}).Where("post_id = ?", post.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	_, err := s.create(post, post.AuthorID)
+	return err
+}
+
+// Record guarda el estado actual del post como una nueva revisión del usuario y elimina las que
+// exceden la retención. Si el título, el slug y el contenido no cambiaron no se guarda nada.
+// Como EnsureInitial, debe ejecutarse en la transacción que modifica el post.
+func (s *PostRevisionService) Record(post *models.Post, authorID uint) (*models.PostRevision, error) {
+	if err := s.lock(post.ID); err != nil {
+		return nil, err
+	}
+
+	latest, err := s.latest(post.ID)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil && latest.Title == post.Title && latest.Slug == post.Slug && latest.Content == post.Content {
+		return latest, nil
+	}
+
+	revision, err := s.create(post, authorID)
+	if err != nil {
+		return nil, err
+	}
+	if s.retention > 0 {
+		if _, err := s.Prune(post.ID, s.retention); err != nil {
+			return nil, err
+		}
+	}
+	return revision, nil
+}
+
+// List retorna las revisiones del post, de la más reciente a la más antigua. Los autores se
+// cargan sin filtrar por organización porque pueden ser miembros de otra.
+func (s *PostRevisionService) List(postID uint) ([]models.PostRevision, error) {
+	var revisions []models.PostRevision
+	err := s.db.Preload("Author", tenancy.Unscoped).
+		Where("post_id = ?", postID).
+		Order("number DESC").
+		Find(&revisions).Error
+	return revisions, err
+}
+
+// Get retorna la revisión number del post
+func (s *PostRevisionService) Get(postID, number uint) (*models.PostRevision, error) {
+	var revision models.PostRevision
+	if err := s.db.Preload("Author", tenancy.Unscoped).Where("post_id = ? AND number = ?", postID, number).First(&revision).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRevisionNotFound()
+		}
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// Diff compara dos revisiones del post: los cambios de título y slug y el contenido línea a línea
+func (s *PostRevisionService) Diff(postID, from, to uint) (*RevisionDiff, error) {
+	older, err := s.Get(postID, from)
+	if err != nil {
+		return nil, err
+	}
+	newer, err := s.Get(postID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &RevisionDiff{
+		From:    from,
+		To:      to,
+		Content: DiffLines(older.Content, newer.Content),
+	}
+	if older.Title != newer.Title {
+		diff.Title = &FieldChange{From: older.Title, To: newer.Title}
+	}
+	if older.Slug != newer.Slug {
+		diff.Slug = &FieldChange{From: older.Slug, To: newer.Slug}
+	}
+	return diff, nil
+}
+
+// Restore devuelve el post al título, el slug y el contenido de la revisión number. La restauración
+// se guarda como una nueva revisión del usuario, así que el historial nunca se reescribe.
+func (s *PostRevisionService) Restore(post *models.Post, number, authorID uint) (*models.PostRevision, error) {
+	var restored *models.PostRevision
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		revisions := &PostRevisionService{db: tx, retention: s.retention}
+
+		revision, err := revisions.Get(post.ID, number)
+		if err != nil {
+			return err
+		}
+
+		// El slug de la revisión pudo pasar a otro post de la organización
+		var count int64
+		if err := tx.Model(&models.Post{}).Where("slug = ? AND id != ?", revision.Slug, post.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrInvalidInput("El slug de la revisión ya lo usa otro post")
+		}
+
+		if err := revisions.EnsureInitial(post); err != nil {
+			return err
+		}
+
+		// UpdateColumns no ejecuta los hooks del modelo, que regenerarían el slug
+		now := time.Now()
+		if err := tx.Model(&models.Post{}).Where("id = ?", post.ID).UpdateColumns(map[string]interface{}{
+			"title":      revision.Title,
+			"slug":       revision.Slug,
+			"content":    revision.Content,
+			"updated_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		post.Title = revision.Title
+		post.Slug = revision.Slug
+		post.Content = revision.Content
+		post.UpdatedAt = now
+
+		restored, err = revisions.Record(post, authorID)
+		return err
+	})
+	return restored, err
+}
+
+// Prune conserva las keep revisiones más recientes del post y elimina las anteriores
+func (s *PostRevisionService) Prune(postID uint, keep int) (int64, error) {
+	if keep < 1 {
+		return 0, ErrInvalidInput("Se debe conservar al menos una revisión")
+	}
+
+	var kept []uint
+	if err := s.db.Model(&models.PostRevision{}).
+		Where("post_id = ?", postID).
+		Order("number DESC").
+		Limit(keep).
+		Pluck("id", &kept).Error; err != nil {
+		return 0, err
+	}
+	if len(kept) == 0 {
+		return 0, nil
+	}
+
+	result := s.db.Where("post_id = ? AND id NOT IN ?", postID, kept).Delete(&models.PostRevision{})
+	return result.RowsAffected, result.Error
+}
+
+// lock bloquea la fila del post hasta el final de la transacción, de modo que dos cambios
+// simultáneos del mismo post numeran sus revisiones uno detrás de otro en lugar de chocar con
+// el índice único. SQLite ignora FOR UPDATE porque ya serializa las escrituras.
+func (s *PostRevisionService) lock(postID uint) error {
+	var post models.Post
+	return s.db.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("id = ?", postID).Take(&post).Error
+}
+
+// latest retorna la revisión más reciente del post o nil si no tiene historial. La lectura
+// bloqueante obtiene la última revisión confirmada aunque la transacción ya hubiese leído antes.
+func (s *PostRevisionService) latest(postID uint) (*models.PostRevision, error) {
+	var revision models.PostRevision
+	if err := s.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("post_id = ?", postID).Order("number DESC").First(&revision).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// create guarda el estado actual del post con el número siguiente al de la última revisión.
+// Quien la llama ya tiene bloqueado el post.
+func (s *PostRevisionService) create(post *models.Post, authorID uint) (*models.PostRevision, error) {
+	last, err := s.latest(post.ID)
+	if err != nil {
+		return nil, err
+	}
+	number := uint(1)
+	if last != nil {
+		number = last.Number + 1
+	}
+
+	revision := models.PostRevision{
+		PostID:   post.ID,
+		Number:   number,
+		TenantID: post.TenantID,
+		AuthorID: authorID,
+		Title:    post.Title,
+		Slug:     post.Slug,
+		Content:  post.Content,
+	}
+	if err := s.db.Create(&revision).Error; err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// maxDiffLines limita las líneas distintas que DiffLines compara línea a línea; por encima se
+// muestran como eliminadas y añadidas en bloque para acotar el tiempo de la comparación
+const maxDiffLines = 10000
+
+// DiffLines compara dos textos línea a línea con el algoritmo de Myers en espacio lineal, que
+// obtiene un diff mínimo sin reservar una tabla de len(from)×len(to)
+func DiffLines(from, to string) []DiffLine {
+	a := splitLines(from)
+	b := splitLines(to)
+
+	// Las líneas iguales al principio y al final no necesitan compararse
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	diff := make([]DiffLine, 0, len(a)+len(b)-prefix-suffix)
+	for _, line := range a[:prefix] {
+		diff = append(diff, DiffLine{Op: DiffEqual, Text: line})
+	}
+
+	x := a[prefix : len(a)-suffix]
+	y := b[prefix : len(b)-suffix]
+	if len(x)+len(y) > maxDiffLines {
+		for _, line := range x {
+			diff = append(diff, DiffLine{Op: DiffDelete, Text: line})
+		}
+		for _, line := range y {
+			diff = append(diff, DiffLine{Op: DiffInsert, Text: line})
+		}
+	} else {
+		diff = newLineDiff(x, y, diff).run()
+	}
+
+	for _, line := range a[len(a)-suffix:] {
+		diff = append(diff, DiffLine{Op: DiffEqual, Text: line})
+	}
+	return diff
+}
+
+// lineDiff guarda el estado de una comparación de Myers: las líneas como enteros, para
+// compararlas sin recorrer las cadenas, y los vectores de las búsquedas hacia delante y atrás
+type lineDiff struct {
+	x, y       []string
+	xIDs, yIDs []int
+	forward    []int
+	backward   []int
+	offset     int
+	diff       []DiffLine
+}
+
+func newLineDiff(x, y []string, diff []DiffLine) *lineDiff {
+	ids := make(map[string]int, len(x)+len(y))
+	identify := func(lines []string) []int {
+		result := make([]int, len(lines))
+		for i, line := range lines {
+			id, ok := ids[line]
+			if !ok {
+				id = len(ids)
+				ids[line] = id
+			}
+			result[i] = id
+		}
+		return result
+	}
+
+	size := (len(x)+len(y)+1)/2 + 1
+	return &lineDiff{
+		x:        x,
+		y:        y,
+		xIDs:     identify(x),
+		yIDs:     identify(y),
+		forward:  make([]int, 2*size+1),
+		backward: make([]int, 2*size+1),
+		offset:   size,
+		diff:     diff,
+	}
+}
+
+func (d *lineDiff) run() []DiffLine {
+	d.compare(0, len(d.x), 0, len(d.y))
+	return d.diff
+}
+
+// compare añade al diff las operaciones que convierten x[x0:x1] en y[y0:y1]
+func (d *lineDiff) compare(x0, x1, y0, y1 int) {
+	for x0 < x1 && y0 < y1 && d.xIDs[x0] == d.yIDs[y0] {
+		d.diff = append(d.diff, DiffLine{Op: DiffEqual, Text: d.x[x0]})
+		x0++
+		y0++
+	}
+	end := x1
+	for x0 < x1 && y0 < y1 && d.xIDs[x1-1] == d.yIDs[y1-1] {
+		x1--
+		y1--
+	}
+
+	switch {
+	case x0 == x1:
+		for _, line := range d.y[y0:y1] {
+			d.diff = append(d.diff, DiffLine{Op: DiffInsert, Text: line})
+		}
+	case y0 == y1:
+		for _, line := range d.x[x0:x1] {
+			d.diff = append(d.diff, DiffLine{Op: DiffDelete, Text: line})
+		}
+	default:
+		// Sin líneas comunes en los extremos hay al menos dos cambios, y cada mitad tiene menos
+		sx, sy, ex, ey := d.middleSnake(x0, x1, y0, y1)
+		d.compare(x0, sx, y0, sy)
+		for _, line := range d.x[sx:ex] {
+			d.diff = append(d.diff, DiffLine{Op: DiffEqual, Text: line})
+		}
+		d.compare(ex, x1, ey, y1)
+	}
+
+	for _, line := range d.x[x1:end] {
+		d.diff = append(d.diff, DiffLine{Op: DiffEqual, Text: line})
+	}
+}
+
+// middleSnake busca a la vez desde el principio y desde el final el tramo de líneas iguales que
+// está en la mitad de un diff mínimo de x[x0:x1] e y[y0:y1] y retorna su inicio y su fin
+func (d *lineDiff) middleSnake(x0, x1, y0, y1 int) (int, int, int, int) {
+	n, m := x1-x0, y1-y0
+	delta := n - m
+	odd := delta%2 != 0
+	forward, backward, offset := d.forward, d.backward, d.offset
+
+	// forward[offset+k] es la línea de x más lejana alcanzada en la diagonal k = x - y;
+	// backward[offset+k] es lo mismo contando desde el final de ambos textos
+	forward[offset+1] = 0
+	backward[offset+1] = 0
+	for depth := 0; depth <= (n+m+1)/2; depth++ {
+		for k := -depth; k <= depth; k += 2 {
+			var x int
+			if k == -depth || (k != depth && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && d.xIDs[x0+x] == d.yIDs[y0+y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+
+			if reverse := delta - k; odd && reverse >= -(depth-1) && reverse <= depth-1 && x+backward[offset+reverse] >= n {
+				return x0 + startX, y0 + startY, x0 + x, y0 + y
+			}
+		}
+
+		for k := -depth; k <= depth; k += 2 {
+			var x int
+			if k == -depth || (k != depth && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && d.xIDs[x1-1-x] == d.yIDs[y1-1-y] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+
+			if reverse := delta - k; !odd && reverse >= -depth && reverse <= depth && x+forward[offset+reverse] >= n {
+				return x1 - x, y1 - y, x1 - startX, y1 - startY
+			}
+		}
+	}
+	// Inalcanzable: el diff mínimo nunca tiene más de n+m cambios
+	return x0, y0, x0, y0
+}
+
+// splitLines separa el texto en líneas; un texto vacío no tiene ninguna
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// diffOps resume el diff como "=línea", "-línea" y "+línea"
+func diffOps(diff []DiffLine) []string {
+	symbols := map[string]string{DiffEqual: "=", DiffDelete: "-", DiffInsert: "+"}
+	ops := make([]string, 0, len(diff))
+	for _, line := range diff {
+		ops = append(ops, symbols[line.Op]+line.Text)
+	}
+	return ops
+}
+
+func TestDiffLines(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		from, to string
+		expected []string
+	}{
+		{"textos vacíos", "", "", []string{}},
+		{"contenido nuevo", "", "a\nb", []string{"+a", "+b"}},
+		{"contenido eliminado", "a\nb", "", []string{"-a", "-b"}},
+		{"sin cambios", "a\nb", "a\nb", []string{"=a", "=b"}},
+		{"línea añadida en medio", "a\nc", "a\nb\nc", []string{"=a", "+b", "=c"}},
+		{"línea eliminada al final", "a\nb\nc", "a\nb", []string{"=a", "=b", "-c"}},
+		{"línea reemplazada", "a\nb\nc", "a\nx\nc", []string{"=a", "-b", "+x", "=c"}},
+		{"saltos de línea de Windows", "a\r\nb", "a\nb", []string{"=a", "=b"}},
+		{"líneas movidas", "a\nb\nc\nd", "c\nd\na\nb", []string{"-a", "-b", "=c", "=d", "+a", "+b"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if ops := diffOps(DiffLines(tc.from, tc.to)); !reflect.DeepEqual(ops, tc.expected) {
+				t.Errorf("DiffLines(%q, %q) = %v, se esperaba %v", tc.from, tc.to, ops, tc.expected)
+			}
+		})
+	}
+}
+
+// lcsLength es la longitud de la subsecuencia común más larga, calculada con la tabla completa
+func lcsLength(a, b []string) int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	return lcs[0][0]
+}
+
+func TestDiffLinesIsMinimalAndRebuildsBothTexts(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	randomLines := func() []string {
+		lines := make([]string, random.Intn(12))
+		for i := range lines {
+			lines[i] = string(rune('a' + random.Intn(4)))
+		}
+		return lines
+	}
+
+	for i := 0; i < 2000; i++ {
+		a, b := randomLines(), randomLines()
+		from, to := strings.Join(a, "\n"), strings.Join(b, "\n")
+		diff := DiffLines(from, to)
+
+		var rebuiltFrom, rebuiltTo []string
+		equal := 0
+		for _, line := range diff {
+			if line.Op != DiffInsert {
+				rebuiltFrom = append(rebuiltFrom, line.Text)
+			}
+			if line.Op != DiffDelete {
+				rebuiltTo = append(rebuiltTo, line.Text)
+			}
+			if line.Op == DiffEqual {
+				equal++
+			}
+		}
+		if strings.Join(rebuiltFrom, "\n") != from || strings.Join(rebuiltTo, "\n") != to {
+			t.Fatalf("DiffLines(%q, %q) = %v no reconstruye los textos", from, to, diffOps(diff))
+		}
+		if expected := lcsLength(splitLines(from), splitLines(to)); equal != expected {
+			t.Fatalf("DiffLines(%q, %q) mantiene %d líneas, se esperaban %d", from, to, equal, expected)
+		}
+	}
+}
+
+func TestDiffLinesBoundsLargeContents(t *testing.T) {
+	numbered := func(prefix string, n int) string {
+		lines := make([]string, n)
+		for i := range lines {
+			lines[i] = fmt.Sprintf("%s %d", prefix, i)
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	// Un cambio pequeño en un texto grande se sigue comparando línea a línea
+	from := numbered("línea", 50000)
+	to := strings.Replace(from, "línea 25000\n", "cambiada\n", 1)
+	diff := DiffLines(from, to)
+	if len(diff) != 50001 || diff[25000].Op != DiffDelete || diff[25001].Op != DiffInsert || diff[25001].Text != "cambiada" {
+		t.Errorf("el diff de un cambio tiene %d líneas: %v", len(diff), diffOps(diff[24999:25003]))
+	}
+
+	// Por encima de maxDiffLines líneas distintas el cambio se muestra en bloque
+	diff = DiffLines(numbered("antes", maxDiffLines), numbered("después", maxDiffLines))
+	if len(diff) != 2*maxDiffLines || diff[0].Op != DiffDelete || diff[maxDiffLines].Op != DiffInsert {
+		t.Errorf("el diff de dos textos distintos tiene %d líneas", len(diff))
+	}
+}
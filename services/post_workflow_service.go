@@ -0,0 +1,167 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"go-api-orm/models"
+	"go-api-orm/tenancy"
+	"gorm.io/gorm"
+)
+
+// PublishPermission permite revisar, programar y publicar posts y ver los que no están publicados
+const PublishPermission = "posts:publish"
+
+// postTransition es un cambio de estado permitido. Los cambios editoriales requieren
+// posts:publish; el resto solo requiere poder editar el post (su autor o posts:update_any).
+type postTransition struct {
+	from      string
+	to        string
+	editorial bool
+}
+
+// postTransitions define el flujo editorial:
+// draft -> in_review -> scheduled -> published -> archived
+var postTransitions = []postTransition{
+	{from: models.PostStatusDraft, to: models.PostStatusInReview},
+	{from: models.PostStatusInReview, to: models.PostStatusDraft},
+	{from: models.PostStatusDraft, to: models.PostStatusScheduled, editorial: true},
+	{from: models.PostStatusDraft, to: models.PostStatusPublished, editorial: true},
+	{from: models.PostStatusInReview, to: models.PostStatusScheduled, editorial: true},
+	{from: models.PostStatusInReview, to: models.PostStatusPublished, editorial: true},
+	{from: models.PostStatusScheduled, to: models.PostStatusPublished, editorial: true},
+	{from: models.PostStatusScheduled, to: models.PostStatusDraft, editorial: true},
+	{from: models.PostStatusPublished, to: models.PostStatusArchived, editorial: true},
+	{from: models.PostStatusPublished, to: models.PostStatusDraft, editorial: true},
+	{from: models.PostStatusArchived, to: models.PostStatusDraft, editorial: true},
+}
+
+// PostWorkflowService aplica el flujo editorial de los posts y publica los programados
+type PostWorkflowService struct {
+	db *gorm.DB
+}
+
+// NewPostWorkflowService crea una nueva instancia del servicio de flujo editorial
+func NewPostWorkflowService(db *gorm.DB) *PostWorkflowService {
+	return &PostWorkflowService{db: db}
+}
+
+// ErrInvalidTransition se retorna cuando el flujo editorial no permite el cambio de estado
+var ErrInvalidTransition = func(from, to string) *APIError {
+	return NewAPIError(
+		http.StatusConflict,
+		"INVALID_STATUS_TRANSITION",
+		"Cambio de estado no permitido",
+		fmt.Sprintf("Un post en estado %s no puede pasar a %s", from, to),
+		nil,
+	)
+}
+
+// CheckTransition indica si el cambio de estado requiere posts:publish o retorna
+// ErrInvalidTransition si no está permitido
+func (s *PostWorkflowService) CheckTransition(from, to string) (editorial bool, err error) {
+	for _, transition := range postTransitions {
+		if transition.from == from && transition.to == to {
+			return transition.editorial, nil
+		}
+	}
+	return false, ErrInvalidTransition(from, to)
+}
+
+// Transition cambia el estado del post. El estado scheduled requiere una fecha de publicación futura.
+// Falla si el estado del post cambió mientras tanto (p. ej. lo publicó el programador).
+func (s *PostWorkflowService) Transition(post *models.Post, to string, publishAt *time.Time) error {
+	if _, err := s.CheckTransition(post.Status, to); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":     to,
+		"publish_at": nil,
+		"updated_at": now,
+	}
+	switch to {
+	case models.PostStatusScheduled:
+		if publishAt == nil || !publishAt.After(now) {
+			return ErrInvalidInput("publish_at debe ser una fecha futura para programar el post")
+		}
+		updates["publish_at"] = *publishAt
+	case models.PostStatusPublished:
+		updates["published_at"] = now
+	}
+
+	// UpdateColumns no ejecuta los hooks del modelo, que regenerarían el slug
+	result := s.db.Model(&models.Post{}).
+		Where("id = ? AND status = ?", post.ID, post.Status).
+		UpdateColumns(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidTransition(post.Status, to)
+	}
+
+	post.Status = to
+	post.UpdatedAt = now
+	post.PublishAt = nil
+	if to == models.PostStatusScheduled {
+		post.PublishAt = publishAt
+	}
+	if to == models.PostStatusPublished {
+		post.PublishedAt = &now
+	}
+	return nil
+}
+
+// PublishDue publica los posts programados cuya fecha de publicación ya llegó, en todas las organizaciones
+func (s *PostWorkflowService) PublishDue(now time.Time) (int64, error) {
+	result := tenancy.Unscoped(s.db).Model(&models.Post{}).
+		Where("status = ? AND publish_at <= ?", models.PostStatusScheduled, now).
+		UpdateColumns(map[string]interface{}{
+			"status":       models.PostStatusPublished,
+			"published_at": gorm.Expr("publish_at"),
+			"publish_at":   nil,
+			"updated_at":   now,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// StartScheduler publica cada interval los posts programados cuya fecha llegó
+func (s *PostWorkflowService) StartScheduler(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			published, err := s.PublishDue(now)
+			if err != nil {
+				log.Printf("Error publishing scheduled posts: %v", err)
+				continue
+			}
+			if published > 0 {
+				log.Printf("Published %d scheduled posts", published)
+			}
+		}
+	}()
+}
+
+// VisiblePosts limita la consulta a los posts que puede ver el usuario: los publicados y los
+// suyos en cualquier estado. Con all (posts:publish) se ven todos. userID 0 es una petición anónima.
+func VisiblePosts(userID uint, all bool) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		switch {
+		case all:
+			return db
+		case userID == 0:
+			return db.Where("posts.status = ?", models.PostStatusPublished)
+		default:
+			return db.Where("(posts.status = ? OR posts.author_id = ?)", models.PostStatusPublished, userID)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"go-api-orm/models"
+	"go-api-orm/utils"
+	"gorm.io/gorm"
+)
+
+// RefreshTokenService maneja la emisión y rotación de tokens de refresco
+type RefreshTokenService struct {
+	db  *gorm.DB
+	ttl time.Duration
+}
+
+// NewRefreshTokenService crea una nueva instancia del servicio de tokens de refresco
+func NewRefreshTokenService(db *gorm.DB) *RefreshTokenService {
+	return &RefreshTokenService{
+		db:  db,
+		ttl: time.Hour * time.Duration(utils.GetEnvInt("JWT_REFRESH_EXPIRATION_HOURS", 720)),
+	}
+}
+
+// ErrInvalidRefreshToken se retorna cuando el token no existe, expiró o fue revocado
+var ErrInvalidRefreshToken = func() *APIError {
+	return ErrUnauthorized("Refresh token inválido o expirado")
+}
+
+// ErrRefreshTokenReused se retorna cuando se detecta la reutilización de un token ya rotado
+var ErrRefreshTokenReused = func() *APIError {
+	return ErrUnauthorized("Refresh token reutilizado; la sesión ha sido revocada")
+}
+
+// Issue emite un nuevo token de refresco que inicia una familia nueva asociada a la sesión
+func (s *RefreshTokenService) Issue(userID uint, sessionID *uint) (string, error) {
+	_, familyID, err := utils.GenerateOpaqueToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	token, _, err := s.create(s.db, userID, familyID, sessionID)
+	return token, err
+}
+
+// Rotate consume un token de refresco y emite uno nuevo de la misma familia.
+// Si el token ya había sido rotado o revocado se revoca la familia completa.
+func (s *RefreshTokenService) Rotate(token string) (string, *models.RefreshToken, error) {
+	var current models.RefreshToken
+	if err := s.db.Where("token_hash = ?", utils.HashToken(token)).First(&current).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil, ErrInvalidRefreshToken()
+		}
+		return "", nil, err
+	}
+
+	if current.RevokedAt != nil {
+		if err := s.RevokeFamily(current.FamilyID); err != nil {
+			return "", nil, err
+		}
+		return "", nil, ErrRefreshTokenReused()
+	}
+
+	if !current.IsActive() {
+		return "", nil, ErrInvalidRefreshToken()
+	}
+
+	var newToken string
+	reused := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Marcar el token actual como usado de forma atómica para evitar rotaciones concurrentes
+		now := time.Now()
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", current.ID).
+			Update("revoked_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			reused = true
+			return ErrRefreshTokenReused()
+		}
+
+		plain, created, err := s.create(tx, current.UserID, current.FamilyID, current.SessionID)
+		if err != nil {
+			return err
+		}
+		newToken = plain
+
+		return tx.Model(&models.RefreshToken{}).
+			Where("id = ?", current.ID).
+			Update("replaced_by_id", created.ID).Error
+	})
+	if err != nil {
+		if reused {
+			s.RevokeFamily(current.FamilyID)
+		}
+		return "", nil, err
+	}
+
+	return newToken, &current, nil
+}
+
+// Revoke revoca un token de refresco concreto
+func (s *RefreshTokenService) Revoke(token string) error {
+	return s.db.Model(&models.RefreshToken{}).
+		Where("token_hash = ? AND revoked_at IS NULL", utils.HashToken(token)).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeFamily revoca todos los tokens activos de una familia
+func (s *RefreshTokenService) RevokeFamily(familyID string) error {
+	return s.db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeSession revoca todos los tokens de refresco activos de una sesión
+func (s *RefreshTokenService) RevokeSession(sessionID uint) error {
+	return s.db.Model(&models.RefreshToken{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeAllForUser revoca todos los tokens de refresco activos de un usuario
+func (s *RefreshTokenService) RevokeAllForUser(userID uint) error {
+	return s.db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeAllExceptSession revoca los tokens de refresco de un usuario salvo los de la sesión indicada
+func (s *RefreshTokenService) RevokeAllExceptSession(userID, sessionID uint) error {
+	return s.db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL AND (session_id IS NULL OR session_id <> ?)", userID, sessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// TTL retorna la duración de los tokens de refresco
+func (s *RefreshTokenService) TTL() time.Duration {
+	return s.ttl
+}
+
+func (s *RefreshTokenService) create(db *gorm.DB, userID uint, familyID string, sessionID *uint) (string, *models.RefreshToken, error) {
+	token, hash, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+
+	record := models.RefreshToken{
+		UserID:    userID,
+		TokenHash: hash,
+		FamilyID:  familyID,
+		SessionID: sessionID,
+		ExpiresAt: time.Now().Add(s.ttl),
+	}
+	if err := db.Create(&record).Error; err != nil {
+		return "", nil, err
+	}
+
+	return token, &record, nil
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"go-api-orm/models"
+	"go-api-orm/utils"
+	"gorm.io/gorm"
+)
+
+// isRefreshTokenReused indica si el error es ErrRefreshTokenReused
+func isRefreshTokenReused(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Detail == ErrRefreshTokenReused().Detail
+}
+
+// activeInFamily cuenta los tokens de refresco sin revocar de la familia del token
+func activeInFamily(t *testing.T, refresh *RefreshTokenService, token string) int64 {
+	t.Helper()
+	var record models.RefreshToken
+	if err := refresh.db.Where("token_hash = ?", utils.HashToken(token)).First(&record).Error; err != nil {
+		t.Fatalf("buscar token: %v", err)
+	}
+	var active int64
+	if err := refresh.db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", record.FamilyID).
+		Count(&active).Error; err != nil {
+		t.Fatalf("contar tokens: %v", err)
+	}
+	return active
+}
+
+func TestRefreshTokenReuseRevokesTheFamily(t *testing.T) {
+	refresh := NewRefreshTokenService(newTestDB(t))
+
+	first, err := refresh.Issue(1, nil)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	other, err := refresh.Issue(1, nil)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	second, rotated, err := refresh.Rotate(first)
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if rotated.UserID != 1 || second == first {
+		t.Fatalf("Rotate = %q, %+v", second, rotated)
+	}
+	third, _, err := refresh.Rotate(second)
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+
+	// Presentar de nuevo un token ya rotado revoca también el último de la familia
+	if _, _, err := refresh.Rotate(first); !isRefreshTokenReused(err) {
+		t.Fatalf("reutilizar un token rotado = %v, se esperaba la reutilización", err)
+	}
+	if active := activeInFamily(t, refresh, third); active != 0 {
+		t.Errorf("quedan %d tokens activos en la familia", active)
+	}
+	if _, _, err := refresh.Rotate(third); !isRefreshTokenReused(err) {
+		t.Errorf("rotar el último token de una familia revocada = %v", err)
+	}
+
+	// Las demás familias del usuario siguen activas
+	if _, _, err := refresh.Rotate(other); err != nil {
+		t.Errorf("rotar un token de otra familia: %v", err)
+	}
+	var apiErr *APIError
+	if _, _, err := refresh.Rotate("desconocido"); !errors.As(err, &apiErr) || apiErr.Detail != ErrInvalidRefreshToken().Detail {
+		t.Errorf("rotar un token desconocido = %v", err)
+	}
+}
+
+func TestConcurrentRefreshTokenRotationHasASingleWinner(t *testing.T) {
+	db := newTestDB(t)
+	// SQLite admite un único escritor: las transacciones se serializan en una conexión
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.SetMaxOpenConns(1)
+	}
+	refresh := NewRefreshTokenService(db)
+
+	token, err := refresh.Issue(1, nil)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	// Todas las rotaciones leen el token todavía activo antes de que ninguna lo marque como usado
+	const attempts = 8
+	var read sync.WaitGroup
+	read.Add(attempts)
+	var readers int32
+	if err := db.Callback().Query().After("gorm:query").Register("test:rotation_barrier", func(tx *gorm.DB) {
+		if tx.Statement.Table == "refresh_tokens" && atomic.AddInt32(&readers, 1) <= attempts {
+			read.Done()
+			read.Wait()
+		}
+	}); err != nil {
+		t.Fatalf("registrar callback: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	results := make(chan error, attempts)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := refresh.Rotate(token)
+			results <- err
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	winners := 0
+	for err := range results {
+		switch {
+		case err == nil:
+			winners++
+		case !isRefreshTokenReused(err):
+			t.Errorf("rotación concurrente = %v, se esperaba la reutilización", err)
+		}
+	}
+	if winners != 1 {
+		t.Fatalf("%d rotaciones concurrentes tuvieron éxito, se esperaba una", winners)
+	}
+
+	// Los perdedores son una reutilización y revocan también el token emitido al ganador
+	if active := activeInFamily(t, refresh, token); active != 0 {
+		t.Errorf("quedan %d tokens activos en la familia", active)
+	}
+	var issued int64
+	if err := db.Model(&models.RefreshToken{}).Count(&issued).Error; err != nil || issued != 2 {
+		t.Errorf("se emitieron %d tokens (%v), se esperaban el original y el del ganador", issued, err)
+	}
+}
@@ -0,0 +1,410 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"go-api-orm/models"
+	"go-api-orm/tenancy"
+	"go-api-orm/utils"
+	"gorm.io/gorm"
+)
+
+// RoleService resuelve la jerarquía de roles. Cada rol puede tener un rol padre del que hereda
+// los permisos (admin > editor > user). La cadena de cada rol se cachea por su ID.
+type RoleService struct {
+	db    *gorm.DB
+	cache *CacheService
+}
+
+// NewRoleService crea una nueva instancia del servicio de roles
+func NewRoleService(db *gorm.DB, cache *CacheService) *RoleService {
+	return &RoleService{db: db, cache: cache}
+}
+
+// AdminRole es el rol de administrador; los roles que heredan de él también se consideran administradores
+const AdminRole = "admin"
+
+// ErrRoleCycle se retorna cuando el padre indicado crearía un ciclo en la jerarquía
+var ErrRoleCycle = func(detail string) *APIError {
+	return NewAPIError(
+		http.StatusBadRequest,
+		"ROLE_HIERARCHY_CYCLE",
+		"La jerarquía de roles no puede tener ciclos",
+		detail,
+		nil,
+	)
+}
+
+// ErrSystemRole se retorna al intentar eliminar o renombrar un rol del sistema
+var ErrSystemRole = func(detail string) *APIError {
+	return NewAPIError(
+		http.StatusForbidden,
+		"SYSTEM_ROLE",
+		"Los roles del sistema no se pueden eliminar ni renombrar",
+		detail,
+		nil,
+	)
+}
+
+// ErrRoleInUse se retorna al eliminar un rol con usuarios asignados sin indicar a qué rol reasignarlos
+var ErrRoleInUse = func(users int64) *APIError {
+	return NewAPIError(
+		http.StatusConflict,
+		"ROLE_IN_USE",
+		"El rol tiene usuarios asignados",
+		fmt.Sprintf("%d usuarios tienen este rol; indica reassign_to para reasignarlos a otro rol", users),
+		nil,
+	)
+}
+
+// ErrInvalidRole se retorna cuando el rol indicado no existe
+var ErrInvalidRole = func() *APIError {
+	return NewAPIError(
+		http.StatusBadRequest,
+		"INVALID_ROLE",
+		"Rol inválido",
+		"El rol especificado no existe",
+		nil,
+	)
+}
+
+// ErrLastAdmin se retorna al quitar el rol de administrador o el acceso al último administrador
+// de la organización
+var ErrLastAdmin = func() *APIError {
+	return NewAPIError(
+		http.StatusConflict,
+		"LAST_ADMIN",
+		"Debe existir al menos un administrador",
+		"No se puede quitar el rol ni el acceso al último administrador de la organización",
+		nil,
+	)
+}
+
+// DefaultRoleName retorna el rol que reciben los usuarios nuevos (DEFAULT_ROLE, por defecto user)
+func DefaultRoleName() string {
+	return utils.GetEnvString("DEFAULT_ROLE", "user")
+}
+
+// DefaultRole retorna el rol que reciben los usuarios nuevos
+func DefaultRole(db *gorm.DB) (*models.Role, error) {
+	var role models.Role
+	if err := db.Where("name = ? AND tenant_id = 0", DefaultRoleName()).First(&role).Error; err != nil {
+		return nil, fmt.Errorf("rol por defecto %s: %w", DefaultRoleName(), err)
+	}
+	return &role, nil
+}
+
+// IsProtected indica si el rol no se puede eliminar ni renombrar:
+// los roles del sistema y el rol por defecto de los usuarios nuevos
+func IsProtected(role *models.Role) bool {
+	return role.System || role.Name == DefaultRoleName()
+}
+
+func roleLineageKey(roleID uint) string {
+	return fmt.Sprintf("role_lineage:%d", roleID)
+}
+
+// Lineage retorna el rol indicado seguido de sus ancestros hasta la raíz.
+// Si el rol no existe retorna una lista vacía.
+func (s *RoleService) Lineage(roleID uint) ([]models.Role, error) {
+	if lineage, found := GetTyped[[]models.Role](s.cache, roleLineageKey(roleID)); found {
+		return lineage, nil
+	}
+
+	lineage := []models.Role{}
+	var role models.Role
+	err := s.db.First(&role, roleID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return lineage, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// visited protege de ciclos creados fuera de la API
+	visited := map[uint]bool{}
+	for {
+		visited[role.ID] = true
+		lineage = append(lineage, role)
+		if role.ParentID == nil || visited[*role.ParentID] {
+			break
+		}
+
+		var parent models.Role
+		err := s.db.First(&parent, *role.ParentID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		role = parent
+	}
+
+	s.cache.Set(roleLineageKey(roleID), lineage)
+	return lineage, nil
+}
+
+// InheritsFrom indica si el rol es el rol global minimum o hereda de él (p. ej. admin hereda
+// de editor). Un rol de una organización con el mismo nombre no cuenta.
+func (s *RoleService) InheritsFrom(roleID uint, minimum string) (bool, error) {
+	lineage, err := s.Lineage(roleID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, role := range lineage {
+		if role.Name == minimum && role.TenantID == 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// EnsureNameAvailable comprueba que ningún otro rol visible para la organización (los suyos y
+// los globales) se llame name, para que un rol propio no pueda hacerse pasar por uno global
+func (s *RoleService) EnsureNameAvailable(name string, roleID uint) error {
+	var count int64
+	if err := s.db.Model(&models.Role{}).Where("name = ? AND id != ?", name, roleID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrInvalidInput(fmt.Sprintf("Ya existe un rol llamado %s", name))
+	}
+	return nil
+}
+
+// ValidateParent comprueba que parentID exista y que asignarlo como padre de roleID
+// no cree un ciclo. Para un rol nuevo roleID es 0.
+func (s *RoleService) ValidateParent(roleID uint, parentID *uint) error {
+	if parentID == nil {
+		return nil
+	}
+	if *parentID == roleID {
+		return ErrRoleCycle("Un rol no puede heredar de sí mismo")
+	}
+
+	visited := map[uint]bool{}
+	current := *parentID
+	for {
+		var role models.Role
+		err := s.db.First(&role, current).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if current == *parentID {
+				return ErrInvalidInput(fmt.Sprintf("El rol padre %d no existe", *parentID))
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if role.ID == roleID {
+			return ErrRoleCycle(fmt.Sprintf("El rol %d ya hereda del rol %d", *parentID, roleID))
+		}
+		visited[role.ID] = true
+		if role.ParentID == nil || visited[*role.ParentID] {
+			return nil
+		}
+		current = *role.ParentID
+	}
+}
+
+// Descendants retorna el rol indicado y todos los roles que heredan de él
+func (s *RoleService) Descendants(roleID uint) ([]models.Role, error) {
+	var role models.Role
+	err := s.db.First(&role, roleID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return []models.Role{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	descendants := []models.Role{role}
+	visited := map[uint]bool{role.ID: true}
+	for i := 0; i < len(descendants); i++ {
+		var children []models.Role
+		if err := s.db.Where("parent_id = ?", descendants[i].ID).Find(&children).Error; err != nil {
+			return nil, err
+		}
+		for _, child := range children {
+			if !visited[child.ID] {
+				visited[child.ID] = true
+				descendants = append(descendants, child)
+			}
+		}
+	}
+	return descendants, nil
+}
+
+// Delete elimina el rol. Los roles del sistema no se pueden eliminar. Si el rol tiene usuarios
+// se reasignan al rol reassignTo; con reassignTo 0 la eliminación falla mientras tenga usuarios.
+// Los roles que heredaban de él pasan a heredar de su padre.
+func (s *RoleService) Delete(role *models.Role, reassignTo uint) error {
+	if IsProtected(role) {
+		return ErrSystemRole(fmt.Sprintf("El rol %s es un rol del sistema o el rol por defecto", role.Name))
+	}
+
+	affected, err := s.Descendants(role.ID)
+	if err != nil {
+		return err
+	}
+
+	// Los usuarios se cuentan dentro de la transacción para que no quede ninguno asignado
+	// entre el recuento y la reasignación
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Se cuentan también los usuarios eliminados para no dejar referencias a un rol inexistente
+		var users int64
+		if err := tx.Unscoped().Model(&models.User{}).Where("role_id = ?", role.ID).Count(&users).Error; err != nil {
+			return err
+		}
+		// Las membresías de usuarios de otras organizaciones también tienen el rol
+		var members int64
+		if err := tenancy.Unscoped(tx).Model(&models.Membership{}).Where("role_id = ?", role.ID).Count(&members).Error; err != nil {
+			return err
+		}
+		users += members
+
+		if users > 0 {
+			if reassignTo == 0 {
+				return ErrRoleInUse(users)
+			}
+			if reassignTo == role.ID {
+				return ErrInvalidInput("No se puede reasignar los usuarios al rol que se elimina")
+			}
+			var target models.Role
+			if err := tx.First(&target, reassignTo).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return ErrInvalidInput(fmt.Sprintf("El rol %d no existe", reassignTo))
+				}
+				return err
+			}
+
+			if err := tx.Unscoped().Model(&models.User{}).Where("role_id = ?", role.ID).Update("role_id", reassignTo).Error; err != nil {
+				return err
+			}
+			if err := tenancy.Unscoped(tx).Model(&models.Membership{}).Where("role_id = ?", role.ID).Update("role_id", reassignTo).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&models.Role{}).Where("parent_id = ?", role.ID).Update("parent_id", role.ParentID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(role).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, descendant := range affected {
+		s.forget(descendant.ID)
+	}
+	return nil
+}
+
+// AssignToUser cambia el rol del usuario. Falla si el usuario es el último administrador
+// (rol admin o que hereda de él) y el nuevo rol no lo es.
+func (s *RoleService) AssignToUser(userID, roleID uint) (*models.User, error) {
+	var user models.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Preload("Role").First(&user, userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound("Usuario")
+			}
+			return err
+		}
+
+		var role models.Role
+		if err := tx.First(&role, roleID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidRole()
+			}
+			return err
+		}
+		if user.RoleID == role.ID {
+			return nil
+		}
+
+		roles := NewRoleService(tx, s.cache)
+		wasAdmin, err := roles.InheritsFrom(user.RoleID, AdminRole)
+		if err != nil {
+			return err
+		}
+		isAdmin, err := roles.InheritsFrom(role.ID, AdminRole)
+		if err != nil {
+			return err
+		}
+		if wasAdmin && !isAdmin {
+			if err := roles.EnsureAnotherAdmin(user.TenantID, user.ID); err != nil {
+				return err
+			}
+		}
+
+		// Sin el rol precargado en el modelo: GORM guardaría la asociación y restauraría role_id
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Update("role_id", role.ID).Error; err != nil {
+			return err
+		}
+		user.RoleID = role.ID
+		user.Role = role
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// EnsureAnotherAdmin retorna ErrLastAdmin si la organización se queda sin administradores al
+// quitarle el rol o el acceso al usuario userID. Cuenta sus usuarios y los miembros de otras
+// organizaciones con el rol admin o un rol que hereda de él.
+func (s *RoleService) EnsureAnotherAdmin(organizationID, userID uint) error {
+	var admin models.Role
+	if err := s.db.Where("name = ? AND tenant_id = 0", AdminRole).First(&admin).Error; err != nil {
+		return err
+	}
+	admins, err := s.Descendants(admin.ID)
+	if err != nil {
+		return err
+	}
+	adminRoleIDs := make([]uint, 0, len(admins))
+	for _, admin := range admins {
+		adminRoleIDs = append(adminRoleIDs, admin.ID)
+	}
+
+	// Los miembros pertenecen a otra organización, así que se cuentan sin filtrar por la actual
+	db := tenancy.Unscoped(s.db)
+	var users, members int64
+	if err := db.Model(&models.User{}).Where("tenant_id = ? AND role_id IN ? AND id <> ?", organizationID, adminRoleIDs, userID).Count(&users).Error; err != nil {
+		return err
+	}
+	if err := db.Model(&models.Membership{}).Where("organization_id = ? AND role_id IN ? AND user_id <> ?", organizationID, adminRoleIDs, userID).Count(&members).Error; err != nil {
+		return err
+	}
+	if users+members == 0 {
+		return ErrLastAdmin()
+	}
+	return nil
+}
+
+// Invalidate descarta la cadena y los permisos cacheados del rol y de los roles que heredan de él
+// (p. ej. al cambiar su padre o modificar sus permisos)
+func (s *RoleService) Invalidate(roleID uint) error {
+	s.forget(roleID)
+
+	descendants, err := s.Descendants(roleID)
+	if err != nil {
+		return err
+	}
+	for _, descendant := range descendants {
+		s.forget(descendant.ID)
+	}
+	return nil
+}
+
+func (s *RoleService) forget(roleID uint) {
+	s.cache.Delete(roleLineageKey(roleID))
+	s.cache.Delete(rolePermissionsKey(roleID))
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-api-orm/models"
+	"go-api-orm/tenancy"
+	"gorm.io/gorm"
+)
+
+func TestRoleDeleteReassignsUsers(t *testing.T) {
+	db := tenancy.Unscoped(newTestDB(t))
+	roles := NewRoleService(db, NewCacheService(time.Minute, 0))
+
+	defaultRole, err := DefaultRole(db)
+	if err != nil {
+		t.Fatalf("DefaultRole: %v", err)
+	}
+	role := models.Role{Name: "revisor", Description: "Revisor"}
+	if err := db.Create(&role).Error; err != nil {
+		t.Fatalf("crear rol: %v", err)
+	}
+	user := models.User{Username: "ana", Email: "ana@example.com", Password: "Secreta123!", RoleID: role.ID}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("crear usuario: %v", err)
+	}
+
+	var apiErr *APIError
+	if err := roles.Delete(&role, 0); !errors.As(err, &apiErr) || apiErr.Code != "ROLE_IN_USE" {
+		t.Fatalf("Delete sin reasignar = %v, se esperaba ROLE_IN_USE", err)
+	}
+	if err := roles.Delete(&role, role.ID); !errors.As(err, &apiErr) || apiErr.Code != "INVALID_INPUT" {
+		t.Fatalf("Delete reasignando al mismo rol = %v", err)
+	}
+
+	if err := roles.Delete(&role, defaultRole.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := db.First(&models.Role{}, role.ID).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("el rol sigue existiendo: %v", err)
+	}
+	if err := db.First(&user, user.ID).Error; err != nil {
+		t.Fatalf("recargar usuario: %v", err)
+	}
+	if user.RoleID != defaultRole.ID {
+		t.Errorf("role_id = %d, se esperaba %d", user.RoleID, defaultRole.ID)
+	}
+}
+
+func TestRoleNamesArePerOrganization(t *testing.T) {
+	db := newTestDB(t)
+	cache := NewCacheService(time.Minute, 0)
+
+	// Dos organizaciones con un rol propio del mismo nombre y permisos distintos
+	reviewers := map[uint]models.Role{}
+	for tenantID, permission := range map[uint]string{101: "posts:publish", 102: "posts:hard_delete"} {
+		tenantDB := db.WithContext(tenancy.WithTenant(context.Background(), tenantID))
+		if err := NewRoleService(tenantDB, cache).EnsureNameAvailable("revisor", 0); err != nil {
+			t.Fatalf("EnsureNameAvailable en la organización %d: %v", tenantID, err)
+		}
+		role := models.Role{Name: "revisor"}
+		if err := tenantDB.Create(&role).Error; err != nil {
+			t.Fatalf("crear rol en la organización %d: %v", tenantID, err)
+		}
+		if _, err := NewPermissionService(tenantDB, cache).Grant(role.ID, []string{permission}); err != nil {
+			t.Fatalf("Grant: %v", err)
+		}
+		reviewers[tenantID] = role
+	}
+
+	permissions := NewPermissionService(tenancy.Unscoped(db), cache)
+	for tenantID, expected := range map[uint][2]bool{101: {true, false}, 102: {false, true}} {
+		role := reviewers[tenantID]
+		publish, err := permissions.HasPermission(role.ID, "posts:publish")
+		if err != nil {
+			t.Fatalf("HasPermission: %v", err)
+		}
+		hardDelete, err := permissions.HasPermission(role.ID, "posts:hard_delete")
+		if err != nil {
+			t.Fatalf("HasPermission: %v", err)
+		}
+		if publish != expected[0] || hardDelete != expected[1] {
+			t.Errorf("organización %d: publish=%v hard_delete=%v, se esperaba %v", tenantID, publish, hardDelete, expected)
+		}
+	}
+
+	// Un rol propio no puede llamarse como un rol global ni repetir nombre en la organización
+	tenantDB := db.WithContext(tenancy.WithTenant(context.Background(), 101))
+	var apiErr *APIError
+	for _, name := range []string{AdminRole, "revisor"} {
+		if err := NewRoleService(tenantDB, cache).EnsureNameAvailable(name, 0); !errors.As(err, &apiErr) || apiErr.Code != "INVALID_INPUT" {
+			t.Errorf("EnsureNameAvailable(%q) = %v, se esperaba INVALID_INPUT", name, err)
+		}
+	}
+
+	// Un rol propio llamado como un rol global no hereda sus privilegios
+	impostor := models.Role{Name: AdminRole, TenantID: 103}
+	if err := tenancy.Unscoped(db).Create(&impostor).Error; err != nil {
+		t.Fatalf("crear rol: %v", err)
+	}
+	admin, err := NewRoleService(tenancy.Unscoped(db), cache).InheritsFrom(impostor.ID, AdminRole)
+	if err != nil {
+		t.Fatalf("InheritsFrom: %v", err)
+	}
+	if admin {
+		t.Error("un rol de una organización llamado admin no debe considerarse administrador")
+	}
+}
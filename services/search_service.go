@@ -60,42 +60,88 @@ func ExtractSortParams(c *gin.Context) map[string]string {
 	return sort
 }
 
-// ApplySearchFilters aplica los filtros de búsqueda al query
-func ApplySearchFilters(db *gorm.DB, filters []map[string]string) *gorm.DB {
+// SearchRelation declara un campo de búsqueda que se compara con un modelo relacionado:
+// la fila coincide si alguno de sus registros relacionados cumple el filtro
+type SearchRelation struct {
+	Field    string // nombre del campo en el parámetro search, p. ej. tag
+	Column   string // columna de la consulta principal, p. ej. posts.id
+	Subquery string // SELECT de los valores de Column unidos al modelo relacionado, sin WHERE
+	Target   string // columna del modelo relacionado que se compara, p. ej. tags.slug
+}
+
+// negatedOperators son los operadores que en una relación se aplican como NOT IN del operador positivo
+var negatedOperators = map[string]string{
+	"ne":    "eq",
+	"nlike": "like",
+	"nin":   "in",
+}
+
+// ApplySearchFilters aplica los filtros de búsqueda al query. Los campos declarados en
+// relations se filtran con una subconsulta sobre el modelo relacionado.
+func ApplySearchFilters(db *gorm.DB, filters []map[string]string, relations ...SearchRelation) *gorm.DB {
 	for _, filter := range filters {
 		field := filter["field"]
 		operator := filter["operator"]
 		value := filter["value"]
 
-		switch operator {
-		case "eq":
-			db = db.Where(field+" = ?", value)
-		case "ne":
-			db = db.Where(field+" != ?", value)
-		case "like":
-			db = db.Where(field+" LIKE ?", "%"+value+"%")
-		case "nlike":
-			db = db.Where(field+" NOT LIKE ?", "%"+value+"%")
-		case "in":
-			values := strings.Split(value, ",")
-			db = db.Where(field+" IN ?", values)
-		case "nin":
-			values := strings.Split(value, ",")
-			db = db.Where(field+" NOT IN ?", values)
-		case "gt":
-			db = db.Where(field+" > ?", value)
-		case "gte":
-			db = db.Where(field+" >= ?", value)
-		case "lt":
-			db = db.Where(field+" < ?", value)
-		case "lte":
-			db = db.Where(field+" <= ?", value)
+		relation, isRelation := findRelation(relations, field)
+		if !isRelation {
+			if condition, arg, ok := searchCondition(field, operator, value); ok {
+				db = db.Where(condition, arg)
+			}
+			continue
+		}
+
+		membership := " IN "
+		if positive, negated := negatedOperators[operator]; negated {
+			membership = " NOT IN "
+			operator = positive
+		}
+		if condition, arg, ok := searchCondition(relation.Target, operator, value); ok {
+			db = db.Where(relation.Column+membership+"("+relation.Subquery+" WHERE "+condition+")", arg)
 		}
 	}
 
 	return db
 }
 
+// searchCondition construye la condición de un filtro sobre la columna field
+func searchCondition(field, operator, value string) (string, interface{}, bool) {
+	switch operator {
+	case "eq":
+		return field + " = ?", value, true
+	case "ne":
+		return field + " != ?", value, true
+	case "like":
+		return field + " LIKE ?", "%" + value + "%", true
+	case "nlike":
+		return field + " NOT LIKE ?", "%" + value + "%", true
+	case "in":
+		return field + " IN ?", strings.Split(value, ","), true
+	case "nin":
+		return field + " NOT IN ?", strings.Split(value, ","), true
+	case "gt":
+		return field + " > ?", value, true
+	case "gte":
+		return field + " >= ?", value, true
+	case "lt":
+		return field + " < ?", value, true
+	case "lte":
+		return field + " <= ?", value, true
+	}
+	return "", nil, false
+}
+
+// findRelation busca la relación declarada para el campo de búsqueda
+func findRelation(relations []SearchRelation, field string) (SearchRelation, bool) {
+	for _, relation := range relations {
+		if relation.Field == field {
+			return relation, true
+		}
+	}
+	return SearchRelation{}, false
+}
+
 // ApplySorting aplica el ordenamiento al query
 func ApplySorting(db *gorm.DB, sort map[string]string) *gorm.DB {
 	for field, direction := range sort {
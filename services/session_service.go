@@ -0,0 +1,212 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go-api-orm/models"
+	"gorm.io/gorm"
+)
+
+// sessionSeenResolution evita escribir last_seen_at en cada petición autenticada
+const sessionSeenResolution = time.Minute
+
+// SessionService maneja las sesiones (dispositivos) en las que un usuario inició sesión.
+// La sesión dura lo mismo que su token de refresco y se extiende con cada rotación.
+type SessionService struct {
+	db    *gorm.DB
+	cache *CacheService
+	ttl   time.Duration
+}
+
+// NewSessionService crea una nueva instancia del servicio de sesiones
+func NewSessionService(db *gorm.DB, cache *CacheService) *SessionService {
+	return &SessionService{
+		db:    db,
+		cache: cache,
+		ttl:   NewRefreshTokenService(db).TTL(),
+	}
+}
+
+// ErrSessionTerminated se retorna cuando el token pertenece a una sesión cerrada o expirada
+var ErrSessionTerminated = func() *APIError {
+	return ErrUnauthorized("La sesión ha sido cerrada")
+}
+
+func sessionStateKey(sessionID uint) string {
+	return fmt.Sprintf("session:%d", sessionID)
+}
+
+// Create registra una nueva sesión para el usuario
+func (s *SessionService) Create(userID uint, userAgent, ip string) (*models.Session, error) {
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+
+	now := time.Now()
+	session := models.Session{
+		UserID:     userID,
+		UserAgent:  userAgent,
+		IPAddress:  ip,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.ttl),
+	}
+	if err := s.db.Create(&session).Error; err != nil {
+		return nil, err
+	}
+
+	s.cache.Set(sessionStateKey(session.ID), session)
+	return &session, nil
+}
+
+// List retorna las sesiones activas de un usuario, de la más reciente a la más antigua
+func (s *SessionService) List(userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+	err := s.db.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// Validate comprueba que la sesión siga activa y registra la actividad
+func (s *SessionService) Validate(sessionID, userID uint, ip string) error {
+	session, err := s.get(sessionID)
+	if err != nil {
+		return err
+	}
+	if session.UserID != userID || !session.IsActive() {
+		return ErrSessionTerminated()
+	}
+
+	now := time.Now()
+	if now.Sub(session.LastSeenAt) >= sessionSeenResolution {
+		if err := s.db.Model(&models.Session{}).Where("id = ?", session.ID).Updates(map[string]interface{}{
+			"last_seen_at": now,
+			"ip_address":   ip,
+		}).Error; err != nil {
+			return err
+		}
+		session.LastSeenAt = now
+		session.IPAddress = ip
+		s.cache.Set(sessionStateKey(session.ID), *session)
+	}
+
+	return nil
+}
+
+// Extend prolonga la sesión tras rotar su token de refresco
+func (s *SessionService) Extend(sessionID uint, ip string) error {
+	now := time.Now()
+	if err := s.db.Model(&models.Session{}).Where("id = ?", sessionID).Updates(map[string]interface{}{
+		"last_seen_at": now,
+		"expires_at":   now.Add(s.ttl),
+		"ip_address":   ip,
+	}).Error; err != nil {
+		return err
+	}
+
+	s.cache.Delete(sessionStateKey(sessionID))
+	return nil
+}
+
+// Revoke cierra una sesión del usuario y revoca sus tokens de refresco
+func (s *SessionService) Revoke(userID, sessionID uint) error {
+	result := s.db.Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound("Sesión")
+	}
+
+	s.cache.Delete(sessionStateKey(sessionID))
+	return NewRefreshTokenService(s.db).RevokeSession(sessionID)
+}
+
+// RevokeOthers cierra todas las sesiones del usuario excepto la indicada
+func (s *SessionService) RevokeOthers(userID, currentID uint) (int, error) {
+	var sessions []models.Session
+	if err := s.db.Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, currentID).Find(&sessions).Error; err != nil {
+		return 0, err
+	}
+
+	for _, session := range sessions {
+		if err := s.Revoke(userID, session.ID); err != nil && !isNotFound(err) {
+			return 0, err
+		}
+	}
+	return len(sessions), nil
+}
+
+// RevokeAllForUser marca como cerradas todas las sesiones de un usuario
+func (s *SessionService) RevokeAllForUser(userID uint) error {
+	_, err := s.RevokeOthers(userID, 0)
+	return err
+}
+
+// RevokeForOrganization cierra las sesiones del usuario que actúan en la organización indicada,
+// p. ej. al cambiar su rol de miembro o quitarle el acceso. Sus demás sesiones siguen abiertas.
+func (s *SessionService) RevokeForOrganization(userID, organizationID uint) error {
+	var sessions []models.Session
+	if err := s.db.Where("user_id = ? AND organization_id = ? AND revoked_at IS NULL", userID, organizationID).Find(&sessions).Error; err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if err := s.Revoke(userID, session.ID); err != nil && !isNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Organization retorna la organización activa de la sesión (0: la organización del usuario)
+func (s *SessionService) Organization(sessionID uint) (uint, error) {
+	session, err := s.get(sessionID)
+	if err != nil {
+		return 0, err
+	}
+	return session.OrganizationID, nil
+}
+
+// SetOrganization cambia la organización activa de la sesión, que se mantiene al refrescar el token
+func (s *SessionService) SetOrganization(sessionID, organizationID uint) error {
+	if err := s.db.Model(&models.Session{}).Where("id = ?", sessionID).Update("organization_id", organizationID).Error; err != nil {
+		return err
+	}
+
+	s.cache.Delete(sessionStateKey(sessionID))
+	return nil
+}
+
+// PurgeExpired elimina las sesiones cerradas o expiradas
+func (s *SessionService) PurgeExpired() error {
+	return s.db.Where("expires_at < ? OR revoked_at IS NOT NULL", time.Now()).Delete(&models.Session{}).Error
+}
+
+// get obtiene la sesión de la caché o de la base de datos
+func (s *SessionService) get(sessionID uint) (*models.Session, error) {
+	if session, found := GetTyped[models.Session](s.cache, sessionStateKey(sessionID)); found {
+		return &session, nil
+	}
+
+	var session models.Session
+	if err := s.db.First(&session, sessionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSessionTerminated()
+		}
+		return nil, err
+	}
+
+	s.cache.Set(sessionStateKey(sessionID), session)
+	return &session, nil
+}
+
+func isNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == "NOT_FOUND"
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go-api-orm/models"
+)
+
+func isSessionTerminated(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Detail == ErrSessionTerminated().Detail
+}
+
+func TestSessionValidateAndRevoke(t *testing.T) {
+	db := newTestDB(t)
+	cache := NewCacheService(time.Minute, 0)
+	sessions := NewSessionService(db, cache)
+
+	session, err := sessions.Create(1, "navegador", "10.0.0.1")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := sessions.Validate(session.ID, 1, "10.0.0.1"); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if err := sessions.Validate(session.ID, 2, "10.0.0.1"); !isSessionTerminated(err) {
+		t.Errorf("Validate de la sesión de otro usuario = %v", err)
+	}
+	if err := sessions.Validate(session.ID+100, 1, "10.0.0.1"); !isSessionTerminated(err) {
+		t.Errorf("Validate de una sesión inexistente = %v", err)
+	}
+
+	// Un usuario no puede cerrar la sesión de otro
+	if err := sessions.Revoke(2, session.ID); !isNotFound(err) {
+		t.Errorf("Revoke de la sesión de otro usuario = %v", err)
+	}
+	if err := sessions.Revoke(1, session.ID); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if err := sessions.Revoke(1, session.ID); !isNotFound(err) {
+		t.Errorf("Revoke de una sesión ya cerrada = %v", err)
+	}
+	// La caché se invalida: la sesión cerrada deja de validar de inmediato
+	if err := sessions.Validate(session.ID, 1, "10.0.0.1"); !isSessionTerminated(err) {
+		t.Errorf("Validate de una sesión cerrada = %v", err)
+	}
+	if active, err := sessions.List(1); err != nil || len(active) != 0 {
+		t.Errorf("List = %v, %v; no se esperaban sesiones activas", active, err)
+	}
+}
+
+func TestRevokeOthersKeepsTheCurrentSessionAndAPIKeys(t *testing.T) {
+	db := newTestDB(t)
+	cache := NewCacheService(time.Minute, 0)
+	sessions := NewSessionService(db, cache)
+	refresh := NewRefreshTokenService(db)
+	apiTokens := NewAPITokenService(db, cache)
+
+	current, err := sessions.Create(1, "portátil", "10.0.0.1")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	other, err := sessions.Create(1, "móvil", "10.0.0.2")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	foreign, err := sessions.Create(2, "otro usuario", "10.0.0.3")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	refreshTokens := map[uint]string{}
+	for _, session := range []*models.Session{current, other, foreign} {
+		token, err := refresh.Issue(session.UserID, &session.ID)
+		if err != nil {
+			t.Fatalf("Issue: %v", err)
+		}
+		refreshTokens[session.ID] = token
+	}
+	apiKey, _, err := apiTokens.Create(1, "ci", []string{APITokenScopeWrite}, 0)
+	if err != nil {
+		t.Fatalf("crear clave: %v", err)
+	}
+
+	if err := NewTokenRevocationService(db, cache).RevokeOthersForUser(1, current.ID); err != nil {
+		t.Fatalf("RevokeOthersForUser: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		session *models.Session
+		active  bool
+	}{
+		{"la sesión actual", current, true},
+		{"la otra sesión del usuario", other, false},
+		{"la sesión de otro usuario", foreign, true},
+	} {
+		if err := sessions.Validate(tc.session.ID, tc.session.UserID, "10.0.0.9"); (err == nil) != tc.active {
+			t.Errorf("%s: Validate = %v, se esperaba activa=%v", tc.name, err, tc.active)
+		}
+		if _, _, err := refresh.Rotate(refreshTokens[tc.session.ID]); (err == nil) != tc.active {
+			t.Errorf("%s: Rotate = %v, se esperaba activo=%v", tc.name, err, tc.active)
+		}
+	}
+
+	// Las claves de API no pertenecen a ninguna sesión y siguen funcionando
+	if _, err := apiTokens.Authenticate(apiKey); err != nil {
+		t.Errorf("la clave de API dejó de funcionar: %v", err)
+	}
+
+	if active, err := sessions.List(1); err != nil || len(active) != 1 || active[0].ID != current.ID {
+		t.Errorf("List = %v, %v; se esperaba solo la sesión actual", active, err)
+	}
+}
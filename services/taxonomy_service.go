@@ -0,0 +1,278 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go-api-orm/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PostTaxonomyRelations permite filtrar los posts por el slug de sus etiquetas y categorías,
+// p. ej. search=tag:in:go,api o search=category:eq:backend
+var PostTaxonomyRelations = []SearchRelation{
+	{
+		Field:    "tag",
+		Column:   "posts.id",
+		Subquery: "SELECT post_tags.post_id FROM post_tags JOIN tags ON tags.id = post_tags.tag_id",
+		Target:   "tags.slug",
+	},
+	{
+		Field:    "category",
+		Column:   "posts.id",
+		Subquery: "SELECT post_categories.post_id FROM post_categories JOIN categories ON categories.id = post_categories.category_id",
+		Target:   "categories.slug",
+	},
+}
+
+// TaxonomyService gestiona las etiquetas y las categorías de los posts
+type TaxonomyService struct {
+	db *gorm.DB
+}
+
+// NewTaxonomyService crea una nueva instancia del servicio de etiquetas y categorías
+func NewTaxonomyService(db *gorm.DB) *TaxonomyService {
+	return &TaxonomyService{db: db}
+}
+
+// ErrCategoryCycle se retorna cuando la categoría padre indicada crearía un ciclo en la jerarquía
+var ErrCategoryCycle = func(detail string) *APIError {
+	return NewAPIError(
+		http.StatusBadRequest,
+		"CATEGORY_HIERARCHY_CYCLE",
+		"La jerarquía de categorías no puede tener ciclos",
+		detail,
+		nil,
+	)
+}
+
+// ResolveTags retorna las etiquetas con los nombres indicados y crea las que no existen.
+// Los nombres con el mismo slug son la misma etiqueta.
+func (s *TaxonomyService) ResolveTags(names []string) ([]models.Tag, error) {
+	tags := make([]models.Tag, 0, len(names))
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		slug := models.GenerateSlug(name)
+		if slug == "" || len(name) > 50 {
+			return nil, ErrInvalidInput(fmt.Sprintf("La etiqueta %q no es válida", name))
+		}
+		if seen[slug] {
+			continue
+		}
+		seen[slug] = true
+
+		var tag models.Tag
+		err := s.db.Where("slug = ?", slug).First(&tag).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			tag, err = s.createTag(name, slug)
+		}
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// createTag crea la etiqueta o, si otra petición la creó a la vez, retorna la existente.
+// ON CONFLICT DO NOTHING evita que el índice único aborte la transacción en curso y la
+// lectura con bloqueo ve la etiqueta aunque la haya confirmado otra transacción.
+func (s *TaxonomyService) createTag(name, slug string) (models.Tag, error) {
+	tag := models.Tag{Name: name, Slug: slug}
+	result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&tag)
+	if result.Error != nil || result.RowsAffected > 0 {
+		return tag, result.Error
+	}
+
+	tag = models.Tag{}
+	err := s.db.Clauses(clause.Locking{Strength: "SHARE"}).Where("slug = ?", slug).First(&tag).Error
+	return tag, err
+}
+
+// ResolveCategories retorna las categorías con los slugs indicados; todas deben existir
+func (s *TaxonomyService) ResolveCategories(slugs []string) ([]models.Category, error) {
+	categories := []models.Category{}
+	if len(slugs) == 0 {
+		return categories, nil
+	}
+	if err := s.db.Where("slug IN ?", slugs).Find(&categories).Error; err != nil {
+		return nil, err
+	}
+
+	found := map[string]bool{}
+	for _, category := range categories {
+		found[category.Slug] = true
+	}
+	for _, slug := range slugs {
+		if !found[slug] {
+			return nil, ErrInvalidInput(fmt.Sprintf("La categoría %s no existe", slug))
+		}
+	}
+	return categories, nil
+}
+
+// SetPostTags reemplaza las etiquetas del post
+func (s *TaxonomyService) SetPostTags(post *models.Post, tags []models.Tag) error {
+	return s.db.Model(post).Association("Tags").Replace(tags)
+}
+
+// SetPostCategories reemplaza las categorías del post
+func (s *TaxonomyService) SetPostCategories(post *models.Post, categories []models.Category) error {
+	return s.db.Model(post).Association("Categories").Replace(categories)
+}
+
+// EnsureSlugAvailable comprueba que ningún otro registro de model (una etiqueta o una
+// categoría) de la organización use el slug
+func (s *TaxonomyService) EnsureSlugAvailable(model interface{}, slug string, id uint) error {
+	if !NewTransformService().ValidateSlug(slug) {
+		return ErrInvalidInput("Slug inválido")
+	}
+
+	var count int64
+	if err := s.db.Model(model).Where("slug = ? AND id != ?", slug, id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrInvalidInput("Slug ya existe")
+	}
+	return nil
+}
+
+// ValidateParent comprueba que la categoría padre existe y que asignarla a la categoría
+// categoryID (0 si es nueva) no crea un ciclo
+func (s *TaxonomyService) ValidateParent(categoryID uint, parentID *uint) error {
+	if parentID == nil {
+		return nil
+	}
+	if *parentID == categoryID {
+		return ErrCategoryCycle("Una categoría no puede ser subcategoría de sí misma")
+	}
+
+	visited := map[uint]bool{}
+	current := *parentID
+	for {
+		var category models.Category
+		err := s.db.First(&category, current).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if current == *parentID {
+				return ErrInvalidInput(fmt.Sprintf("La categoría padre %d no existe", *parentID))
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if category.ID == categoryID {
+			return ErrCategoryCycle(fmt.Sprintf("La categoría %d ya es subcategoría de la categoría %d", *parentID, categoryID))
+		}
+		visited[category.ID] = true
+		if category.ParentID == nil || visited[*category.ParentID] {
+			return nil
+		}
+		current = *category.ParentID
+	}
+}
+
+// DeleteTag elimina la etiqueta y la quita de sus posts
+func (s *TaxonomyService) DeleteTag(tag *models.Tag) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM post_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(tag).Error
+	})
+}
+
+// DeleteCategory elimina la categoría y la quita de sus posts. Sus subcategorías pasan a
+// depender de la categoría padre de la eliminada.
+func (s *TaxonomyService) DeleteCategory(category *models.Category) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Category{}).
+			Where("parent_id = ?", category.ID).
+			Update("parent_id", category.ParentID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM post_categories WHERE category_id = ?", category.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(category).Error
+	})
+}
+
+// ExpandCategoryFilters retorna una copia de los filtros en la que category:eq, ne, in y nin
+// incluyen las subcategorías: filtrar por una categoría retorna también los posts de sus descendientes
+func (s *TaxonomyService) ExpandCategoryFilters(filters []map[string]string) ([]map[string]string, error) {
+	var categories []models.Category
+	loaded := false
+
+	expanded := make([]map[string]string, 0, len(filters))
+	for _, original := range filters {
+		if original["field"] != "category" {
+			expanded = append(expanded, original)
+			continue
+		}
+		filter := map[string]string{"field": original["field"], "operator": original["operator"], "value": original["value"]}
+		expanded = append(expanded, filter)
+
+		operator := filter["operator"]
+		switch operator {
+		case "eq":
+			operator = "in"
+		case "ne":
+			operator = "nin"
+		case "in", "nin":
+		default:
+			continue
+		}
+
+		if !loaded {
+			if err := s.db.Select("id", "slug", "parent_id").Find(&categories).Error; err != nil {
+				return nil, err
+			}
+			loaded = true
+		}
+
+		filter["operator"] = operator
+		filter["value"] = strings.Join(descendantSlugs(categories, strings.Split(filter["value"], ",")), ",")
+	}
+	return expanded, nil
+}
+
+// descendantSlugs retorna los slugs indicados y los de todas sus subcategorías
+func descendantSlugs(categories []models.Category, slugs []string) []string {
+	children := map[uint][]models.Category{}
+	for _, category := range categories {
+		if category.ParentID != nil {
+			children[*category.ParentID] = append(children[*category.ParentID], category)
+		}
+	}
+
+	result := append([]string{}, slugs...)
+	seen := map[uint]bool{}
+	var queue []models.Category
+	for _, category := range categories {
+		for _, slug := range slugs {
+			if category.Slug == slug {
+				queue = append(queue, category)
+			}
+		}
+	}
+	for len(queue) > 0 {
+		category := queue[0]
+		queue = queue[1:]
+		if seen[category.ID] {
+			continue
+		}
+		seen[category.ID] = true
+		for _, child := range children[category.ID] {
+			result = append(result, child.Slug)
+			queue = append(queue, child)
+		}
+	}
+	return result
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"go-api-orm/models"
+	"go-api-orm/tenancy"
+	"gorm.io/gorm"
+)
+
+func TestResolveTagsReusesATagCreatedConcurrently(t *testing.T) {
+	db := newTestDB(t)
+	home, err := NewOrganizationService(tenancy.Unscoped(db), NewCacheService(time.Minute, 0)).Default()
+	if err != nil {
+		t.Fatalf("Default: %v", err)
+	}
+	tenantDB := db.WithContext(tenancy.WithTenant(context.Background(), home.ID))
+
+	// Otra petición crea la etiqueta entre la búsqueda y el INSERT de ResolveTags. SQLite admite
+	// un único escritor, así que se inserta en la misma conexión de la transacción.
+	var concurrent models.Tag
+	created := false
+	if err := db.Callback().Create().Before("gorm:create").Register("test:concurrent_tag", func(tx *gorm.DB) {
+		if tag, ok := tx.Statement.Dest.(*models.Tag); ok && !created && tag.Slug == "go" {
+			created = true
+			concurrent = models.Tag{Name: "Go", Slug: "go"}
+			if err := tx.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Create(&concurrent).Error; err != nil {
+				t.Errorf("crear la etiqueta concurrente: %v", err)
+			}
+		}
+	}); err != nil {
+		t.Fatalf("registrar callback: %v", err)
+	}
+
+	// SQLite no admite otro escritor durante una transacción, así que ResolveTags se llama sin ella
+	tags, err := NewTaxonomyService(tenantDB).ResolveTags([]string{"go", "API"})
+	if err != nil {
+		t.Fatalf("ResolveTags: %v", err)
+	}
+	if len(tags) != 2 || tags[0].ID != concurrent.ID || tags[1].Slug != "api" || tags[1].ID == 0 {
+		t.Errorf("ResolveTags = %+v, se esperaba la etiqueta %d creada a la vez", tags, concurrent.ID)
+	}
+
+	var count int64
+	if err := tenantDB.Model(&models.Tag{}).Where("slug = ?", "go").Count(&count).Error; err != nil || count != 1 {
+		t.Errorf("hay %d etiquetas go (%v)", count, err)
+	}
+}
+
+func TestExpandCategoryFiltersIncludesSubcategories(t *testing.T) {
+	db := tenancy.Unscoped(newTestDB(t))
+	backend := models.Category{Name: "Backend", Slug: "backend"}
+	frontend := models.Category{Name: "Frontend", Slug: "frontend"}
+	for _, category := range []*models.Category{&backend, &frontend} {
+		if err := db.Create(category).Error; err != nil {
+			t.Fatalf("crear categoría: %v", err)
+		}
+	}
+	golang := models.Category{Name: "Go", Slug: "go", ParentID: &backend.ID}
+	if err := db.Create(&golang).Error; err != nil {
+		t.Fatalf("crear categoría: %v", err)
+	}
+	gin := models.Category{Name: "Gin", Slug: "gin", ParentID: &golang.ID}
+	if err := db.Create(&gin).Error; err != nil {
+		t.Fatalf("crear categoría: %v", err)
+	}
+
+	filters := []map[string]string{
+		{"field": "category", "operator": "eq", "value": "backend"},
+		{"field": "category", "operator": "ne", "value": "go"},
+		{"field": "category", "operator": "in", "value": "gin,frontend"},
+		{"field": "category", "operator": "nin", "value": "backend,desconocida"},
+		{"field": "category", "operator": "like", "value": "end"},
+		{"field": "title", "operator": "eq", "value": "backend"},
+	}
+	expanded, err := NewTaxonomyService(db).ExpandCategoryFilters(filters)
+	if err != nil {
+		t.Fatalf("ExpandCategoryFilters: %v", err)
+	}
+
+	expected := []struct {
+		operator string
+		values   []string
+	}{
+		{"in", []string{"backend", "gin", "go"}},
+		{"nin", []string{"gin", "go"}},
+		{"in", []string{"frontend", "gin"}},
+		{"nin", []string{"backend", "desconocida", "gin", "go"}},
+		{"like", []string{"end"}},
+		{"eq", []string{"backend"}},
+	}
+	for i, filter := range expanded {
+		values := splitValues(filter["value"])
+		if filter["operator"] != expected[i].operator || !reflect.DeepEqual(values, expected[i].values) {
+			t.Errorf("filtro %v = %s %v, se esperaba %s %v", filters[i], filter["operator"], values, expected[i].operator, expected[i].values)
+		}
+	}
+	// Los filtros originales se siguen usando para la respuesta y no deben cambiar
+	if filters[0]["operator"] != "eq" || filters[0]["value"] != "backend" {
+		t.Errorf("ExpandCategoryFilters modificó los filtros originales: %v", filters[0])
+	}
+}
+
+func TestTaxonomyRelationFilters(t *testing.T) {
+	db := tenancy.Unscoped(newTestDB(t))
+	taxonomy := NewTaxonomyService(db)
+
+	tags, err := taxonomy.ResolveTags([]string{"go", "api", "web"})
+	if err != nil {
+		t.Fatalf("ResolveTags: %v", err)
+	}
+	backend := models.Category{Name: "Backend", Slug: "backend"}
+	if err := db.Create(&backend).Error; err != nil {
+		t.Fatalf("crear categoría: %v", err)
+	}
+	golang := models.Category{Name: "Go", Slug: "golang", ParentID: &backend.ID}
+	if err := db.Create(&golang).Error; err != nil {
+		t.Fatalf("crear categoría: %v", err)
+	}
+
+	for _, post := range []models.Post{
+		{Title: "Go y API", Slug: "go-y-api", Tags: []models.Tag{tags[0], tags[1]}, Categories: []models.Category{golang}},
+		{Title: "Solo API", Slug: "solo-api", Tags: []models.Tag{tags[1]}, Categories: []models.Category{backend}},
+		{Title: "Web", Slug: "web", Tags: []models.Tag{tags[2]}},
+		{Title: "Sin etiquetas", Slug: "sin-etiquetas"},
+	} {
+		post.Content = "Contenido"
+		post.AuthorID = 1
+		if err := db.Create(&post).Error; err != nil {
+			t.Fatalf("crear post: %v", err)
+		}
+	}
+
+	for _, tc := range []struct {
+		filter   map[string]string
+		expected []string
+	}{
+		{map[string]string{"field": "tag", "operator": "eq", "value": "go"}, []string{"go-y-api"}},
+		{map[string]string{"field": "tag", "operator": "in", "value": "go,web"}, []string{"go-y-api", "web"}},
+		// Un post con varias etiquetas queda excluido si cualquiera coincide, y los posts sin etiquetas se incluyen
+		{map[string]string{"field": "tag", "operator": "ne", "value": "go"}, []string{"sin-etiquetas", "solo-api", "web"}},
+		{map[string]string{"field": "tag", "operator": "nin", "value": "go,api"}, []string{"sin-etiquetas", "web"}},
+		{map[string]string{"field": "tag", "operator": "nlike", "value": "p"}, []string{"sin-etiquetas", "web"}},
+		// Filtrar por una categoría incluye sus subcategorías
+		{map[string]string{"field": "category", "operator": "eq", "value": "backend"}, []string{"go-y-api", "solo-api"}},
+		{map[string]string{"field": "category", "operator": "ne", "value": "backend"}, []string{"sin-etiquetas", "web"}},
+		{map[string]string{"field": "category", "operator": "nin", "value": "golang"}, []string{"sin-etiquetas", "solo-api", "web"}},
+	} {
+		filters, err := taxonomy.ExpandCategoryFilters([]map[string]string{tc.filter})
+		if err != nil {
+			t.Fatalf("ExpandCategoryFilters: %v", err)
+		}
+		var slugs []string
+		if err := ApplySearchFilters(db.Model(&models.Post{}), filters, PostTaxonomyRelations...).Order("slug").Pluck("slug", &slugs).Error; err != nil {
+			t.Fatalf("%v: %v", tc.filter, err)
+		}
+		if !reflect.DeepEqual(slugs, tc.expected) {
+			t.Errorf("%s:%s:%s = %v, se esperaba %v", tc.filter["field"], tc.filter["operator"], tc.filter["value"], slugs, tc.expected)
+		}
+	}
+}
+
+// splitValues retorna los valores de un filtro ordenados
+func splitValues(value string) []string {
+	values := strings.Split(value, ",")
+	sort.Strings(values)
+	return values
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go-api-orm/models"
+	"go-api-orm/tenancy"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TokenRevocationService mantiene la lista de tokens de acceso revocados.
+// La base de datos es la fuente de verdad y la caché evita una consulta por petición.
+type TokenRevocationService struct {
+	db    *gorm.DB
+	cache *CacheService
+}
+
+// NewTokenRevocationService crea una nueva instancia del servicio de revocación
+func NewTokenRevocationService(db *gorm.DB, cache *CacheService) *TokenRevocationService {
+	return &TokenRevocationService{
+		db:    db,
+		cache: cache,
+	}
+}
+
+func revokedJTIKey(jti string) string {
+	return "revoked_jti:" + jti
+}
+
+func userRevokedAtKey(userID uint) string {
+	return fmt.Sprintf("tokens_revoked_at:%d", userID)
+}
+
+// ErrMissingJTI se retorna al revocar un token que no tiene jti
+var ErrMissingJTI = errors.New("el token no tiene jti")
+
+// RevokeToken revoca un token de acceso concreto hasta su expiración
+func (s *TokenRevocationService) RevokeToken(jti string, userID uint, expiresAt time.Time) error {
+	if jti == "" {
+		return ErrMissingJTI
+	}
+
+	record := models.RevokedToken{
+		JTI:       jti,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record).Error; err != nil {
+		return err
+	}
+
+	s.cache.SetWithTTL(revokedJTIKey(jti), true, time.Until(expiresAt))
+	return nil
+}
+
+// RevokeAllForUser revoca todos los tokens de acceso, de refresco y claves de API emitidos a un usuario
+// y cierra todas sus sesiones
+func (s *TokenRevocationService) RevokeAllForUser(userID uint) error {
+	if err := s.RevokeSessionsForUser(userID); err != nil {
+		return err
+	}
+	return NewAPITokenService(s.db, s.cache).RevokeAllForUser(userID)
+}
+
+// RevokeSessionsForUser revoca los tokens de acceso y de refresco de un usuario y cierra todas sus sesiones.
+// Sus claves de API se mantienen: el rol se lee del usuario en cada petición hecha con ellas.
+func (s *TokenRevocationService) RevokeSessionsForUser(userID uint) error {
+	now := time.Now()
+	if err := tenancy.Unscoped(s.db).Model(&models.User{}).Where("id = ?", userID).Update("tokens_revoked_at", now).Error; err != nil {
+		return err
+	}
+
+	s.cache.Set(userRevokedAtKey(userID), now)
+	if err := NewRefreshTokenService(s.db).RevokeAllForUser(userID); err != nil {
+		return err
+	}
+	return NewSessionService(s.db, s.cache).RevokeAllForUser(userID)
+}
+
+// RevokeOthersForUser cierra todas las sesiones del usuario salvo la indicada y revoca sus tokens de refresco.
+// Los tokens de acceso de las demás sesiones dejan de ser válidos al cerrarse su sesión; las claves de API
+// no pertenecen a ninguna sesión y se mantienen.
+func (s *TokenRevocationService) RevokeOthersForUser(userID, keepSessionID uint) error {
+	if err := NewRefreshTokenService(s.db).RevokeAllExceptSession(userID, keepSessionID); err != nil {
+		return err
+	}
+	_, err := NewSessionService(s.db, s.cache).RevokeOthers(userID, keepSessionID)
+	return err
+}
+
+// IsRevoked indica si un token fue revocado, ya sea individualmente o por una revocación global del usuario
+func (s *TokenRevocationService) IsRevoked(jti string, userID uint, issuedAt time.Time) (bool, error) {
+	if jti != "" {
+		revoked, err := s.isJTIRevoked(jti)
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+
+	revokedAt, err := s.userRevokedAt(userID)
+	if err != nil {
+		return false, err
+	}
+
+	// iat tiene precisión de segundos: un token emitido en el mismo segundo de la revocación se considera revocado
+	return !revokedAt.IsZero() && issuedAt.Unix() <= revokedAt.Unix(), nil
+}
+
+// PurgeExpired elimina los registros de tokens revocados que ya expiraron
+func (s *TokenRevocationService) PurgeExpired() error {
+	return s.db.Where("expires_at < ?", time.Now()).Delete(&models.RevokedToken{}).Error
+}
+
+func (s *TokenRevocationService) isJTIRevoked(jti string) (bool, error) {
+	if revoked, found := GetTyped[bool](s.cache, revokedJTIKey(jti)); found {
+		return revoked, nil
+	}
+
+	var count int64
+	if err := s.db.Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	s.cache.Set(revokedJTIKey(jti), count > 0)
+	return count > 0, nil
+}
+
+func (s *TokenRevocationService) userRevokedAt(userID uint) (time.Time, error) {
+	if revokedAt, found := GetTyped[time.Time](s.cache, userRevokedAtKey(userID)); found {
+		return revokedAt, nil
+	}
+
+	var user models.User
+	if err := tenancy.Unscoped(s.db).Unscoped().Select("id", "tokens_revoked_at").First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+
+	var revokedAt time.Time
+	if user.TokensRevokedAt != nil {
+		revokedAt = *user.TokensRevokedAt
+	}
+
+	s.cache.Set(userRevokedAtKey(userID), revokedAt)
+	return revokedAt, nil
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go-api-orm/models"
+	"go-api-orm/tenancy"
+)
+
+func TestRevokedTokensAreDeniedByJTI(t *testing.T) {
+	db := newTestDB(t)
+	revocations := NewTokenRevocationService(db, NewCacheService(time.Minute, 0))
+	issuedAt := time.Now()
+
+	if err := revocations.RevokeToken("", 1, issuedAt.Add(time.Hour)); !errors.Is(err, ErrMissingJTI) {
+		t.Errorf("RevokeToken sin jti = %v, se esperaba ErrMissingJTI", err)
+	}
+	// Consultar antes de revocar no deja en caché un resultado obsoleto
+	if revoked, err := revocations.IsRevoked("jti-1", 1, issuedAt); err != nil || revoked {
+		t.Fatalf("IsRevoked antes de revocar = %v, %v", revoked, err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := revocations.RevokeToken("jti-1", 1, issuedAt.Add(time.Hour)); err != nil {
+			t.Fatalf("RevokeToken (%d): %v", i+1, err)
+		}
+	}
+	if err := revocations.RevokeToken("jti-caducado", 1, issuedAt.Add(-time.Minute)); err != nil {
+		t.Fatalf("RevokeToken: %v", err)
+	}
+
+	// La base de datos es la fuente de verdad: otra instancia con la caché vacía también lo rechaza
+	for name, service := range map[string]*TokenRevocationService{
+		"misma instancia": revocations,
+		"caché vacía":     NewTokenRevocationService(db, NewCacheService(time.Minute, 0)),
+	} {
+		if revoked, err := service.IsRevoked("jti-1", 1, issuedAt); err != nil || !revoked {
+			t.Errorf("%s: IsRevoked del token revocado = %v, %v", name, revoked, err)
+		}
+		if revoked, err := service.IsRevoked("jti-2", 1, issuedAt); err != nil || revoked {
+			t.Errorf("%s: IsRevoked de otro token = %v, %v", name, revoked, err)
+		}
+	}
+
+	// PurgeExpired solo elimina los registros de tokens que ya expiraron
+	if err := revocations.PurgeExpired(); err != nil {
+		t.Fatalf("PurgeExpired: %v", err)
+	}
+	var jtis []string
+	if err := db.Model(&models.RevokedToken{}).Pluck("jti", &jtis).Error; err != nil {
+		t.Fatalf("listar revocados: %v", err)
+	}
+	if len(jtis) != 1 || jtis[0] != "jti-1" {
+		t.Errorf("tras PurgeExpired quedan %v, se esperaba [jti-1]", jtis)
+	}
+}
+
+func TestRevokeAllForUserRejectsTokensIssuedBefore(t *testing.T) {
+	db := newTestDB(t)
+	unscoped := tenancy.Unscoped(db)
+	revocations := NewTokenRevocationService(db, NewCacheService(time.Minute, 0))
+
+	role, err := DefaultRole(unscoped)
+	if err != nil {
+		t.Fatalf("DefaultRole: %v", err)
+	}
+	ana := models.User{Username: "ana", Email: "ana@example.com", Password: "Secreta123!", RoleID: role.ID}
+	bob := models.User{Username: "bob", Email: "bob@example.com", Password: "Secreta123!", RoleID: role.ID}
+	for _, user := range []*models.User{&ana, &bob} {
+		if err := unscoped.Create(user).Error; err != nil {
+			t.Fatalf("crear usuario: %v", err)
+		}
+	}
+	refresh := NewRefreshTokenService(db)
+	refreshToken, err := refresh.Issue(ana.ID, nil)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	before := time.Now().Add(-time.Minute)
+	if err := revocations.RevokeAllForUser(ana.ID); err != nil {
+		t.Fatalf("RevokeAllForUser: %v", err)
+	}
+	var stored models.User
+	if err := unscoped.First(&stored, ana.ID).Error; err != nil || stored.TokensRevokedAt == nil {
+		t.Fatalf("tokens_revoked_at no se guardó (%v)", err)
+	}
+	revokedAt := *stored.TokensRevokedAt
+
+	for name, service := range map[string]*TokenRevocationService{
+		"misma instancia": revocations,
+		"caché vacía":     NewTokenRevocationService(db, NewCacheService(time.Minute, 0)),
+	} {
+		for _, tc := range []struct {
+			desc     string
+			userID   uint
+			issuedAt time.Time
+			revoked  bool
+		}{
+			{"emitido antes", ana.ID, before, true},
+			// iat tiene precisión de segundos, así que el mismo segundo cuenta como anterior
+			{"emitido en el mismo segundo", ana.ID, revokedAt.Truncate(time.Second), true},
+			{"emitido después", ana.ID, revokedAt.Add(2 * time.Second), false},
+			{"de otro usuario", bob.ID, before, false},
+		} {
+			if revoked, err := service.IsRevoked("", tc.userID, tc.issuedAt); err != nil || revoked != tc.revoked {
+				t.Errorf("%s: token %s = %v, %v; se esperaba %v", name, tc.desc, revoked, err, tc.revoked)
+			}
+		}
+	}
+
+	// Los tokens de refresco del usuario tampoco sirven para obtener tokens nuevos
+	if _, _, err := refresh.Rotate(refreshToken); err == nil {
+		t.Errorf("se pudo rotar un token de refresco tras revocar todos los del usuario")
+	}
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"crypto/rand"
+	"strings"
+	"time"
+
+	"go-api-orm/models"
+	"go-api-orm/tenancy"
+	"go-api-orm/utils"
+	"gorm.io/gorm"
+)
+
+// Propósitos de los tokens intermedios del login en dos pasos
+const (
+	MFAPendingPurpose    = "mfa_pending"
+	MFAEnrollmentPurpose = "mfa_enrollment"
+)
+
+const recoveryCodeCount = 10
+
+// TwoFactorService maneja la autenticación en dos pasos basada en TOTP (RFC 6238)
+type TwoFactorService struct {
+	db     *gorm.DB
+	issuer string
+}
+
+// NewTwoFactorService crea una nueva instancia del servicio de autenticación en dos pasos.
+// La cuenta del usuario es global, así que opera sin filtro por organización.
+func NewTwoFactorService(db *gorm.DB) *TwoFactorService {
+	return &TwoFactorService{
+		db:     tenancy.Unscoped(db),
+		issuer: utils.GetEnvString("MFA_ISSUER", "go-api-orm"),
+	}
+}
+
+// MFATokenTTL retorna la duración de los tokens intermedios mfa_pending y mfa_enrollment
+func MFATokenTTL() time.Duration {
+	return time.Minute * time.Duration(utils.GetEnvInt("MFA_TOKEN_EXPIRATION_MINUTES", 5))
+}
+
+// IsMFARequiredForRole indica si el rol debe inscribirse obligatoriamente (MFA_REQUIRED_ROLES)
+func IsMFARequiredForRole(role string) bool {
+	for _, required := range strings.Split(utils.GetEnvString("MFA_REQUIRED_ROLES", ""), ",") {
+		if strings.TrimSpace(required) == role && role != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrInvalidMFACode se retorna cuando el código TOTP o de recuperación no es válido
+var ErrInvalidMFACode = func() *APIError {
+	return ErrUnauthorized("Código de verificación inválido")
+}
+
+// BeginEnrollment genera un secreto pendiente de confirmación y su URI de aprovisionamiento
+func (s *TwoFactorService) BeginEnrollment(user *models.User) (string, string, error) {
+	if user.MFAEnabled() {
+		return "", "", ErrInvalidInput("La autenticación en dos pasos ya está activada")
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := s.db.Model(user).Updates(map[string]interface{}{
+		"totp_secret":    secret,
+		"totp_last_step": 0,
+	}).Error; err != nil {
+		return "", "", err
+	}
+
+	return secret, utils.TOTPProvisioningURI(s.issuer, user.Email, secret), nil
+}
+
+// ConfirmEnrollment activa la autenticación en dos pasos si el código corresponde al secreto pendiente
+// y retorna los códigos de recuperación en claro (solo se muestran una vez)
+func (s *TwoFactorService) ConfirmEnrollment(user *models.User, code string) ([]string, error) {
+	if user.MFAEnabled() {
+		return nil, ErrInvalidInput("La autenticación en dos pasos ya está activada")
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrInvalidInput("Primero debes iniciar la inscripción")
+	}
+
+	valid, step := utils.ValidateTOTP(user.TOTPSecret, code, time.Now(), 1)
+	if !valid {
+		return nil, ErrInvalidMFACode()
+	}
+
+	var codes []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"mfa_enabled_at": now,
+			"totp_last_step": step,
+		}).Error; err != nil {
+			return err
+		}
+
+		var err error
+		codes, err = s.replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Verify valida un código TOTP o, si no lo es, un código de recuperación sin usar
+func (s *TwoFactorService) Verify(user *models.User, code string) error {
+	if !user.MFAEnabled() {
+		return ErrInvalidInput("La autenticación en dos pasos no está activada")
+	}
+
+	if valid, step := utils.ValidateTOTP(user.TOTPSecret, code, time.Now(), 1); valid {
+		// Cada código solo puede usarse una vez
+		result := s.db.Model(&models.User{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			Update("totp_last_step", step)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidMFACode()
+		}
+		return nil
+	}
+
+	return s.useRecoveryCode(user.ID, code)
+}
+
+// Disable desactiva la autenticación en dos pasos tras verificar un código válido
+func (s *TwoFactorService) Disable(user *models.User, code string) error {
+	if err := s.Verify(user, code); err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"mfa_enabled_at": nil,
+			"totp_secret":    "",
+			"totp_last_step": 0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// RegenerateRecoveryCodes invalida los códigos de recuperación anteriores y genera unos nuevos
+func (s *TwoFactorService) RegenerateRecoveryCodes(user *models.User, code string) ([]string, error) {
+	if err := s.Verify(user, code); err != nil {
+		return nil, err
+	}
+	return s.replaceRecoveryCodes(s.db, user.ID)
+}
+
+func (s *TwoFactorService) useRecoveryCode(userID uint, code string) error {
+	hash := utils.HashToken(normalizeRecoveryCode(code))
+
+	result := s.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidMFACode()
+	}
+	return nil
+}
+
+func (s *TwoFactorService) replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		records = append(records, models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: utils.HashToken(normalizeRecoveryCode(code)),
+		})
+	}
+
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// recoveryCodeAlphabet son los caracteres de los códigos de recuperación (sin 0/o, 1/l/i)
+const recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// generateRecoveryCode genera un código con el formato xxxxx-xxxxx. Los bytes aleatorios
+// mayores o iguales que el mayor múltiplo del tamaño del alfabeto se descartan para que
+// todos los caracteres tengan la misma probabilidad.
+func generateRecoveryCode() (string, error) {
+	const length = 10
+	limit := 256 - 256%len(recoveryCodeAlphabet)
+
+	var code strings.Builder
+	buffer := make([]byte, length)
+	written := 0
+	for written < length {
+		if _, err := rand.Read(buffer); err != nil {
+			return "", err
+		}
+		for _, b := range buffer {
+			if int(b) >= limit {
+				continue
+			}
+			if written == 5 {
+				code.WriteByte('-')
+			}
+			code.WriteByte(recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)])
+			written++
+			if written == length {
+				break
+			}
+		}
+	}
+	return code.String(), nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
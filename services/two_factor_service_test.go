@@ -0,0 +1,46 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateRecoveryCodeFormat(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			t.Fatalf("generateRecoveryCode: %v", err)
+		}
+		if len(code) != 11 || code[5] != '-' {
+			t.Fatalf("formato inesperado %q", code)
+		}
+		for _, r := range strings.ReplaceAll(code, "-", "") {
+			if !strings.ContainsRune(recoveryCodeAlphabet, r) {
+				t.Fatalf("carácter %q fuera del alfabeto en %q", r, code)
+			}
+		}
+	}
+}
+
+func TestGenerateRecoveryCodeDistribution(t *testing.T) {
+	counts := map[rune]int{}
+	total := 0
+	for i := 0; i < 3000; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			t.Fatalf("generateRecoveryCode: %v", err)
+		}
+		for _, r := range strings.ReplaceAll(code, "-", "") {
+			counts[r]++
+			total++
+		}
+	}
+
+	// Con 30000 caracteres cada uno aparece ~968 veces; una tolerancia amplia evita falsos positivos
+	expected := total / len(recoveryCodeAlphabet)
+	for _, r := range recoveryCodeAlphabet {
+		if counts[r] < expected*3/4 || counts[r] > expected*5/4 {
+			t.Errorf("el carácter %q apareció %d veces, se esperaban ~%d", r, counts[r], expected)
+		}
+	}
+}
@@ -1,19 +1,25 @@
 package services
 
 import (
+	"fmt"
 	"regexp"
 	"unicode"
+	"unicode/utf8"
+
+	"go-api-orm/utils"
 )
 
 type ValidationService struct {
-	emailRegex    *regexp.Regexp
-	usernameRegex *regexp.Regexp
+	emailRegex     *regexp.Regexp
+	usernameRegex  *regexp.Regexp
+	passwordPolicy PasswordPolicy
 }
 
 func NewValidationService() *ValidationService {
 	return &ValidationService{
-		emailRegex:    regexp.MustCompile(`^[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,4}$`),
-		usernameRegex: regexp.MustCompile(`^[a-zA-Z0-9_-]{3,30}$`),
+		emailRegex:     regexp.MustCompile(`^[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,4}$`),
+		usernameRegex:  regexp.MustCompile(`^[a-zA-Z0-9_-]{3,30}$`),
+		passwordPolicy: LoadPasswordPolicy(),
 	}
 }
 
@@ -27,10 +33,40 @@ func (s *ValidationService) ValidateUsername(username string) bool {
 	return s.usernameRegex.MatchString(username)
 }
 
-// ValidatePassword verifica que la contraseña cumpla con los requisitos mínimos
+// PasswordPolicy son los requisitos que debe cumplir una contraseña nueva
+type PasswordPolicy struct {
+	MinLength      int
+	MaxLength      int
+	RequireUpper   bool
+	RequireLower   bool
+	RequireNumber  bool
+	RequireSpecial bool
+	HistorySize    int // número de contraseñas anteriores que no se pueden reutilizar
+}
+
+// LoadPasswordPolicy lee la política de contraseñas de las variables de entorno PASSWORD_*
+func LoadPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:      utils.GetEnvInt("PASSWORD_MIN_LENGTH", 8),
+		MaxLength:      utils.GetEnvInt("PASSWORD_MAX_LENGTH", 72),
+		RequireUpper:   utils.GetEnvBool("PASSWORD_REQUIRE_UPPERCASE", true),
+		RequireLower:   utils.GetEnvBool("PASSWORD_REQUIRE_LOWERCASE", true),
+		RequireNumber:  utils.GetEnvBool("PASSWORD_REQUIRE_NUMBER", true),
+		RequireSpecial: utils.GetEnvBool("PASSWORD_REQUIRE_SPECIAL", true),
+		HistorySize:    utils.GetEnvInt("PASSWORD_HISTORY_SIZE", 5),
+	}
+}
+
+// ValidatePassword verifica que la contraseña cumpla con la política de contraseñas
 func (s *ValidationService) ValidatePassword(password string) (bool, string) {
-	if len(password) < 8 {
-		return false, "La contraseña debe tener al menos 8 caracteres"
+	policy := s.passwordPolicy
+
+	length := utf8.RuneCountInString(password)
+	if length < policy.MinLength {
+		return false, fmt.Sprintf("La contraseña debe tener al menos %d caracteres", policy.MinLength)
+	}
+	if policy.MaxLength > 0 && length > policy.MaxLength {
+		return false, fmt.Sprintf("La contraseña no puede tener más de %d caracteres", policy.MaxLength)
 	}
 
 	var (
@@ -53,16 +89,16 @@ func (s *ValidationService) ValidatePassword(password string) (bool, string) {
 		}
 	}
 
-	if !hasUpper {
+	if policy.RequireUpper && !hasUpper {
 		return false, "La contraseña debe contener al menos una mayúscula"
 	}
-	if !hasLower {
+	if policy.RequireLower && !hasLower {
 		return false, "La contraseña debe contener al menos una minúscula"
 	}
-	if !hasNumber {
+	if policy.RequireNumber && !hasNumber {
 		return false, "La contraseña debe contener al menos un número"
 	}
-	if !hasSpecial {
+	if policy.RequireSpecial && !hasSpecial {
 		return false, "La contraseña debe contener al menos un carácter especial"
 	}
 
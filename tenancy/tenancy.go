@@ -0,0 +1,148 @@
+package tenancy
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Field es el campo que identifica la organización (tenant) a la que pertenece un registro
+const Field = "TenantID"
+
+// ErrCrossTenant se retorna al crear un registro de otra organización que la del contexto
+var ErrCrossTenant = errors.New("no se puede crear un registro de otra organización")
+
+// ErrMissingTenant se retorna al consultar, modificar o crear registros de un modelo con
+// TenantID sin organización en el contexto y sin Unscoped
+var ErrMissingTenant = errors.New("consulta sin organización: usa db.WithContext con una organización o tenancy.Unscoped")
+
+type contextKey struct{}
+
+// skipKey desactiva el filtro en las consultas con ese contexto (ver Unscoped)
+type skipKey struct{}
+
+// Shared lo implementan los modelos cuyos registros con tenant_id 0 son visibles en todas
+// las organizaciones (p. ej. los roles del sistema)
+type Shared interface {
+	TenantShared() bool
+}
+
+// WithTenant retorna un contexto cuyas consultas se limitan a la organización indicada
+func WithTenant(ctx context.Context, tenantID uint) context.Context {
+	return context.WithValue(ctx, contextKey{}, tenantID)
+}
+
+// FromContext retorna la organización del contexto
+func FromContext(ctx context.Context) (uint, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	tenantID, ok := ctx.Value(contextKey{}).(uint)
+	return tenantID, ok && tenantID != 0
+}
+
+// Unscoped desactiva el filtro por organización (p. ej. para comprobar que un email no exista
+// en ninguna organización, en el login o en las tareas internas). Es la única forma de operar
+// sobre modelos con TenantID sin organización en el contexto. Se guarda en el contexto para
+// que alcance también a las precargas, asociaciones y hooks, y la sesión retornada puede
+// reutilizarse para varias consultas.
+func Unscoped(db *gorm.DB) *gorm.DB {
+	return db.WithContext(context.WithValue(db.Statement.Context, skipKey{}, true))
+}
+
+// Plugin filtra automáticamente por organización todas las consultas sobre modelos con el
+// campo TenantID según la organización del contexto de la consulta (db.WithContext), y asigna
+// esa organización a los registros que se crean. Sin organización en el contexto las
+// operaciones sobre esos modelos fallan con ErrMissingTenant salvo que se use Unscoped.
+type Plugin struct{}
+
+// Name implementa gorm.Plugin
+func (Plugin) Name() string {
+	return "tenancy"
+}
+
+// Initialize implementa gorm.Plugin registrando los callbacks de filtrado
+func (Plugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Query().Before("gorm:query").Register("tenancy:query", scope); err != nil {
+		return err
+	}
+	if err := callbacks.Row().Before("gorm:row").Register("tenancy:row", scope); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("tenancy:update", scope); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register("tenancy:delete", scope); err != nil {
+		return err
+	}
+	return callbacks.Create().Before("gorm:create").Register("tenancy:create", assign)
+}
+
+// tenantField retorna el campo TenantID del modelo y la organización del contexto. Si el
+// modelo tiene TenantID y no hay organización ni Unscoped, añade ErrMissingTenant.
+func tenantField(db *gorm.DB) (*schema.Field, uint, bool) {
+	if db.Statement.Schema == nil {
+		return nil, 0, false
+	}
+	if skip, _ := db.Statement.Context.Value(skipKey{}).(bool); skip {
+		return nil, 0, false
+	}
+	field := db.Statement.Schema.LookUpField(Field)
+	if field == nil {
+		return nil, 0, false
+	}
+	tenantID, ok := FromContext(db.Statement.Context)
+	if !ok {
+		db.AddError(ErrMissingTenant)
+		return nil, 0, false
+	}
+	return field, tenantID, true
+}
+
+func scope(db *gorm.DB) {
+	field, tenantID, ok := tenantField(db)
+	if !ok {
+		return
+	}
+
+	column := clause.Column{Table: clause.CurrentTable, Name: field.DBName}
+	var condition clause.Expression = clause.Eq{Column: column, Value: tenantID}
+	if shared, ok := reflect.New(db.Statement.Schema.ModelType).Interface().(Shared); ok && shared.TenantShared() {
+		condition = clause.IN{Column: column, Values: []interface{}{uint(0), tenantID}}
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{condition}})
+}
+
+func assign(db *gorm.DB) {
+	field, tenantID, ok := tenantField(db)
+	if !ok {
+		return
+	}
+
+	set := func(value reflect.Value) {
+		current, zero := field.ValueOf(db.Statement.Context, value)
+		if zero {
+			if err := field.Set(db.Statement.Context, value, tenantID); err != nil {
+				db.AddError(err)
+			}
+			return
+		}
+		if current != tenantID {
+			db.AddError(ErrCrossTenant)
+		}
+	}
+
+	switch value := db.Statement.ReflectValue; value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			set(reflect.Indirect(value.Index(i)))
+		}
+	case reflect.Struct:
+		set(value)
+	}
+}
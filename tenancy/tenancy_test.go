@@ -0,0 +1,195 @@
+package tenancy
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type note struct {
+	ID       uint
+	Title    string
+	TenantID uint
+	Tags     []noteTag `gorm:"foreignKey:NoteID"`
+}
+
+type noteTag struct {
+	ID       uint
+	NoteID   uint
+	Name     string
+	TenantID uint
+}
+
+type sharedNote struct {
+	ID       uint
+	Title    string
+	TenantID uint
+}
+
+func (sharedNote) TenantShared() bool {
+	return true
+}
+
+// setting no tiene TenantID: el plugin no lo filtra
+type setting struct {
+	ID    uint
+	Value string
+}
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "tenancy.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := db.Use(Plugin{}); err != nil {
+		t.Fatalf("plugin: %v", err)
+	}
+	if err := db.AutoMigrate(&note{}, &noteTag{}, &sharedNote{}, &setting{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	seed := Unscoped(db)
+	for _, record := range []interface{}{
+		&note{Title: "a", TenantID: 1, Tags: []noteTag{{Name: "a", TenantID: 1}}},
+		&note{Title: "b", TenantID: 2, Tags: []noteTag{{Name: "b", TenantID: 2}}},
+		&sharedNote{Title: "global", TenantID: 0},
+		&sharedNote{Title: "a", TenantID: 1},
+		&sharedNote{Title: "b", TenantID: 2},
+		&setting{Value: "x"},
+	} {
+		if err := seed.Create(record).Error; err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+	return db
+}
+
+func inTenant(db *gorm.DB, tenantID uint) *gorm.DB {
+	return db.WithContext(WithTenant(context.Background(), tenantID))
+}
+
+func TestWithoutTenantFailsClosed(t *testing.T) {
+	db := newTestDB(t)
+
+	var notes []note
+	if err := db.Find(&notes).Error; !errors.Is(err, ErrMissingTenant) {
+		t.Errorf("Find sin organización = %v, se esperaba ErrMissingTenant", err)
+	}
+	var count int64
+	if err := db.Model(&note{}).Count(&count).Error; !errors.Is(err, ErrMissingTenant) {
+		t.Errorf("Count sin organización = %v", err)
+	}
+	if err := db.Model(&note{}).Where("id > 0").Update("title", "x").Error; !errors.Is(err, ErrMissingTenant) {
+		t.Errorf("Update sin organización = %v", err)
+	}
+	if err := db.Where("id > 0").Delete(&note{}).Error; !errors.Is(err, ErrMissingTenant) {
+		t.Errorf("Delete sin organización = %v", err)
+	}
+	if err := db.Create(&note{Title: "c", TenantID: 1}).Error; !errors.Is(err, ErrMissingTenant) {
+		t.Errorf("Create sin organización = %v", err)
+	}
+	var shared []sharedNote
+	if err := db.Find(&shared).Error; !errors.Is(err, ErrMissingTenant) {
+		t.Errorf("Find de un modelo compartido sin organización = %v", err)
+	}
+
+	// Nada se modificó
+	if err := Unscoped(db).Model(&note{}).Where("title = ?", "x").Count(&count).Error; err != nil || count != 0 {
+		t.Errorf("se modificaron %d notas (%v)", count, err)
+	}
+	if err := Unscoped(db).Model(&note{}).Count(&count).Error; err != nil || count != 2 {
+		t.Errorf("quedan %d notas (%v), se esperaban 2", count, err)
+	}
+
+	// Los modelos sin TenantID no se ven afectados
+	var settings []setting
+	if err := db.Find(&settings).Error; err != nil || len(settings) != 1 {
+		t.Errorf("Find de un modelo sin organización = %d, %v", len(settings), err)
+	}
+}
+
+func TestTenantScopesQueries(t *testing.T) {
+	db := newTestDB(t)
+
+	var notes []note
+	if err := inTenant(db, 1).Preload("Tags").Find(&notes).Error; err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	if len(notes) != 1 || notes[0].Title != "a" || len(notes[0].Tags) != 1 || notes[0].Tags[0].Name != "a" {
+		t.Errorf("la organización 1 ve %+v", notes)
+	}
+
+	// Las modificaciones masivas no alcanzan a otras organizaciones
+	if err := inTenant(db, 1).Model(&note{}).Where("id > 0").Update("title", "x").Error; err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	var other note
+	if err := Unscoped(db).Where("tenant_id = ?", 2).First(&other).Error; err != nil || other.Title != "b" {
+		t.Errorf("la nota de la organización 2 es %q (%v)", other.Title, err)
+	}
+
+	// Crear asigna la organización del contexto y rechaza otra
+	created := note{Title: "c"}
+	if err := inTenant(db, 1).Create(&created).Error; err != nil || created.TenantID != 1 {
+		t.Errorf("Create asignó la organización %d (%v)", created.TenantID, err)
+	}
+	if err := inTenant(db, 1).Create(&note{Title: "d", TenantID: 2}).Error; !errors.Is(err, ErrCrossTenant) {
+		t.Errorf("Create en otra organización = %v, se esperaba ErrCrossTenant", err)
+	}
+
+	// Los registros compartidos con tenant_id 0 son visibles en todas las organizaciones
+	var shared []sharedNote
+	if err := inTenant(db, 2).Order("id").Find(&shared).Error; err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	if len(shared) != 2 || shared[0].Title != "global" || shared[1].Title != "b" {
+		t.Errorf("la organización 2 ve %+v", shared)
+	}
+}
+
+func TestUnscopedIsReusableAndReachesPreloads(t *testing.T) {
+	db := newTestDB(t)
+	unscoped := Unscoped(db)
+
+	var first note
+	if err := unscoped.Where("title = ?", "a").First(&first).Error; err != nil {
+		t.Fatalf("First: %v", err)
+	}
+	// La segunda consulta no arrastra las condiciones de la primera
+	var notes []note
+	if err := unscoped.Preload("Tags").Order("id").Find(&notes).Error; err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	if len(notes) != 2 || len(notes[1].Tags) != 1 {
+		t.Errorf("Unscoped ve %+v", notes)
+	}
+
+	// Dentro de una transacción también se mantiene
+	err := unscoped.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&note{}).Count(&count).Error; err != nil {
+			return err
+		}
+		if count != 2 {
+			t.Errorf("la transacción ve %d notas", count)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Transaction: %v", err)
+	}
+}
@@ -1,10 +1,20 @@
 package main
 
 import (
+	"crypto"
+	"crypto/ed25519"
 	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 func generateSecretKey() (string, error) {
@@ -15,22 +25,121 @@ func generateSecretKey() (string, error) {
 	return base64.URLEncoding.EncodeToString(bytes), nil
 }
 
+// generateKeyPair genera un par de claves RSA o Ed25519 según el algoritmo
+func generateKeyPair(alg string, bits int) (crypto.Signer, error) {
+	switch alg {
+	case "RS256":
+		return rsa.GenerateKey(rand.Reader, bits)
+	case "EDDSA":
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		return privateKey, err
+	}
+	return nil, fmt.Errorf("algoritmo no soportado: %s", alg)
+}
+
+// keyID deriva el kid de la misma forma que utils.KeyIDFor
+func keyID(publicKey crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// writeKeyPair escribe <kid>.key (privada) y <kid>.pem (pública) en el directorio indicado
+func writeKeyPair(dir, kid string, privateKey crypto.Signer) (string, string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", "", err
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return "", "", err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	if err != nil {
+		return "", "", err
+	}
+
+	privatePath := filepath.Join(dir, kid+".key")
+	publicPath := filepath.Join(dir, kid+".pem")
+
+	privatePEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER})
+	if err := os.WriteFile(privatePath, privatePEM, 0600); err != nil {
+		return "", "", err
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+	if err := os.WriteFile(publicPath, publicPEM, 0644); err != nil {
+		return "", "", err
+	}
+
+	return privatePath, publicPath, nil
+}
+
 func main() {
-	key, err := generateSecretKey()
+	alg := flag.String("alg", "HS256", "Algoritmo de firma: HS256, RS256 o EdDSA")
+	kid := flag.String("kid", "", "Identificador de la clave (por defecto se deriva de la clave pública)")
+	out := flag.String("out", "keys", "Directorio donde se escriben los pares de claves")
+	bits := flag.Int("bits", 2048, "Tamaño de la clave RSA")
+	flag.Parse()
+
+	algorithm := strings.ToUpper(*alg)
+	if algorithm == "HS256" {
+		key, err := generateSecretKey()
+		if err != nil {
+			fmt.Printf("Error generando la clave: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Println("=== Generador de Clave JWT ===")
+		fmt.Println()
+		fmt.Println("Se ha generado una nueva clave JWT segura:")
+		fmt.Printf("JWT_SECRET_KEY=%s\n", key)
+		fmt.Println()
+		fmt.Println("Instrucciones:")
+		fmt.Println("1. Copia la línea completa 'JWT_SECRET_KEY=...'")
+		fmt.Println("2. Pégala en tu archivo .env")
+		fmt.Println("3. Reinicia tu aplicación")
+		fmt.Println()
+		fmt.Println("Nota: Mantén esta clave segura y nunca la compartas")
+		return
+	}
+
+	privateKey, err := generateKeyPair(algorithm, *bits)
+	if err != nil {
+		fmt.Printf("Error generando el par de claves: %v\n", err)
+		os.Exit(1)
+	}
+
+	id := *kid
+	if id == "" {
+		if id, err = keyID(privateKey.Public()); err != nil {
+			fmt.Printf("Error generando el kid: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	privatePath, publicPath, err := writeKeyPair(*out, id, privateKey)
 	if err != nil {
-		fmt.Printf("Error generando la clave: %v\n", err)
+		fmt.Printf("Error escribiendo las claves: %v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Println("=== Generador de Clave JWT ===")
+	fmt.Println("=== Generador de Claves JWT ===")
 	fmt.Println()
-	fmt.Println("Se ha generado una nueva clave JWT segura:")
-	fmt.Printf("JWT_SECRET_KEY=%s\n", key)
+	fmt.Printf("Se ha generado un nuevo par de claves %s (kid: %s):\n", *alg, id)
+	fmt.Printf("  Privada: %s\n", privatePath)
+	fmt.Printf("  Pública: %s\n", publicPath)
 	fmt.Println()
 	fmt.Println("Instrucciones:")
-	fmt.Println("1. Copia la línea completa 'JWT_SECRET_KEY=...'")
-	fmt.Println("2. Pégala en tu archivo .env")
+	fmt.Println("1. Agrega estas líneas a tu archivo .env:")
+	fmt.Printf("   JWT_SIGNING_METHOD=%s\n", *alg)
+	fmt.Printf("   JWT_PRIVATE_KEY_PATH=%s\n", privatePath)
+	fmt.Printf("   JWT_KEY_ID=%s\n", id)
+	fmt.Printf("   JWT_PUBLIC_KEYS_DIR=%s\n", *out)
+	fmt.Println("2. Para rotar, conserva los .pem anteriores en JWT_PUBLIC_KEYS_DIR hasta que expiren sus tokens")
 	fmt.Println("3. Reinicia tu aplicación")
 	fmt.Println()
-	fmt.Println("Nota: Mantén esta clave segura y nunca la compartas")
+	fmt.Println("Nota: Mantén la clave privada segura y nunca la compartas")
 }
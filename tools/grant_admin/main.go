@@ -0,0 +1,53 @@
+// grant_admin asigna el rol admin a un usuario ya registrado. Es la forma de crear el primer
+// administrador de una instalación nueva, ya que el registro público siempre asigna DEFAULT_ROLE.
+// Usa la misma configuración (.env) que la API.
+//
+// Uso:
+//
+//	go run ./tools/grant_admin -email admin@ejemplo.com
+package main
+
+import (
+	"flag"
+	"log"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"go-api-orm/config"
+	"go-api-orm/models"
+	"go-api-orm/services"
+	"go-api-orm/tenancy"
+)
+
+func main() {
+	email := flag.String("email", "", "Email del usuario que recibe el rol admin")
+	flag.Parse()
+	if *email == "" {
+		log.Fatal("-email is required")
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Error loading .env file: %v", err)
+	}
+	config.InitDB()
+
+	var user models.User
+	if err := tenancy.Unscoped(config.DB).Where("LOWER(email) = ?", strings.ToLower(*email)).First(&user).Error; err != nil {
+		log.Fatalf("User %s not found: %v", *email, err)
+	}
+
+	var admin models.Role
+	if err := tenancy.Unscoped(config.DB).Where("name = ? AND tenant_id = 0", services.AdminRole).First(&admin).Error; err != nil {
+		log.Fatalf("Error loading role %s: %v", services.AdminRole, err)
+	}
+
+	if user.RoleID == admin.ID {
+		log.Printf("%s is already an administrator", user.Email)
+		return
+	}
+	if err := tenancy.Unscoped(config.DB).Model(&user).UpdateColumn("role_id", admin.ID).Error; err != nil {
+		log.Fatalf("Error granting role %s: %v", services.AdminRole, err)
+	}
+
+	log.Printf("%s is now an administrator; the new role applies from the next login", user.Email)
+}
@@ -0,0 +1,44 @@
+// mock_oidc es un proveedor OpenID Connect mínimo para probar localmente el login
+// con proveedores externos. Aprueba automáticamente cada autorización con el usuario
+// configurado por flags y exige PKCE (S256).
+//
+// Uso:
+//
+//	go run ./tools/mock_oidc -addr :9999 -client-id api -email usuario@ejemplo.com
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"go-api-orm/tools/mock_oidc/provider"
+)
+
+func main() {
+	addr := flag.String("addr", ":9999", "Dirección de escucha")
+	issuer := flag.String("issuer", "http://localhost:9999", "Issuer publicado en el descubrimiento y en los id_token")
+	clientID := flag.String("client-id", "api", "client_id aceptado")
+	clientSecret := flag.String("client-secret", "", "client_secret exigido (vacío para clientes públicos)")
+	subject := flag.String("sub", "mock-user-1", "sub del usuario autenticado")
+	email := flag.String("email", "usuario@ejemplo.com", "email del usuario autenticado")
+	emailVerified := flag.Bool("email-verified", true, "Valor del claim email_verified")
+	username := flag.String("username", "", "preferred_username del usuario autenticado")
+	flag.Parse()
+
+	handler, err := provider.New(provider.Config{
+		Issuer:        *issuer,
+		ClientID:      *clientID,
+		ClientSecret:  *clientSecret,
+		Subject:       *subject,
+		Email:         *email,
+		EmailVerified: *emailVerified,
+		Username:      *username,
+	})
+	if err != nil {
+		log.Fatalf("Error generating key: %v", err)
+	}
+
+	log.Printf("Mock OIDC provider listening on %s (issuer %s)", *addr, *issuer)
+	log.Fatal(http.ListenAndServe(*addr, handler))
+}
@@ -1,59 +0,0 @@
-package utils
-
-import (
-	"errors"
-	"os"
-	"strconv"
-	"time"
-
-	"github.com/golang-jwt/jwt/v5"
-)
-
-// GenerateToken genera un nuevo token JWT para el usuario
-func GenerateToken(userId uint, role string) (string, error) {
-	// Obtener la duración de expiración desde las variables de entorno
-	expirationHours, err := strconv.Atoi(os.Getenv("JWT_EXPIRATION_HOURS"))
-	if err != nil {
-		expirationHours = 24 // valor por defecto de 24 horas
-	}
-
-	// Crear los claims
-	claims := jwt.MapClaims{
-		"id":   userId,
-		"role": role,
-		"exp":  time.Now().Add(time.Hour * time.Duration(expirationHours)).Unix(),
-	}
-
-	// Crear el token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	// Firmar el token con la clave secreta
-	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET_KEY")))
-	if err != nil {
-		return "", err
-	}
-
-	return tokenString, nil
-}
-
-// ValidateToken valida un token JWT y retorna los claims si es válido
-func ValidateToken(tokenString string) (jwt.MapClaims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Validar el método de firma
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("método de firma inesperado")
-		}
-
-		return []byte(os.Getenv("JWT_SECRET_KEY")), nil
-	})
-
-	if err != nil {
-		return nil, err
-	}
-
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		return claims, nil
-	}
-
-	return nil, errors.New("token inválido")
-}
@@ -0,0 +1,425 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWK representa una clave pública en formato JSON Web Key
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKSet representa el documento publicado en /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+type verificationKey struct {
+	method jwt.SigningMethod
+	key    interface{}
+}
+
+// KeySet contiene la clave de firma activa y todas las claves aceptadas para verificar
+type KeySet struct {
+	signingKID    string
+	signingMethod jwt.SigningMethod
+	signingKey    interface{}
+	verification  map[string]verificationKey
+	hmacSecret    []byte
+}
+
+var (
+	keySetMu sync.RWMutex
+	keySet   *KeySet
+)
+
+// InitJWTKeys carga las claves JWT desde las variables de entorno
+func InitJWTKeys() error {
+	keys, err := LoadKeySet()
+	if err != nil {
+		return err
+	}
+
+	keySetMu.Lock()
+	keySet = keys
+	keySetMu.Unlock()
+	return nil
+}
+
+func currentKeySet() (*KeySet, error) {
+	keySetMu.RLock()
+	keys := keySet
+	keySetMu.RUnlock()
+	if keys != nil {
+		return keys, nil
+	}
+
+	if err := InitJWTKeys(); err != nil {
+		return nil, err
+	}
+
+	keySetMu.RLock()
+	defer keySetMu.RUnlock()
+	return keySet, nil
+}
+
+// LoadKeySet construye un KeySet a partir de las variables de entorno
+func LoadKeySet() (*KeySet, error) {
+	keys := &KeySet{
+		verification: make(map[string]verificationKey),
+	}
+
+	method := strings.ToUpper(GetEnvString("JWT_SIGNING_METHOD", "HS256"))
+	secret := os.Getenv("JWT_SECRET_KEY")
+	if secret != "" && (method == "HS256" || GetEnvBool("JWT_ALLOW_HS256", false)) {
+		keys.hmacSecret = []byte(secret)
+	}
+
+	switch method {
+	case "HS256":
+		keys.signingMethod = jwt.SigningMethodHS256
+		keys.signingKey = []byte(secret)
+		keys.signingKID = os.Getenv("JWT_KEY_ID")
+	case "RS256", "EDDSA":
+		privateKey, err := readPrivateKey(os.Getenv("JWT_PRIVATE_KEY_PATH"))
+		if err != nil {
+			return nil, err
+		}
+
+		verifyMethod, publicKey, err := signingMethodFor(privateKey.Public())
+		if err != nil {
+			return nil, err
+		}
+		if !strings.EqualFold(verifyMethod.Alg(), method) {
+			return nil, fmt.Errorf("JWT_PRIVATE_KEY_PATH no es una clave %s", method)
+		}
+
+		kid := os.Getenv("JWT_KEY_ID")
+		if kid == "" {
+			if kid, err = KeyIDFor(publicKey); err != nil {
+				return nil, err
+			}
+		}
+
+		keys.signingMethod = verifyMethod
+		keys.signingKey = privateKey
+		keys.signingKID = kid
+		keys.verification[kid] = verificationKey{method: verifyMethod, key: publicKey}
+	default:
+		return nil, fmt.Errorf("JWT_SIGNING_METHOD no soportado: %s", method)
+	}
+
+	if dir := os.Getenv("JWT_PUBLIC_KEYS_DIR"); dir != "" {
+		if err := keys.loadPublicKeys(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	return keys, nil
+}
+
+// loadPublicKeys agrega cada archivo <kid>.pem del directorio como clave de verificación
+func (k *KeySet) loadPublicKeys(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+		if _, exists := k.verification[kid]; exists {
+			continue
+		}
+
+		publicKey, err := readPublicKey(file)
+		if err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+
+		method, key, err := signingMethodFor(publicKey)
+		if err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+		k.verification[kid] = verificationKey{method: method, key: key}
+	}
+
+	return nil
+}
+
+// Sign firma los claims con la clave activa e incluye el kid en la cabecera
+func (k *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.signingMethod, claims)
+	if k.signingKID != "" {
+		token.Header["kid"] = k.signingKID
+	}
+	return token.SignedString(k.signingKey)
+}
+
+// Keyfunc selecciona la clave de verificación según el algoritmo y el kid del token
+func (k *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if len(k.hmacSecret) == 0 {
+			return nil, errors.New("método de firma inesperado")
+		}
+		return k.hmacSecret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("el token no tiene kid")
+	}
+
+	verification, ok := k.verification[kid]
+	if !ok {
+		return nil, errors.New("kid desconocido")
+	}
+	if verification.method.Alg() != token.Method.Alg() {
+		return nil, errors.New("método de firma inesperado")
+	}
+
+	return verification.key, nil
+}
+
+// ValidMethods retorna los algoritmos aceptados al verificar
+func (k *KeySet) ValidMethods() []string {
+	methods := map[string]bool{}
+	if len(k.hmacSecret) > 0 {
+		methods[jwt.SigningMethodHS256.Alg()] = true
+	}
+	for _, verification := range k.verification {
+		methods[verification.method.Alg()] = true
+	}
+
+	result := make([]string, 0, len(methods))
+	for method := range methods {
+		result = append(result, method)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// JWKS retorna las claves públicas de verificación en formato JWK
+func (k *KeySet) JWKS() JWKSet {
+	kids := make([]string, 0, len(k.verification))
+	for kid := range k.verification {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	set := JWKSet{Keys: []JWK{}}
+	for _, kid := range kids {
+		verification := k.verification[kid]
+		jwk := JWK{Kid: kid, Use: "sig", Alg: verification.method.Alg()}
+
+		switch key := verification.key.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(key)
+		default:
+			continue
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set
+}
+
+// PublicKey decodifica la clave pública de un JWK (RSA, EC o Ed25519)
+func (j JWK) PublicKey() (crypto.PublicKey, error) {
+	decode := base64.RawURLEncoding.DecodeString
+
+	switch j.Kty {
+	case "RSA":
+		n, err := decode(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(j.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("exponente RSA inválido")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("curva no soportada: %s", j.Crv)
+		}
+		x, err := decode(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("punto EC inválido")
+		}
+		return key, nil
+	case "OKP":
+		if j.Crv != "Ed25519" {
+			return nil, fmt.Errorf("curva no soportada: %s", j.Crv)
+		}
+		x, err := decode(j.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("clave Ed25519 inválida")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, fmt.Errorf("tipo de clave no soportado: %s", j.Kty)
+}
+
+// SignToken firma los claims con la clave JWT activa
+func SignToken(claims jwt.Claims) (string, error) {
+	keys, err := currentKeySet()
+	if err != nil {
+		return "", err
+	}
+	return keys.Sign(claims)
+}
+
+// ParseToken valida la firma de un token y decodifica sus claims.
+// Las opciones adicionales permiten validar claims como iss o aud.
+func ParseToken(tokenString string, claims jwt.Claims, options ...jwt.ParserOption) (*jwt.Token, error) {
+	keys, err := currentKeySet()
+	if err != nil {
+		return nil, err
+	}
+	options = append(options, jwt.WithValidMethods(keys.ValidMethods()))
+	return jwt.ParseWithClaims(tokenString, claims, keys.Keyfunc, options...)
+}
+
+// GetJWKS retorna el conjunto de claves públicas de verificación
+func GetJWKS() (JWKSet, error) {
+	keys, err := currentKeySet()
+	if err != nil {
+		return JWKSet{}, err
+	}
+	return keys.JWKS(), nil
+}
+
+// KeyIDFor deriva un kid estable a partir de una clave pública
+func KeyIDFor(publicKey crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+func signingMethodFor(publicKey crypto.PublicKey) (jwt.SigningMethod, crypto.PublicKey, error) {
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, key, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, key, nil
+	}
+	return nil, nil, errors.New("tipo de clave no soportado")
+}
+
+func readPrivateKey(path string) (crypto.Signer, error) {
+	if path == "" {
+		return nil, errors.New("JWT_PRIVATE_KEY_PATH es requerido para firmas asimétricas")
+	}
+
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("tipo de clave no soportado")
+		}
+		return signer, nil
+	}
+
+	return nil, fmt.Errorf("bloque PEM no soportado: %s", block.Type)
+}
+
+func readPublicKey(path string) (crypto.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+
+	return nil, fmt.Errorf("bloque PEM no soportado: %s", block.Type)
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s no contiene un bloque PEM", path)
+	}
+	return block, nil
+}
@@ -0,0 +1,206 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// writePEM guarda un bloque PEM en path
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatalf("escribir %s: %v", path, err)
+	}
+}
+
+// writePrivateKey guarda una clave privada en formato PKCS8 y retorna su kid derivado
+func writePrivateKey(t *testing.T, path string, key crypto.Signer) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey: %v", err)
+	}
+	writePEM(t, path, "PRIVATE KEY", der)
+	kid, err := KeyIDFor(key.Public())
+	if err != nil {
+		t.Fatalf("KeyIDFor: %v", err)
+	}
+	return kid
+}
+
+// writePublicKey guarda una clave pública en formato PKIX
+func writePublicKey(t *testing.T, path string, key crypto.PublicKey) {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey: %v", err)
+	}
+	writePEM(t, path, "PUBLIC KEY", der)
+}
+
+// useKeys recarga el KeySet global y lo descarta al terminar el test
+func useKeys(t *testing.T) {
+	t.Helper()
+	if err := InitJWTKeys(); err != nil {
+		t.Fatalf("InitJWTKeys: %v", err)
+	}
+	t.Cleanup(func() {
+		keySetMu.Lock()
+		keySet = nil
+		keySetMu.Unlock()
+	})
+}
+
+func testClaims() jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{Subject: "1", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))}
+}
+
+func TestTokensSignedWithARotatedKeyVerifyByKid(t *testing.T) {
+	dir := t.TempDir()
+	publicDir := filepath.Join(dir, "public")
+	if err := os.Mkdir(publicDir, 0o700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	_, oldKey, _ := ed25519.GenerateKey(rand.Reader)
+	_, newKey, _ := ed25519.GenerateKey(rand.Reader)
+	oldKID := writePrivateKey(t, filepath.Join(dir, "old.pem"), oldKey)
+	newKID := writePrivateKey(t, filepath.Join(dir, "new.pem"), newKey)
+
+	t.Setenv("JWT_SECRET_KEY", "secreto-compartido")
+	t.Setenv("JWT_ALLOW_HS256", "false")
+	t.Setenv("JWT_KEY_ID", "")
+	t.Setenv("JWT_SIGNING_METHOD", "EdDSA")
+	t.Setenv("JWT_PRIVATE_KEY_PATH", filepath.Join(dir, "old.pem"))
+	t.Setenv("JWT_PUBLIC_KEYS_DIR", publicDir)
+	useKeys(t)
+	oldToken, err := SignToken(testClaims())
+	if err != nil {
+		t.Fatalf("SignToken: %v", err)
+	}
+
+	// Rotación: la clave nueva firma y la anterior queda publicada solo para verificar
+	writePublicKey(t, filepath.Join(publicDir, oldKID+".pem"), oldKey.Public())
+	t.Setenv("JWT_PRIVATE_KEY_PATH", filepath.Join(dir, "new.pem"))
+	useKeys(t)
+	newToken, err := SignToken(testClaims())
+	if err != nil {
+		t.Fatalf("SignToken: %v", err)
+	}
+
+	for kid, token := range map[string]string{oldKID: oldToken, newKID: newToken} {
+		parsed, err := ParseToken(token, &jwt.RegisteredClaims{})
+		if err != nil {
+			t.Errorf("token con kid %s: %v", kid, err)
+			continue
+		}
+		if parsed.Header["kid"] != kid || parsed.Method != jwt.SigningMethodEdDSA {
+			t.Errorf("cabecera = %v, se esperaba kid %s y EdDSA", parsed.Header, kid)
+		}
+	}
+
+	forge := func(kid string, method jwt.SigningMethod, key interface{}) string {
+		token := jwt.NewWithClaims(method, testClaims())
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatalf("SignedString: %v", err)
+		}
+		return signed
+	}
+	_, unknownKey, _ := ed25519.GenerateKey(rand.Reader)
+	for name, token := range map[string]string{
+		"kid desconocido":                  forge("desconocido", jwt.SigningMethodEdDSA, unknownKey),
+		"sin kid":                          forge("", jwt.SigningMethodEdDSA, newKey),
+		"kid de otra clave":                forge(oldKID, jwt.SigningMethodEdDSA, newKey),
+		"HS256 sin JWT_ALLOW_HS256":        forge(newKID, jwt.SigningMethodHS256, []byte("secreto-compartido")),
+		"clave pública usada como secreto": forge(newKID, jwt.SigningMethodHS256, []byte(newKey.Public().(ed25519.PublicKey))),
+	} {
+		if _, err := ParseToken(token, &jwt.RegisteredClaims{}); err == nil {
+			t.Errorf("%s: el token fue aceptado", name)
+		}
+	}
+
+	// Al retirar la clave anterior sus tokens dejan de verificarse
+	if err := os.Remove(filepath.Join(publicDir, oldKID+".pem")); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	useKeys(t)
+	if _, err := ParseToken(oldToken, &jwt.RegisteredClaims{}); err == nil {
+		t.Errorf("se aceptó un token firmado con una clave retirada")
+	}
+	if _, err := ParseToken(newToken, &jwt.RegisteredClaims{}); err != nil {
+		t.Errorf("token con la clave activa: %v", err)
+	}
+}
+
+func TestJWKSPublishesTheVerificationKeys(t *testing.T) {
+	dir := t.TempDir()
+	publicDir := filepath.Join(dir, "public")
+	if err := os.Mkdir(publicDir, 0o700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	_, signingKey, _ := ed25519.GenerateKey(rand.Reader)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey: %v", err)
+	}
+	writePublicKey(t, filepath.Join(publicDir, "anterior-rsa.pem"), &rsaKey.PublicKey)
+
+	t.Setenv("JWT_SIGNING_METHOD", "EdDSA")
+	t.Setenv("JWT_PRIVATE_KEY_PATH", filepath.Join(dir, "signing.pem"))
+	t.Setenv("JWT_PUBLIC_KEYS_DIR", publicDir)
+	t.Setenv("JWT_KEY_ID", "actual")
+	writePrivateKey(t, filepath.Join(dir, "signing.pem"), signingKey)
+	useKeys(t)
+
+	set, err := GetJWKS()
+	if err != nil {
+		t.Fatalf("GetJWKS: %v", err)
+	}
+	expected := map[string]struct {
+		kty, alg string
+		key      crypto.PublicKey
+	}{
+		"actual":       {"OKP", "EdDSA", signingKey.Public()},
+		"anterior-rsa": {"RSA", "RS256", &rsaKey.PublicKey},
+	}
+	if len(set.Keys) != len(expected) || set.Keys[0].Kid != "actual" || set.Keys[1].Kid != "anterior-rsa" {
+		t.Fatalf("JWKS = %+v, se esperaban las claves ordenadas por kid", set.Keys)
+	}
+	for _, jwk := range set.Keys {
+		want := expected[jwk.Kid]
+		if jwk.Kty != want.kty || jwk.Alg != want.alg || jwk.Use != "sig" {
+			t.Errorf("%s: kty=%s alg=%s use=%s", jwk.Kid, jwk.Kty, jwk.Alg, jwk.Use)
+		}
+		publicKey, err := jwk.PublicKey()
+		if err != nil {
+			t.Errorf("%s: PublicKey: %v", jwk.Kid, err)
+			continue
+		}
+		if !reflect.DeepEqual(publicKey, want.key) {
+			t.Errorf("%s: la clave publicada no coincide con la de verificación", jwk.Kid)
+		}
+	}
+
+	// Con HS256 el secreto compartido nunca se publica
+	t.Setenv("JWT_SIGNING_METHOD", "HS256")
+	t.Setenv("JWT_SECRET_KEY", "secreto-compartido")
+	t.Setenv("JWT_PUBLIC_KEYS_DIR", "")
+	useKeys(t)
+	if set, err := GetJWKS(); err != nil || len(set.Keys) != 0 {
+		t.Errorf("JWKS con HS256 = %+v, %v; se esperaba vacío", set, err)
+	}
+}
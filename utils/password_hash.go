@@ -0,0 +1,248 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Algoritmos de hash de contraseñas soportados
+const (
+	PasswordHashArgon2id = "argon2id"
+	PasswordHashBcrypt   = "bcrypt"
+)
+
+var (
+	// ErrPasswordMismatch se retorna cuando la contraseña no coincide con el hash
+	ErrPasswordMismatch = errors.New("la contraseña no coincide")
+	// ErrUnknownPasswordHash se retorna cuando el hash almacenado tiene un formato no reconocido
+	ErrUnknownPasswordHash = errors.New("formato de hash de contraseña no reconocido")
+)
+
+// PasswordHasher genera y verifica hashes de contraseñas en formato PHC
+// ($argon2id$v=19$m=...,t=...,p=...$salt$hash o el formato estándar de bcrypt $2a$...)
+type PasswordHasher interface {
+	// Hash genera el hash de la contraseña con los parámetros del hasher
+	Hash(password string) (string, error)
+	// Verify comprueba la contraseña contra un hash generado por este algoritmo
+	Verify(password, encoded string) error
+	// Matches indica si el hash fue generado por este algoritmo
+	Matches(encoded string) bool
+	// NeedsRehash indica si el hash usa parámetros distintos a los actuales
+	NeedsRehash(encoded string) bool
+}
+
+// Argon2idHasher genera hashes argon2id
+type Argon2idHasher struct {
+	Memory      uint32 // memoria en KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// Límites de los parámetros argon2id aceptados. Un hash almacenado con valores fuera de
+// ellos se rechaza para que un hash manipulado no pueda consumir memoria o CPU sin límite.
+const (
+	argon2MaxMemory     = 1024 * 1024 // KiB (1 GiB)
+	argon2MaxIterations = 64
+	argon2MinSaltLength = 8
+	argon2MaxSaltLength = 64
+	argon2MinKeyLength  = 16
+	argon2MaxKeyLength  = 128
+)
+
+// argon2idParams son los parámetros codificados en un hash argon2id
+type argon2idParams struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+// Hash genera el hash argon2id de la contraseña con una sal aleatoria
+func (h Argon2idHasher) Hash(password string) (string, error) {
+	if !validArgon2idParams(h.Memory, h.Iterations, h.Parallelism, h.SaltLength, h.KeyLength) {
+		return "", fmt.Errorf("parámetros argon2id fuera de rango: m=%d, t=%d, p=%d, sal=%d, clave=%d",
+			h.Memory, h.Iterations, h.Parallelism, h.SaltLength, h.KeyLength)
+	}
+
+	salt := make([]byte, h.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.Memory, h.Parallelism, h.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.Memory, h.Iterations, h.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify comprueba la contraseña usando los parámetros guardados en el hash
+func (h Argon2idHasher) Verify(password, encoded string) error {
+	params, err := decodeArgon2id(encoded)
+	if err != nil {
+		return err
+	}
+
+	key := argon2.IDKey([]byte(password), params.salt, params.iterations, params.memory, params.parallelism, uint32(len(params.key)))
+	if subtle.ConstantTimeCompare(key, params.key) != 1 {
+		return ErrPasswordMismatch
+	}
+	return nil
+}
+
+// Matches indica si el hash es argon2id
+func (h Argon2idHasher) Matches(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+// NeedsRehash indica si el hash se generó con parámetros distintos a los configurados
+func (h Argon2idHasher) NeedsRehash(encoded string) bool {
+	params, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return params.memory != h.Memory ||
+		params.iterations != h.Iterations ||
+		params.parallelism != h.Parallelism ||
+		uint32(len(params.salt)) != h.SaltLength ||
+		uint32(len(params.key)) != h.KeyLength
+}
+
+func decodeArgon2id(encoded string) (*argon2idParams, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", sal, hash
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, ErrUnknownPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, ErrUnknownPasswordHash
+	}
+
+	params := &argon2idParams{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return nil, ErrUnknownPasswordHash
+	}
+	// Rechazar texto adicional o ceros a la izquierda que Sscanf ignora
+	if fmt.Sprintf("m=%d,t=%d,p=%d", params.memory, params.iterations, params.parallelism) != parts[3] {
+		return nil, ErrUnknownPasswordHash
+	}
+
+	var err error
+	if params.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, ErrUnknownPasswordHash
+	}
+	if params.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return nil, ErrUnknownPasswordHash
+	}
+
+	if !validArgon2idParams(params.memory, params.iterations, params.parallelism, uint32(len(params.salt)), uint32(len(params.key))) {
+		return nil, ErrUnknownPasswordHash
+	}
+
+	return params, nil
+}
+
+// validArgon2idParams comprueba que los parámetros estén dentro de los límites aceptados.
+// argon2 exige al menos 8 KiB de memoria por hilo.
+func validArgon2idParams(memory, iterations uint32, parallelism uint8, saltLength, keyLength uint32) bool {
+	return parallelism >= 1 &&
+		memory >= 8*uint32(parallelism) && memory <= argon2MaxMemory &&
+		iterations >= 1 && iterations <= argon2MaxIterations &&
+		saltLength >= argon2MinSaltLength && saltLength <= argon2MaxSaltLength &&
+		keyLength >= argon2MinKeyLength && keyLength <= argon2MaxKeyLength
+}
+
+// BcryptHasher genera hashes bcrypt
+type BcryptHasher struct {
+	Cost int
+}
+
+// Hash genera el hash bcrypt de la contraseña
+func (h BcryptHasher) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// Verify comprueba la contraseña contra un hash bcrypt
+func (h BcryptHasher) Verify(password, encoded string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrPasswordMismatch
+	}
+	return err
+}
+
+// Matches indica si el hash es bcrypt ($2a$, $2b$ o $2y$)
+func (h BcryptHasher) Matches(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+// NeedsRehash indica si el hash usa un coste distinto al configurado
+func (h BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != h.Cost
+}
+
+// DefaultPasswordHasher retorna el hasher configurado con PASSWORD_HASH_ALGORITHM
+// (argon2id por defecto) y sus parámetros PASSWORD_ARGON2_* o PASSWORD_BCRYPT_COST
+func DefaultPasswordHasher() PasswordHasher {
+	if strings.ToLower(GetEnvString("PASSWORD_HASH_ALGORITHM", PasswordHashArgon2id)) == PasswordHashBcrypt {
+		return bcryptHasherFromEnv()
+	}
+	return argon2idHasherFromEnv()
+}
+
+func argon2idHasherFromEnv() Argon2idHasher {
+	return Argon2idHasher{
+		Memory:      uint32(GetEnvInt("PASSWORD_ARGON2_MEMORY_KB", 64*1024)),
+		Iterations:  uint32(GetEnvInt("PASSWORD_ARGON2_ITERATIONS", 3)),
+		Parallelism: uint8(GetEnvInt("PASSWORD_ARGON2_PARALLELISM", 4)),
+		SaltLength:  uint32(GetEnvInt("PASSWORD_ARGON2_SALT_LENGTH", 16)),
+		KeyLength:   uint32(GetEnvInt("PASSWORD_ARGON2_KEY_LENGTH", 32)),
+	}
+}
+
+func bcryptHasherFromEnv() BcryptHasher {
+	return BcryptHasher{Cost: GetEnvInt("PASSWORD_BCRYPT_COST", bcrypt.DefaultCost)}
+}
+
+// HashPassword genera el hash de la contraseña con el hasher configurado
+func HashPassword(password string) (string, error) {
+	return DefaultPasswordHasher().Hash(password)
+}
+
+// VerifyPassword comprueba la contraseña contra un hash de cualquier algoritmo soportado.
+// needsRehash indica si el hash debe regenerarse con el algoritmo o los parámetros actuales.
+func VerifyPassword(password, encoded string) (needsRehash bool, err error) {
+	current := DefaultPasswordHasher()
+	hashers := []PasswordHasher{current, argon2idHasherFromEnv(), bcryptHasherFromEnv()}
+
+	for _, hasher := range hashers {
+		if !hasher.Matches(encoded) {
+			continue
+		}
+		if err := hasher.Verify(password, encoded); err != nil {
+			return false, err
+		}
+		// Un hash de otro algoritmo siempre se actualiza al configurado
+		return !current.Matches(encoded) || current.NeedsRehash(encoded), nil
+	}
+
+	return false, ErrUnknownPasswordHash
+}
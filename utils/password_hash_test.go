@@ -0,0 +1,194 @@
+package utils
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgon2id usa parámetros bajos para que las pruebas sean rápidas
+var testArgon2id = Argon2idHasher{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+// setTestArgon2idEnv configura el hasher por defecto con los parámetros de testArgon2id
+func setTestArgon2idEnv(t *testing.T) {
+	t.Setenv("PASSWORD_HASH_ALGORITHM", PasswordHashArgon2id)
+	t.Setenv("PASSWORD_ARGON2_MEMORY_KB", "64")
+	t.Setenv("PASSWORD_ARGON2_ITERATIONS", "1")
+	t.Setenv("PASSWORD_ARGON2_PARALLELISM", "1")
+	t.Setenv("PASSWORD_ARGON2_SALT_LENGTH", "16")
+	t.Setenv("PASSWORD_ARGON2_KEY_LENGTH", "32")
+}
+
+func TestArgon2idHashUsesPHCFormat(t *testing.T) {
+	encoded, err := testArgon2id.Hash("Secreta123!")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+
+	phc := regexp.MustCompile(`^\$argon2id\$v=19\$m=64,t=1,p=1\$[A-Za-z0-9+/]{22}\$[A-Za-z0-9+/]{43}$`)
+	if !phc.MatchString(encoded) {
+		t.Fatalf("hash con formato inesperado: %s", encoded)
+	}
+
+	params, err := decodeArgon2id(encoded)
+	if err != nil {
+		t.Fatalf("decodeArgon2id: %v", err)
+	}
+	if params.memory != 64 || params.iterations != 1 || params.parallelism != 1 || len(params.salt) != 16 || len(params.key) != 32 {
+		t.Errorf("parámetros inesperados: %+v", params)
+	}
+
+	other, _ := testArgon2id.Hash("Secreta123!")
+	if other == encoded {
+		t.Error("dos hashes de la misma contraseña deberían usar sales distintas")
+	}
+}
+
+func TestArgon2idVerify(t *testing.T) {
+	encoded, err := testArgon2id.Hash("Secreta123!")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+
+	if err := testArgon2id.Verify("Secreta123!", encoded); err != nil {
+		t.Errorf("Verify con la contraseña correcta: %v", err)
+	}
+	if err := testArgon2id.Verify("secreta123!", encoded); !errors.Is(err, ErrPasswordMismatch) {
+		t.Errorf("Verify con otra contraseña = %v, se esperaba ErrPasswordMismatch", err)
+	}
+}
+
+func TestArgon2idVerifyExternalHash(t *testing.T) {
+	// Un hash generado fuera del hasher, con otros parámetros, se verifica con los suyos
+	salt := []byte("sal-de-prueba-16")
+	key := argon2.IDKey([]byte("Secreta123!"), salt, 2, 128, 2, 24)
+	encoded := fmt.Sprintf("$argon2id$v=19$m=128,t=2,p=2$%s$%s",
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+
+	if err := testArgon2id.Verify("Secreta123!", encoded); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+	if !testArgon2id.NeedsRehash(encoded) {
+		t.Error("un hash con otros parámetros debería regenerarse")
+	}
+}
+
+func TestDecodeArgon2idRejectsInvalidHashes(t *testing.T) {
+	salt := base64.RawStdEncoding.EncodeToString([]byte("sal-de-prueba-16"))
+	key := base64.RawStdEncoding.EncodeToString(make([]byte, 32))
+	build := func(version, params string) string {
+		return "$argon2id$" + version + "$" + params + "$" + salt + "$" + key
+	}
+
+	cases := map[string]string{
+		"otro algoritmo":       "$argon2i$v=19$m=64,t=1,p=1$" + salt + "$" + key,
+		"versión":              build("v=16", "m=64,t=1,p=1"),
+		"partes":               "$argon2id$v=19$m=64,t=1,p=1$" + salt,
+		"parámetros":           build("v=19", "t=1,m=64,p=1"),
+		"texto adicional":      build("v=19", "m=64,t=1,p=1,x=2"),
+		"memoria excesiva":     build("v=19", "m=4194304,t=1,p=1"),
+		"memoria insuficiente": build("v=19", "m=8,t=1,p=4"),
+		"sin iteraciones":      build("v=19", "m=64,t=0,p=1"),
+		"iteraciones":          build("v=19", "m=64,t=1000000,p=1"),
+		"sin paralelismo":      build("v=19", "m=64,t=1,p=0"),
+		"paralelismo":          build("v=19", "m=64,t=1,p=300"),
+		"sal corta":            "$argon2id$v=19$m=64,t=1,p=1$" + base64.RawStdEncoding.EncodeToString([]byte("corta")) + "$" + key,
+		"hash corto":           "$argon2id$v=19$m=64,t=1,p=1$" + salt + "$" + base64.RawStdEncoding.EncodeToString(make([]byte, 8)),
+		"base64":               "$argon2id$v=19$m=64,t=1,p=1$" + salt + "$no*es*base64",
+	}
+
+	for name, encoded := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := decodeArgon2id(encoded); !errors.Is(err, ErrUnknownPasswordHash) {
+				t.Errorf("decodeArgon2id(%q) = %v, se esperaba ErrUnknownPasswordHash", encoded, err)
+			}
+			if err := testArgon2id.Verify("Secreta123!", encoded); err == nil {
+				t.Errorf("Verify(%q) debería fallar", encoded)
+			}
+		})
+	}
+}
+
+func TestArgon2idHashRejectsInvalidParams(t *testing.T) {
+	for _, hasher := range []Argon2idHasher{
+		{Memory: 64, Iterations: 0, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+		{Memory: 64, Iterations: 1, Parallelism: 0, SaltLength: 16, KeyLength: 32},
+		{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 4, KeyLength: 32},
+		{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 4},
+	} {
+		if _, err := hasher.Hash("Secreta123!"); err == nil {
+			t.Errorf("Hash con %+v debería fallar", hasher)
+		}
+	}
+}
+
+func TestArgon2idNeedsRehash(t *testing.T) {
+	encoded, _ := testArgon2id.Hash("Secreta123!")
+	if testArgon2id.NeedsRehash(encoded) {
+		t.Error("un hash con los parámetros actuales no debería regenerarse")
+	}
+
+	stronger := testArgon2id
+	stronger.Iterations = 2
+	if !stronger.NeedsRehash(encoded) {
+		t.Error("un hash con menos iteraciones que las configuradas debería regenerarse")
+	}
+	if !testArgon2id.NeedsRehash("$argon2id$v=19$corrupto") {
+		t.Error("un hash inválido debería regenerarse")
+	}
+}
+
+func TestVerifyPasswordRehashesOutdatedHashes(t *testing.T) {
+	setTestArgon2idEnv(t)
+
+	current, err := HashPassword("Secreta123!")
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	if needsRehash, err := VerifyPassword("Secreta123!", current); err != nil || needsRehash {
+		t.Errorf("hash actual: needsRehash=%v, err=%v", needsRehash, err)
+	}
+
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("Secreta123!"), bcrypt.MinCost)
+	if needsRehash, err := VerifyPassword("Secreta123!", string(legacy)); err != nil || !needsRehash {
+		t.Errorf("hash bcrypt: needsRehash=%v, err=%v", needsRehash, err)
+	}
+	if _, err := VerifyPassword("otra", string(legacy)); !errors.Is(err, ErrPasswordMismatch) {
+		t.Errorf("bcrypt con otra contraseña = %v", err)
+	}
+
+	t.Setenv("PASSWORD_ARGON2_ITERATIONS", "2")
+	if needsRehash, err := VerifyPassword("Secreta123!", current); err != nil || !needsRehash {
+		t.Errorf("hash con parámetros anteriores: needsRehash=%v, err=%v", needsRehash, err)
+	}
+
+	if _, err := VerifyPassword("Secreta123!", "texto-plano"); !errors.Is(err, ErrUnknownPasswordHash) {
+		t.Errorf("hash desconocido = %v", err)
+	}
+}
+
+func TestVerifyPasswordWithBcryptConfigured(t *testing.T) {
+	setTestArgon2idEnv(t)
+	argonHash, _ := HashPassword("Secreta123!")
+
+	t.Setenv("PASSWORD_HASH_ALGORITHM", PasswordHashBcrypt)
+	t.Setenv("PASSWORD_BCRYPT_COST", fmt.Sprint(bcrypt.MinCost))
+
+	// Un hash argon2id sigue siendo válido y se regenera con bcrypt
+	if needsRehash, err := VerifyPassword("Secreta123!", argonHash); err != nil || !needsRehash {
+		t.Errorf("hash argon2id: needsRehash=%v, err=%v", needsRehash, err)
+	}
+
+	bcryptHash, err := HashPassword("Secreta123!")
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	if needsRehash, err := VerifyPassword("Secreta123!", bcryptHash); err != nil || needsRehash {
+		t.Errorf("hash bcrypt actual: needsRehash=%v, err=%v", needsRehash, err)
+	}
+}
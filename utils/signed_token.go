@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// ErrInvalidSignedToken se retorna cuando la firma o el formato del token no son válidos
+var ErrInvalidSignedToken = errors.New("token firmado inválido")
+
+// SignPayload serializa payload como JSON y lo firma con HMAC-SHA256.
+// El resultado tiene la forma base64url(payload).base64url(firma).
+func SignPayload(secret []byte, payload interface{}) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(data)
+	return encoded + "." + signatureFor(secret, encoded), nil
+}
+
+// VerifyPayload verifica la firma de un token generado con SignPayload y decodifica su payload en out
+func VerifyPayload(secret []byte, token string, out interface{}) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return ErrInvalidSignedToken
+	}
+
+	if !hmac.Equal([]byte(parts[1]), []byte(signatureFor(secret, parts[0]))) {
+		return ErrInvalidSignedToken
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return ErrInvalidSignedToken
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return ErrInvalidSignedToken
+	}
+	return nil
+}
+
+func signatureFor(secret []byte, encoded string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
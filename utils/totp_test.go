@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret es la clave SHA1 de los vectores de prueba del RFC 6238 ("12345678901234567890")
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// rfc6238Vectors son los vectores SHA1 del apéndice B del RFC 6238, truncados a 6 dígitos
+var rfc6238Vectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestTOTPCodeRFC6238Vectors(t *testing.T) {
+	for _, vector := range rfc6238Vectors {
+		code, err := TOTPCode(rfc6238Secret, TOTPStep(time.Unix(vector.unix, 0)))
+		if err != nil {
+			t.Fatalf("TOTPCode(%d): %v", vector.unix, err)
+		}
+		if code != vector.code {
+			t.Errorf("TOTPCode(%d) = %s, se esperaba %s", vector.unix, code, vector.code)
+		}
+	}
+}
+
+func TestTOTPCodeAcceptsLowercaseSecret(t *testing.T) {
+	code, err := TOTPCode(" gezdgnbvgy3tqojqgezdgnbvgy3tqojq ", 1)
+	if err != nil {
+		t.Fatalf("TOTPCode: %v", err)
+	}
+	if code != "287082" {
+		t.Errorf("TOTPCode = %s, se esperaba 287082", code)
+	}
+}
+
+func TestValidateTOTPWindow(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := TOTPStep(now)
+
+	for offset := int64(-1); offset <= 1; offset++ {
+		code, _ := TOTPCode(rfc6238Secret, current+offset)
+		ok, step := ValidateTOTP(rfc6238Secret, code, now, 1)
+		if !ok {
+			t.Errorf("el código del intervalo %+d debería aceptarse con skew 1", offset)
+		}
+		if step != current+offset {
+			t.Errorf("intervalo = %d, se esperaba %d", step, current+offset)
+		}
+	}
+
+	for _, offset := range []int64{-2, 2} {
+		code, _ := TOTPCode(rfc6238Secret, current+offset)
+		if ok, _ := ValidateTOTP(rfc6238Secret, code, now, 1); ok {
+			t.Errorf("el código del intervalo %+d no debería aceptarse con skew 1", offset)
+		}
+	}
+
+	previous, _ := TOTPCode(rfc6238Secret, current-1)
+	if ok, _ := ValidateTOTP(rfc6238Secret, previous, now, 0); ok {
+		t.Error("con skew 0 solo debería aceptarse el intervalo actual")
+	}
+}
+
+func TestValidateTOTPRejectsMalformedCodes(t *testing.T) {
+	now := time.Unix(59, 0)
+	for _, code := range []string{"", "28708", "2870820", "94287082"} {
+		if ok, _ := ValidateTOTP(rfc6238Secret, code, now, 1); ok {
+			t.Errorf("ValidateTOTP(%q) debería fallar", code)
+		}
+	}
+	if ok, _ := ValidateTOTP("no-es-base32!", "287082", now, 1); ok {
+		t.Error("ValidateTOTP con un secreto inválido debería fallar")
+	}
+	if ok, _ := ValidateTOTP(rfc6238Secret, " 287082 ", now, 0); !ok {
+		t.Error("ValidateTOTP debería ignorar los espacios alrededor del código")
+	}
+}